@@ -4,6 +4,7 @@
 package v1
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -18,6 +19,52 @@ import (
 
 const (
 	ApiKeyAuthScopes = "ApiKeyAuth.Scopes"
+	BearerAuthScopes = "BearerAuth.Scopes"
+)
+
+// Defines values for CommandStatus.
+const (
+	CommandStatusFailed    CommandStatus = "failed"
+	CommandStatusSucceeded CommandStatus = "succeeded"
+)
+
+// Defines values for CommandType.
+const (
+	CommandTypeDiagnose CommandType = "diagnose"
+	CommandTypeLogs     CommandType = "logs"
+	CommandTypeRedeploy CommandType = "redeploy"
+	CommandTypeRestart  CommandType = "restart"
+)
+
+// Defines values for ConfigAckStatus.
+const (
+	ConfigAckStatusApplied ConfigAckStatus = "applied"
+	ConfigAckStatusFailed  ConfigAckStatus = "failed"
+)
+
+// Defines values for EnvironmentVariableMode.
+const (
+	Expand EnvironmentVariableMode = "expand"
+	Raw    EnvironmentVariableMode = "raw"
+)
+
+// Defines values for EnvironmentVariableOnChange.
+const (
+	EnvironmentVariableOnChangeNone    EnvironmentVariableOnChange = "none"
+	EnvironmentVariableOnChangeRestart EnvironmentVariableOnChange = "restart"
+	EnvironmentVariableOnChangeSignal  EnvironmentVariableOnChange = "signal"
+)
+
+// Defines values for LogEntryStream.
+const (
+	LogEntryStreamStderr LogEntryStream = "stderr"
+	LogEntryStreamStdout LogEntryStream = "stdout"
+)
+
+// Defines values for LogLineStream.
+const (
+	LogLineStreamStderr LogLineStream = "stderr"
+	LogLineStreamStdout LogLineStream = "stdout"
 )
 
 // Defines values for NodeType.
@@ -27,17 +74,159 @@ const (
 	Vanguard NodeType = "vanguard"
 )
 
+// Defines values for ServiceHealth.
+const (
+	Healthy   ServiceHealth = "healthy"
+	Unhealthy ServiceHealth = "unhealthy"
+	Unknown   ServiceHealth = "unknown"
+)
+
+// Defines values for ServiceState.
+const (
+	Exited  ServiceState = "exited"
+	Failed  ServiceState = "failed"
+	Pending ServiceState = "pending"
+	Running ServiceState = "running"
+	Stopped ServiceState = "stopped"
+)
+
+// APIKeyAuth defines model for APIKeyAuth.
+type APIKeyAuth struct {
+	Header *string       `json:"header,omitempty"`
+	Keys   []SecretValue `json:"keys"`
+}
+
+// BasicAuth defines model for BasicAuth.
+type BasicAuth struct {
+	Realm *string     `json:"realm,omitempty"`
+	Users []BasicUser `json:"users"`
+}
+
+// BasicUser defines model for BasicUser.
+type BasicUser struct {
+	// Password A literal value or a reference resolved by the node.
+	Password SecretValue `json:"password"`
+	Username string      `json:"username"`
+}
+
+// BodyLimitMiddleware defines model for BodyLimitMiddleware.
+type BodyLimitMiddleware struct {
+	MaxBytes int64 `json:"maxBytes"`
+}
+
+// CORSMiddleware defines model for CORSMiddleware.
+type CORSMiddleware struct {
+	AllowCredentials *bool     `json:"allowCredentials,omitempty"`
+	AllowHeaders     *[]string `json:"allowHeaders,omitempty"`
+	AllowMethods     *[]string `json:"allowMethods,omitempty"`
+	AllowOrigins     []string  `json:"allowOrigins"`
+	ExposeHeaders    *[]string `json:"exposeHeaders,omitempty"`
+	MaxAge           *int      `json:"maxAge,omitempty"`
+}
+
+// Command defines model for Command.
+type Command struct {
+	// Commit Commit to redeploy; the branch head when absent
+	Commit *string `json:"commit,omitempty"`
+
+	// Id Unique ID of the command
+	Id       string    `json:"id"`
+	IssuedAt time.Time `json:"issuedAt"`
+
+	// Lines Number of log lines to return
+	Lines *int `json:"lines,omitempty"`
+
+	// Service Target service for restart, redeploy and logs
+	Service *string     `json:"service,omitempty"`
+	Type    CommandType `json:"type"`
+}
+
+// CommandResult defines model for CommandResult.
+type CommandResult struct {
+	Diagnostics *Diagnostics `json:"diagnostics,omitempty"`
+
+	// Error Why the command failed
+	Error      *string   `json:"error,omitempty"`
+	FinishedAt time.Time `json:"finishedAt"`
+
+	// Logs Log lines returned by a logs command, oldest first
+	Logs      *[]LogLine    `json:"logs,omitempty"`
+	StartedAt time.Time     `json:"startedAt"`
+	Status    CommandStatus `json:"status"`
+}
+
+// CommandStatus defines model for CommandStatus.
+type CommandStatus string
+
+// CommandType defines model for CommandType.
+type CommandType string
+
+// CompressMiddleware defines model for CompressMiddleware.
+type CompressMiddleware struct {
+	Algorithms   *[]string `json:"algorithms,omitempty"`
+	ContentTypes *[]string `json:"contentTypes,omitempty"`
+	MinSize      *int      `json:"minSize,omitempty"`
+}
+
+// ConfigAckStatus defines model for ConfigAckStatus.
+type ConfigAckStatus string
+
+// Diagnostics defines model for Diagnostics.
+type Diagnostics struct {
+	Goroutines int    `json:"goroutines"`
+	Hostname   string `json:"hostname"`
+
+	// MemoryBytes Memory obtained from the OS by the agent
+	MemoryBytes   int64           `json:"memoryBytes"`
+	Services      []ServiceStatus `json:"services"`
+	UptimeSeconds int             `json:"uptimeSeconds"`
+
+	// Version Go runtime version of the agent
+	Version string `json:"version"`
+}
+
+// EnrollRequest defines model for EnrollRequest.
+type EnrollRequest struct {
+	// EnrollmentToken One-time token issued by Citadel for this node
+	EnrollmentToken string `json:"enrollmentToken"`
+
+	// Hostname Hostname of the machine enrolling
+	Hostname *string `json:"hostname,omitempty"`
+}
+
+// EnrollResponse defines model for EnrollResponse.
+type EnrollResponse struct {
+	Credentials NodeCredentials `json:"credentials"`
+
+	// NodeId Unique ID assigned to the node
+	NodeId string `json:"nodeId"`
+}
+
 // EnvironmentVariable defines model for EnvironmentVariable.
 type EnvironmentVariable struct {
-	Name string `json:"name"`
+	// Keys Maps dotted JSON paths of the secret to variable names. Only mapped fields are set.
+	Keys *map[string]string `json:"keys,omitempty"`
+
+	// Mode How a JSON object secret becomes variables: 'expand' adds one NAME_KEY variable per key, 'raw' keeps the JSON as the value.
+	Mode *EnvironmentVariableMode `json:"mode,omitempty"`
+	Name string                   `json:"name"`
 
-	// Ref Reference to an external secret store path.
+	// OnChange What happens to the running service when the secret behind ref changes: 'restart' restarts it, 'signal' sends it SIGHUP and 'none' only applies the new value on its next start.
+	OnChange *EnvironmentVariableOnChange `json:"onChange,omitempty"`
+
+	// Ref Reference to an external secret store path. A '#' suffix selects a field of a JSON secret by its dotted path, e.g. '#db.password'.
 	Ref *string `json:"ref,omitempty"`
 
 	// Value Literal string value.
 	Value *string `json:"value,omitempty"`
 }
 
+// EnvironmentVariableMode How a JSON object secret becomes variables: 'expand' adds one NAME_KEY variable per key, 'raw' keeps the JSON as the value.
+type EnvironmentVariableMode string
+
+// EnvironmentVariableOnChange What happens to the running service when the secret behind ref changes: 'restart' restarts it, 'signal' sends it SIGHUP and 'none' only applies the new value on its next start.
+type EnvironmentVariableOnChange string
+
 // ErrorResponse defines model for ErrorResponse.
 type ErrorResponse struct {
 	Code    int    `json:"code"`
@@ -48,10 +237,21 @@ type ErrorResponse struct {
 type GetGitHubAccessTokenResponse struct {
 	// AccessToken The GitHub access token
 	AccessToken string `json:"accessToken"`
+
+	// ExpiresAt When the access token stops being valid. Absent for tokens without a known expiry.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// GetNodeCommandsResponse defines model for GetNodeCommandsResponse.
+type GetNodeCommandsResponse struct {
+	Commands []Command `json:"commands"`
 }
 
 // GetNodeConfigResponse defines model for GetNodeConfigResponse.
 type GetNodeConfigResponse struct {
+	// Revision Opaque identifier of this configuration revision
+	Revision string `json:"revision"`
+
 	// ServiceDeployments A list of all services assigned to this node with their final overridden configurations.
 	ServiceDeployments []ServiceDeployment `json:"serviceDeployments"`
 	Type               NodeType            `json:"type"`
@@ -69,33 +269,272 @@ type GetNodePingResponse struct {
 	Version *string `json:"version,omitempty"`
 }
 
+// GetSecretResponse defines model for GetSecretResponse.
+type GetSecretResponse struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+
+	// Version Changes whenever the secret is rotated
+	Version *string `json:"version,omitempty"`
+}
+
+// HeaderRules defines model for HeaderRules.
+type HeaderRules struct {
+	Add    *map[string]string `json:"add,omitempty"`
+	Remove *[]string          `json:"remove,omitempty"`
+	Set    *map[string]string `json:"set,omitempty"`
+}
+
+// HeadersMiddleware defines model for HeadersMiddleware.
+type HeadersMiddleware struct {
+	Request  *HeaderRules `json:"request,omitempty"`
+	Response *HeaderRules `json:"response,omitempty"`
+}
+
+// IngestLogsRequest defines model for IngestLogsRequest.
+type IngestLogsRequest struct {
+	// BatchId Unique ID of the batch, stable across retries
+	BatchId string     `json:"batchId"`
+	Entries []LogEntry `json:"entries"`
+	NodeId  string     `json:"nodeId"`
+}
+
+// Ingress defines model for Ingress.
+type Ingress struct {
+	Auth        *IngressAuth  `json:"auth,omitempty"`
+	Middlewares *[]Middleware `json:"middlewares,omitempty"`
+}
+
+// IngressAuth defines model for IngressAuth.
+type IngressAuth struct {
+	ApiKey      *APIKeyAuth `json:"apiKey,omitempty"`
+	Basic       *BasicAuth  `json:"basic,omitempty"`
+	IpAllowlist *[]string   `json:"ipAllowlist,omitempty"`
+	Oidc        *OIDCAuth   `json:"oidc,omitempty"`
+}
+
+// LogEntry defines model for LogEntry.
+type LogEntry struct {
+	Service string         `json:"service"`
+	Stream  LogEntryStream `json:"stream"`
+	Text    string         `json:"text"`
+	Time    time.Time      `json:"time"`
+}
+
+// LogEntryStream defines model for LogEntry.Stream.
+type LogEntryStream string
+
+// LogLine defines model for LogLine.
+type LogLine struct {
+	Stream LogLineStream `json:"stream"`
+	Text   string        `json:"text"`
+	Time   time.Time     `json:"time"`
+}
+
+// LogLineStream defines model for LogLine.Stream.
+type LogLineStream string
+
+// Middleware Exactly one of the properties is set.
+type Middleware struct {
+	BodyLimit *BodyLimitMiddleware `json:"bodyLimit,omitempty"`
+	Compress  *CompressMiddleware  `json:"compress,omitempty"`
+	Cors      *CORSMiddleware      `json:"cors,omitempty"`
+	Headers   *HeadersMiddleware   `json:"headers,omitempty"`
+	RateLimit *RateLimitMiddleware `json:"rateLimit,omitempty"`
+	Timeout   *TimeoutMiddleware   `json:"timeout,omitempty"`
+}
+
+// NodeCredentials defines model for NodeCredentials.
+type NodeCredentials struct {
+	// AccessToken Signed bearer token for API calls
+	AccessToken string `json:"accessToken"`
+
+	// ExpiresAt When the access token expires
+	ExpiresAt time.Time `json:"expiresAt"`
+
+	// RefreshToken Long-lived token to obtain new access tokens. Only present when it was issued or rotated.
+	RefreshToken *string `json:"refreshToken,omitempty"`
+}
+
 // NodeType defines model for NodeType.
 type NodeType string
 
-// ServiceDeployment defines model for ServiceDeployment.
+// OIDCAuth defines model for OIDCAuth.
+type OIDCAuth struct {
+	AllowedDomains *[]string `json:"allowedDomains,omitempty"`
+	AllowedEmails  *[]string `json:"allowedEmails,omitempty"`
+	ClientID       string    `json:"clientID"`
+
+	// ClientSecret A literal value or a reference resolved by the node.
+	ClientSecret SecretValue `json:"clientSecret"`
+
+	// CookieSecret A literal value or a reference resolved by the node.
+	CookieSecret SecretValue `json:"cookieSecret"`
+	IssuerURL    string      `json:"issuerURL"`
+	RedirectURL  *string     `json:"redirectURL,omitempty"`
+	Scopes       *[]string   `json:"scopes,omitempty"`
+}
+
+// PostConfigAckRequest defines model for PostConfigAckRequest.
+type PostConfigAckRequest struct {
+	AppliedAt time.Time `json:"appliedAt"`
+
+	// Error Why the revision was not fully applied
+	Error *string `json:"error,omitempty"`
+
+	// Revision The revision the node attempted to apply
+	Revision string          `json:"revision"`
+	Status   ConfigAckStatus `json:"status"`
+}
+
+// PostNodeStatusRequest defines model for PostNodeStatusRequest.
+type PostNodeStatusRequest struct {
+	// ReportedAt The node time when the report was taken
+	ReportedAt time.Time `json:"reportedAt"`
+
+	// Services Status of every service assigned to the node.
+	Services []ServiceStatus `json:"services"`
+}
+
+// RateLimitMiddleware defines model for RateLimitMiddleware.
+type RateLimitMiddleware struct {
+	Burst             *int    `json:"burst,omitempty"`
+	KeyHeader         *string `json:"keyHeader,omitempty"`
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+}
+
+// RefreshTokenRequest defines model for RefreshTokenRequest.
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// RuntimeSpec defines model for RuntimeSpec.
+type RuntimeSpec struct {
+	Engine  string `json:"engine"`
+	Version string `json:"version"`
+}
+
+// SecretValue A literal value or a reference resolved by the node.
+type SecretValue struct {
+	Ref   *string `json:"ref,omitempty"`
+	Value *string `json:"value,omitempty"`
+}
+
+// ServiceDeployment A service definition with environment overrides applied. It mirrors the v1 Service definition field for field.
 type ServiceDeployment struct {
 	// Branch The git branch derived from environment overrides.
-	Branch     string `json:"branch"`
-	DefVersion string `json:"defVersion"`
-	GitUrl     string `json:"gitUrl"`
+	Branch     string         `json:"branch"`
+	DefVersion string         `json:"defVersion"`
+	Files      *[]ServiceFile `json:"files,omitempty"`
+	GitUrl     string         `json:"gitUrl"`
+	Ingress    *Ingress       `json:"ingress,omitempty"`
 
 	// IngressHost Optional host mapping from environment overrides.
 	IngressHost *string `json:"ingressHost,omitempty"`
 	Kind        string  `json:"kind"`
 	Name        string  `json:"name"`
 
+	// Path Directory of the service within the repository, for monorepos.
+	Path *string `json:"path,omitempty"`
+
 	// Port The final overridden port number.
-	Port      int     `json:"port"`
-	RunScript *string `json:"runScript,omitempty"`
-	Runtime   struct {
-		Engine  string `json:"engine"`
-		Version string `json:"version"`
-	} `json:"runtime"`
+	Port      int         `json:"port"`
+	RunScript *string     `json:"runScript,omitempty"`
+	Runtime   RuntimeSpec `json:"runtime"`
 
 	// Variables Final list of environment variables including secrets.
 	Variables *[]EnvironmentVariable `json:"variables,omitempty"`
 }
 
+// ServiceFile A file rendered for the service before it starts, from either template or ref. Files are written with mode 0600.
+type ServiceFile struct {
+	// Env Variable that receives the file's absolute path. Defaults to VANGUARD_FILE_ followed by the upper-cased name.
+	Env  *string `json:"env,omitempty"`
+	Name string  `json:"name"`
+
+	// Path Location relative to the service checkout. Without one the file goes to a private directory.
+	Path *string `json:"path,omitempty"`
+
+	// Ref Reference to an external secret store path whose value is the file content.
+	Ref *string `json:"ref,omitempty"`
+
+	// Template Go text/template over the service's variables, e.g. '{{ .DB_PASSWORD }}'.
+	Template *string `json:"template,omitempty"`
+}
+
+// ServiceHealth defines model for ServiceHealth.
+type ServiceHealth string
+
+// ServiceState defines model for ServiceState.
+type ServiceState string
+
+// ServiceStatus defines model for ServiceStatus.
+type ServiceStatus struct {
+	// Commit The git commit the running service was built from.
+	Commit *string `json:"commit,omitempty"`
+
+	// Error The last deployment error, if any.
+	Error *string `json:"error,omitempty"`
+
+	// ExitCode Exit code of the last process exit, -1 if killed by a signal.
+	ExitCode *int          `json:"exitCode,omitempty"`
+	Health   ServiceHealth `json:"health"`
+	Name     string        `json:"name"`
+
+	// Pid Process ID while the service is running.
+	Pid  *int `json:"pid,omitempty"`
+	Port int  `json:"port"`
+
+	// Restarts Number of times the service process was restarted.
+	Restarts  int          `json:"restarts"`
+	StartedAt *time.Time   `json:"startedAt,omitempty"`
+	State     ServiceState `json:"state"`
+}
+
+// TimeoutMiddleware defines model for TimeoutMiddleware.
+type TimeoutMiddleware struct {
+	Duration string `json:"duration"`
+}
+
+// GetNodeIdCommandsParams defines parameters for GetNodeIdCommands.
+type GetNodeIdCommandsParams struct {
+	// Wait Maximum number of seconds to hold the request open waiting for commands
+	Wait *int `form:"wait,omitempty" json:"wait,omitempty"`
+}
+
+// GetNodeIdGetConfigParams defines parameters for GetNodeIdGetConfig.
+type GetNodeIdGetConfigParams struct {
+	// Wait Maximum number of seconds to hold the request open waiting for a new revision
+	Wait *int `form:"wait,omitempty" json:"wait,omitempty"`
+
+	// IfNoneMatch Revision already applied by the node
+	IfNoneMatch *string `json:"If-None-Match,omitempty"`
+}
+
+// GetNodeIdSecretParams defines parameters for GetNodeIdSecret.
+type GetNodeIdSecretParams struct {
+	// Name Name of the secret, the path of a citadel/ ref
+	Name string `form:"name" json:"name"`
+}
+
+// PostEnrollJSONRequestBody defines body for PostEnroll for application/json ContentType.
+type PostEnrollJSONRequestBody = EnrollRequest
+
+// PostNodeIdCommandsCommandIdResultJSONRequestBody defines body for PostNodeIdCommandsCommandIdResult for application/json ContentType.
+type PostNodeIdCommandsCommandIdResultJSONRequestBody = CommandResult
+
+// PostNodeIdConfigAckJSONRequestBody defines body for PostNodeIdConfigAck for application/json ContentType.
+type PostNodeIdConfigAckJSONRequestBody = PostConfigAckRequest
+
+// PostNodeIdLogsJSONRequestBody defines body for PostNodeIdLogs for application/json ContentType.
+type PostNodeIdLogsJSONRequestBody = IngestLogsRequest
+
+// PostNodeIdStatusJSONRequestBody defines body for PostNodeIdStatus for application/json ContentType.
+type PostNodeIdStatusJSONRequestBody = PostNodeStatusRequest
+
+// PostNodeIdTokenJSONRequestBody defines body for PostNodeIdToken for application/json ContentType.
+type PostNodeIdTokenJSONRequestBody = RefreshTokenRequest
+
 // RequestEditorFn  is the function signature for the RequestEditor callback function
 type RequestEditorFn func(ctx context.Context, req *http.Request) error
 
@@ -169,18 +608,57 @@ func WithRequestEditorFn(fn RequestEditorFn) ClientOption {
 
 // The interface specification for the client above.
 type ClientInterface interface {
+	// PostEnrollWithBody request with any body
+	PostEnrollWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	PostEnroll(ctx context.Context, body PostEnrollJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
 	// GetGithubAccessToken request
 	GetGithubAccessToken(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error)
 
+	// GetNodeIdCommands request
+	GetNodeIdCommands(ctx context.Context, id string, params *GetNodeIdCommandsParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// PostNodeIdCommandsCommandIdResultWithBody request with any body
+	PostNodeIdCommandsCommandIdResultWithBody(ctx context.Context, id string, commandId string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	PostNodeIdCommandsCommandIdResult(ctx context.Context, id string, commandId string, body PostNodeIdCommandsCommandIdResultJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// PostNodeIdConfigAckWithBody request with any body
+	PostNodeIdConfigAckWithBody(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	PostNodeIdConfigAck(ctx context.Context, id string, body PostNodeIdConfigAckJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
 	// GetNodeIdGetConfig request
-	GetNodeIdGetConfig(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*http.Response, error)
+	GetNodeIdGetConfig(ctx context.Context, id string, params *GetNodeIdGetConfigParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// PostNodeIdLogsWithBody request with any body
+	PostNodeIdLogsWithBody(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	PostNodeIdLogs(ctx context.Context, id string, body PostNodeIdLogsJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
 
 	// GetNodeIdPing request
 	GetNodeIdPing(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// PostNodeIdRotateCredentials request
+	PostNodeIdRotateCredentials(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// GetNodeIdSecret request
+	GetNodeIdSecret(ctx context.Context, id string, params *GetNodeIdSecretParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// PostNodeIdStatusWithBody request with any body
+	PostNodeIdStatusWithBody(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	PostNodeIdStatus(ctx context.Context, id string, body PostNodeIdStatusJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// PostNodeIdTokenWithBody request with any body
+	PostNodeIdTokenWithBody(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	PostNodeIdToken(ctx context.Context, id string, body PostNodeIdTokenJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
 }
 
-func (c *Client) GetGithubAccessToken(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error) {
-	req, err := NewGetGithubAccessTokenRequest(c.Server)
+func (c *Client) PostEnrollWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostEnrollRequestWithBody(c.Server, contentType, body)
 	if err != nil {
 		return nil, err
 	}
@@ -191,8 +669,8 @@ func (c *Client) GetGithubAccessToken(ctx context.Context, reqEditors ...Request
 	return c.Client.Do(req)
 }
 
-func (c *Client) GetNodeIdGetConfig(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*http.Response, error) {
-	req, err := NewGetNodeIdGetConfigRequest(c.Server, id)
+func (c *Client) PostEnroll(ctx context.Context, body PostEnrollJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostEnrollRequest(c.Server, body)
 	if err != nil {
 		return nil, err
 	}
@@ -203,8 +681,8 @@ func (c *Client) GetNodeIdGetConfig(ctx context.Context, id string, reqEditors .
 	return c.Client.Do(req)
 }
 
-func (c *Client) GetNodeIdPing(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*http.Response, error) {
-	req, err := NewGetNodeIdPingRequest(c.Server, id)
+func (c *Client) GetGithubAccessToken(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetGithubAccessTokenRequest(c.Server)
 	if err != nil {
 		return nil, err
 	}
@@ -215,266 +693,1530 @@ func (c *Client) GetNodeIdPing(ctx context.Context, id string, reqEditors ...Req
 	return c.Client.Do(req)
 }
 
-// NewGetGithubAccessTokenRequest generates requests for GetGithubAccessToken
-func NewGetGithubAccessTokenRequest(server string) (*http.Request, error) {
-	var err error
-
-	serverURL, err := url.Parse(server)
+func (c *Client) GetNodeIdCommands(ctx context.Context, id string, params *GetNodeIdCommandsParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetNodeIdCommandsRequest(c.Server, id, params)
 	if err != nil {
 		return nil, err
 	}
-
-	operationPath := fmt.Sprintf("/github/access-token")
-	if operationPath[0] == '/' {
-		operationPath = "." + operationPath
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
 	}
+	return c.Client.Do(req)
+}
 
-	queryURL, err := serverURL.Parse(operationPath)
+func (c *Client) PostNodeIdCommandsCommandIdResultWithBody(ctx context.Context, id string, commandId string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostNodeIdCommandsCommandIdResultRequestWithBody(c.Server, id, commandId, contentType, body)
 	if err != nil {
 		return nil, err
 	}
-
-	req, err := http.NewRequest("GET", queryURL.String(), nil)
-	if err != nil {
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
 		return nil, err
 	}
-
-	return req, nil
+	return c.Client.Do(req)
 }
 
-// NewGetNodeIdGetConfigRequest generates requests for GetNodeIdGetConfig
-func NewGetNodeIdGetConfigRequest(server string, id string) (*http.Request, error) {
-	var err error
-
-	var pathParam0 string
-
-	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+func (c *Client) PostNodeIdCommandsCommandIdResult(ctx context.Context, id string, commandId string, body PostNodeIdCommandsCommandIdResultJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostNodeIdCommandsCommandIdResultRequest(c.Server, id, commandId, body)
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
 
-	serverURL, err := url.Parse(server)
+func (c *Client) PostNodeIdConfigAckWithBody(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostNodeIdConfigAckRequestWithBody(c.Server, id, contentType, body)
 	if err != nil {
 		return nil, err
 	}
-
-	operationPath := fmt.Sprintf("/node/%s/get-config", pathParam0)
-	if operationPath[0] == '/' {
-		operationPath = "." + operationPath
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
 	}
+	return c.Client.Do(req)
+}
 
-	queryURL, err := serverURL.Parse(operationPath)
+func (c *Client) PostNodeIdConfigAck(ctx context.Context, id string, body PostNodeIdConfigAckJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostNodeIdConfigAckRequest(c.Server, id, body)
 	if err != nil {
 		return nil, err
 	}
-
-	req, err := http.NewRequest("GET", queryURL.String(), nil)
-	if err != nil {
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
 		return nil, err
 	}
-
-	return req, nil
+	return c.Client.Do(req)
 }
 
-// NewGetNodeIdPingRequest generates requests for GetNodeIdPing
-func NewGetNodeIdPingRequest(server string, id string) (*http.Request, error) {
-	var err error
-
-	var pathParam0 string
-
-	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+func (c *Client) GetNodeIdGetConfig(ctx context.Context, id string, params *GetNodeIdGetConfigParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetNodeIdGetConfigRequest(c.Server, id, params)
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
 
-	serverURL, err := url.Parse(server)
+func (c *Client) PostNodeIdLogsWithBody(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostNodeIdLogsRequestWithBody(c.Server, id, contentType, body)
 	if err != nil {
 		return nil, err
 	}
-
-	operationPath := fmt.Sprintf("/node/%s/ping", pathParam0)
-	if operationPath[0] == '/' {
-		operationPath = "." + operationPath
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
 	}
+	return c.Client.Do(req)
+}
 
-	queryURL, err := serverURL.Parse(operationPath)
+func (c *Client) PostNodeIdLogs(ctx context.Context, id string, body PostNodeIdLogsJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostNodeIdLogsRequest(c.Server, id, body)
 	if err != nil {
 		return nil, err
 	}
-
-	req, err := http.NewRequest("GET", queryURL.String(), nil)
-	if err != nil {
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
 		return nil, err
 	}
-
-	return req, nil
+	return c.Client.Do(req)
 }
 
-func (c *Client) applyEditors(ctx context.Context, req *http.Request, additionalEditors []RequestEditorFn) error {
-	for _, r := range c.RequestEditors {
-		if err := r(ctx, req); err != nil {
-			return err
+func (c *Client) GetNodeIdPing(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetNodeIdPingRequest(c.Server, id)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) PostNodeIdRotateCredentials(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostNodeIdRotateCredentialsRequest(c.Server, id)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) GetNodeIdSecret(ctx context.Context, id string, params *GetNodeIdSecretParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetNodeIdSecretRequest(c.Server, id, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) PostNodeIdStatusWithBody(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostNodeIdStatusRequestWithBody(c.Server, id, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) PostNodeIdStatus(ctx context.Context, id string, body PostNodeIdStatusJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostNodeIdStatusRequest(c.Server, id, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) PostNodeIdTokenWithBody(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostNodeIdTokenRequestWithBody(c.Server, id, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) PostNodeIdToken(ctx context.Context, id string, body PostNodeIdTokenJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostNodeIdTokenRequest(c.Server, id, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+// NewPostEnrollRequest calls the generic PostEnroll builder with application/json body
+func NewPostEnrollRequest(server string, body PostEnrollJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewPostEnrollRequestWithBody(server, "application/json", bodyReader)
+}
+
+// NewPostEnrollRequestWithBody generates requests for PostEnroll with any type of body
+func NewPostEnrollRequestWithBody(server string, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/enroll")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewGetGithubAccessTokenRequest generates requests for GetGithubAccessToken
+func NewGetGithubAccessTokenRequest(server string) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/github/access-token")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewGetNodeIdCommandsRequest generates requests for GetNodeIdCommands
+func NewGetNodeIdCommandsRequest(server string, id string, params *GetNodeIdCommandsParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/node/%s/commands", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if params.Wait != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "wait", runtime.ParamLocationQuery, *params.Wait); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewPostNodeIdCommandsCommandIdResultRequest calls the generic PostNodeIdCommandsCommandIdResult builder with application/json body
+func NewPostNodeIdCommandsCommandIdResultRequest(server string, id string, commandId string, body PostNodeIdCommandsCommandIdResultJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewPostNodeIdCommandsCommandIdResultRequestWithBody(server, id, commandId, "application/json", bodyReader)
+}
+
+// NewPostNodeIdCommandsCommandIdResultRequestWithBody generates requests for PostNodeIdCommandsCommandIdResult with any type of body
+func NewPostNodeIdCommandsCommandIdResultRequestWithBody(server string, id string, commandId string, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathParam1 string
+
+	pathParam1, err = runtime.StyleParamWithLocation("simple", false, "commandId", runtime.ParamLocationPath, commandId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/node/%s/commands/%s/result", pathParam0, pathParam1)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewPostNodeIdConfigAckRequest calls the generic PostNodeIdConfigAck builder with application/json body
+func NewPostNodeIdConfigAckRequest(server string, id string, body PostNodeIdConfigAckJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewPostNodeIdConfigAckRequestWithBody(server, id, "application/json", bodyReader)
+}
+
+// NewPostNodeIdConfigAckRequestWithBody generates requests for PostNodeIdConfigAck with any type of body
+func NewPostNodeIdConfigAckRequestWithBody(server string, id string, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/node/%s/config-ack", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewGetNodeIdGetConfigRequest generates requests for GetNodeIdGetConfig
+func NewGetNodeIdGetConfigRequest(server string, id string, params *GetNodeIdGetConfigParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/node/%s/get-config", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if params.Wait != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "wait", runtime.ParamLocationQuery, *params.Wait); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+
+		if params.IfNoneMatch != nil {
+			var headerParam0 string
+
+			headerParam0, err = runtime.StyleParamWithLocation("simple", false, "If-None-Match", runtime.ParamLocationHeader, *params.IfNoneMatch)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("If-None-Match", headerParam0)
+		}
+
+	}
+
+	return req, nil
+}
+
+// NewPostNodeIdLogsRequest calls the generic PostNodeIdLogs builder with application/json body
+func NewPostNodeIdLogsRequest(server string, id string, body PostNodeIdLogsJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewPostNodeIdLogsRequestWithBody(server, id, "application/json", bodyReader)
+}
+
+// NewPostNodeIdLogsRequestWithBody generates requests for PostNodeIdLogs with any type of body
+func NewPostNodeIdLogsRequestWithBody(server string, id string, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/node/%s/logs", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewGetNodeIdPingRequest generates requests for GetNodeIdPing
+func NewGetNodeIdPingRequest(server string, id string) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/node/%s/ping", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewPostNodeIdRotateCredentialsRequest generates requests for PostNodeIdRotateCredentials
+func NewPostNodeIdRotateCredentialsRequest(server string, id string) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/node/%s/rotate-credentials", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewGetNodeIdSecretRequest generates requests for GetNodeIdSecret
+func NewGetNodeIdSecretRequest(server string, id string, params *GetNodeIdSecretParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/node/%s/secret", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "name", runtime.ParamLocationQuery, params.Name); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewPostNodeIdStatusRequest calls the generic PostNodeIdStatus builder with application/json body
+func NewPostNodeIdStatusRequest(server string, id string, body PostNodeIdStatusJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewPostNodeIdStatusRequestWithBody(server, id, "application/json", bodyReader)
+}
+
+// NewPostNodeIdStatusRequestWithBody generates requests for PostNodeIdStatus with any type of body
+func NewPostNodeIdStatusRequestWithBody(server string, id string, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/node/%s/status", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewPostNodeIdTokenRequest calls the generic PostNodeIdToken builder with application/json body
+func NewPostNodeIdTokenRequest(server string, id string, body PostNodeIdTokenJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewPostNodeIdTokenRequestWithBody(server, id, "application/json", bodyReader)
+}
+
+// NewPostNodeIdTokenRequestWithBody generates requests for PostNodeIdToken with any type of body
+func NewPostNodeIdTokenRequestWithBody(server string, id string, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/node/%s/token", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+func (c *Client) applyEditors(ctx context.Context, req *http.Request, additionalEditors []RequestEditorFn) error {
+	for _, r := range c.RequestEditors {
+		if err := r(ctx, req); err != nil {
+			return err
+		}
+	}
+	for _, r := range additionalEditors {
+		if err := r(ctx, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClientWithResponses builds on ClientInterface to offer response payloads
+type ClientWithResponses struct {
+	ClientInterface
+}
+
+// NewClientWithResponses creates a new ClientWithResponses, which wraps
+// Client with return type handling
+func NewClientWithResponses(server string, opts ...ClientOption) (*ClientWithResponses, error) {
+	client, err := NewClient(server, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientWithResponses{client}, nil
+}
+
+// WithBaseURL overrides the baseURL.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) error {
+		newBaseURL, err := url.Parse(baseURL)
+		if err != nil {
+			return err
+		}
+		c.Server = newBaseURL.String()
+		return nil
+	}
+}
+
+// ClientWithResponsesInterface is the interface specification for the client with responses above.
+type ClientWithResponsesInterface interface {
+	// PostEnrollWithBodyWithResponse request with any body
+	PostEnrollWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostEnrollResponse, error)
+
+	PostEnrollWithResponse(ctx context.Context, body PostEnrollJSONRequestBody, reqEditors ...RequestEditorFn) (*PostEnrollResponse, error)
+
+	// GetGithubAccessTokenWithResponse request
+	GetGithubAccessTokenWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetGithubAccessTokenResponse, error)
+
+	// GetNodeIdCommandsWithResponse request
+	GetNodeIdCommandsWithResponse(ctx context.Context, id string, params *GetNodeIdCommandsParams, reqEditors ...RequestEditorFn) (*GetNodeIdCommandsResponse, error)
+
+	// PostNodeIdCommandsCommandIdResultWithBodyWithResponse request with any body
+	PostNodeIdCommandsCommandIdResultWithBodyWithResponse(ctx context.Context, id string, commandId string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostNodeIdCommandsCommandIdResultResponse, error)
+
+	PostNodeIdCommandsCommandIdResultWithResponse(ctx context.Context, id string, commandId string, body PostNodeIdCommandsCommandIdResultJSONRequestBody, reqEditors ...RequestEditorFn) (*PostNodeIdCommandsCommandIdResultResponse, error)
+
+	// PostNodeIdConfigAckWithBodyWithResponse request with any body
+	PostNodeIdConfigAckWithBodyWithResponse(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostNodeIdConfigAckResponse, error)
+
+	PostNodeIdConfigAckWithResponse(ctx context.Context, id string, body PostNodeIdConfigAckJSONRequestBody, reqEditors ...RequestEditorFn) (*PostNodeIdConfigAckResponse, error)
+
+	// GetNodeIdGetConfigWithResponse request
+	GetNodeIdGetConfigWithResponse(ctx context.Context, id string, params *GetNodeIdGetConfigParams, reqEditors ...RequestEditorFn) (*GetNodeIdGetConfigResponse, error)
+
+	// PostNodeIdLogsWithBodyWithResponse request with any body
+	PostNodeIdLogsWithBodyWithResponse(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostNodeIdLogsResponse, error)
+
+	PostNodeIdLogsWithResponse(ctx context.Context, id string, body PostNodeIdLogsJSONRequestBody, reqEditors ...RequestEditorFn) (*PostNodeIdLogsResponse, error)
+
+	// GetNodeIdPingWithResponse request
+	GetNodeIdPingWithResponse(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*GetNodeIdPingResponse, error)
+
+	// PostNodeIdRotateCredentialsWithResponse request
+	PostNodeIdRotateCredentialsWithResponse(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*PostNodeIdRotateCredentialsResponse, error)
+
+	// GetNodeIdSecretWithResponse request
+	GetNodeIdSecretWithResponse(ctx context.Context, id string, params *GetNodeIdSecretParams, reqEditors ...RequestEditorFn) (*GetNodeIdSecretResponse, error)
+
+	// PostNodeIdStatusWithBodyWithResponse request with any body
+	PostNodeIdStatusWithBodyWithResponse(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostNodeIdStatusResponse, error)
+
+	PostNodeIdStatusWithResponse(ctx context.Context, id string, body PostNodeIdStatusJSONRequestBody, reqEditors ...RequestEditorFn) (*PostNodeIdStatusResponse, error)
+
+	// PostNodeIdTokenWithBodyWithResponse request with any body
+	PostNodeIdTokenWithBodyWithResponse(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostNodeIdTokenResponse, error)
+
+	PostNodeIdTokenWithResponse(ctx context.Context, id string, body PostNodeIdTokenJSONRequestBody, reqEditors ...RequestEditorFn) (*PostNodeIdTokenResponse, error)
+}
+
+type PostEnrollResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *EnrollResponse
+	JSON401      *ErrorResponse
+}
+
+// Status returns HTTPResponse.Status
+func (r PostEnrollResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r PostEnrollResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetGithubAccessTokenResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *GetGitHubAccessTokenResponse
+	JSON401      *ErrorResponse
+}
+
+// Status returns HTTPResponse.Status
+func (r GetGithubAccessTokenResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetGithubAccessTokenResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetNodeIdCommandsResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *GetNodeCommandsResponse
+	JSON401      *ErrorResponse
+}
+
+// Status returns HTTPResponse.Status
+func (r GetNodeIdCommandsResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetNodeIdCommandsResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type PostNodeIdCommandsCommandIdResultResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON401      *ErrorResponse
+}
+
+// Status returns HTTPResponse.Status
+func (r PostNodeIdCommandsCommandIdResultResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r PostNodeIdCommandsCommandIdResultResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type PostNodeIdConfigAckResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON401      *ErrorResponse
+}
+
+// Status returns HTTPResponse.Status
+func (r PostNodeIdConfigAckResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r PostNodeIdConfigAckResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetNodeIdGetConfigResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *GetNodeConfigResponse
+	JSON401      *ErrorResponse
+}
+
+// Status returns HTTPResponse.Status
+func (r GetNodeIdGetConfigResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetNodeIdGetConfigResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type PostNodeIdLogsResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON401      *ErrorResponse
+	JSON413      *ErrorResponse
+}
+
+// Status returns HTTPResponse.Status
+func (r PostNodeIdLogsResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r PostNodeIdLogsResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetNodeIdPingResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *GetNodePingResponse
+	JSON401      *ErrorResponse
+}
+
+// Status returns HTTPResponse.Status
+func (r GetNodeIdPingResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetNodeIdPingResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type PostNodeIdRotateCredentialsResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *NodeCredentials
+	JSON401      *ErrorResponse
+}
+
+// Status returns HTTPResponse.Status
+func (r PostNodeIdRotateCredentialsResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r PostNodeIdRotateCredentialsResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetNodeIdSecretResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *GetSecretResponse
+	JSON401      *ErrorResponse
+	JSON404      *ErrorResponse
+}
+
+// Status returns HTTPResponse.Status
+func (r GetNodeIdSecretResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetNodeIdSecretResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type PostNodeIdStatusResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON401      *ErrorResponse
+}
+
+// Status returns HTTPResponse.Status
+func (r PostNodeIdStatusResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r PostNodeIdStatusResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type PostNodeIdTokenResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *NodeCredentials
+	JSON401      *ErrorResponse
+}
+
+// Status returns HTTPResponse.Status
+func (r PostNodeIdTokenResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r PostNodeIdTokenResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+// PostEnrollWithBodyWithResponse request with arbitrary body returning *PostEnrollResponse
+func (c *ClientWithResponses) PostEnrollWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostEnrollResponse, error) {
+	rsp, err := c.PostEnrollWithBody(ctx, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostEnrollResponse(rsp)
+}
+
+func (c *ClientWithResponses) PostEnrollWithResponse(ctx context.Context, body PostEnrollJSONRequestBody, reqEditors ...RequestEditorFn) (*PostEnrollResponse, error) {
+	rsp, err := c.PostEnroll(ctx, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostEnrollResponse(rsp)
+}
+
+// GetGithubAccessTokenWithResponse request returning *GetGithubAccessTokenResponse
+func (c *ClientWithResponses) GetGithubAccessTokenWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetGithubAccessTokenResponse, error) {
+	rsp, err := c.GetGithubAccessToken(ctx, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetGithubAccessTokenResponse(rsp)
+}
+
+// GetNodeIdCommandsWithResponse request returning *GetNodeIdCommandsResponse
+func (c *ClientWithResponses) GetNodeIdCommandsWithResponse(ctx context.Context, id string, params *GetNodeIdCommandsParams, reqEditors ...RequestEditorFn) (*GetNodeIdCommandsResponse, error) {
+	rsp, err := c.GetNodeIdCommands(ctx, id, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetNodeIdCommandsResponse(rsp)
+}
+
+// PostNodeIdCommandsCommandIdResultWithBodyWithResponse request with arbitrary body returning *PostNodeIdCommandsCommandIdResultResponse
+func (c *ClientWithResponses) PostNodeIdCommandsCommandIdResultWithBodyWithResponse(ctx context.Context, id string, commandId string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostNodeIdCommandsCommandIdResultResponse, error) {
+	rsp, err := c.PostNodeIdCommandsCommandIdResultWithBody(ctx, id, commandId, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostNodeIdCommandsCommandIdResultResponse(rsp)
+}
+
+func (c *ClientWithResponses) PostNodeIdCommandsCommandIdResultWithResponse(ctx context.Context, id string, commandId string, body PostNodeIdCommandsCommandIdResultJSONRequestBody, reqEditors ...RequestEditorFn) (*PostNodeIdCommandsCommandIdResultResponse, error) {
+	rsp, err := c.PostNodeIdCommandsCommandIdResult(ctx, id, commandId, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostNodeIdCommandsCommandIdResultResponse(rsp)
+}
+
+// PostNodeIdConfigAckWithBodyWithResponse request with arbitrary body returning *PostNodeIdConfigAckResponse
+func (c *ClientWithResponses) PostNodeIdConfigAckWithBodyWithResponse(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostNodeIdConfigAckResponse, error) {
+	rsp, err := c.PostNodeIdConfigAckWithBody(ctx, id, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostNodeIdConfigAckResponse(rsp)
+}
+
+func (c *ClientWithResponses) PostNodeIdConfigAckWithResponse(ctx context.Context, id string, body PostNodeIdConfigAckJSONRequestBody, reqEditors ...RequestEditorFn) (*PostNodeIdConfigAckResponse, error) {
+	rsp, err := c.PostNodeIdConfigAck(ctx, id, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostNodeIdConfigAckResponse(rsp)
+}
+
+// GetNodeIdGetConfigWithResponse request returning *GetNodeIdGetConfigResponse
+func (c *ClientWithResponses) GetNodeIdGetConfigWithResponse(ctx context.Context, id string, params *GetNodeIdGetConfigParams, reqEditors ...RequestEditorFn) (*GetNodeIdGetConfigResponse, error) {
+	rsp, err := c.GetNodeIdGetConfig(ctx, id, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetNodeIdGetConfigResponse(rsp)
+}
+
+// PostNodeIdLogsWithBodyWithResponse request with arbitrary body returning *PostNodeIdLogsResponse
+func (c *ClientWithResponses) PostNodeIdLogsWithBodyWithResponse(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostNodeIdLogsResponse, error) {
+	rsp, err := c.PostNodeIdLogsWithBody(ctx, id, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostNodeIdLogsResponse(rsp)
+}
+
+func (c *ClientWithResponses) PostNodeIdLogsWithResponse(ctx context.Context, id string, body PostNodeIdLogsJSONRequestBody, reqEditors ...RequestEditorFn) (*PostNodeIdLogsResponse, error) {
+	rsp, err := c.PostNodeIdLogs(ctx, id, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostNodeIdLogsResponse(rsp)
+}
+
+// GetNodeIdPingWithResponse request returning *GetNodeIdPingResponse
+func (c *ClientWithResponses) GetNodeIdPingWithResponse(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*GetNodeIdPingResponse, error) {
+	rsp, err := c.GetNodeIdPing(ctx, id, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetNodeIdPingResponse(rsp)
+}
+
+// PostNodeIdRotateCredentialsWithResponse request returning *PostNodeIdRotateCredentialsResponse
+func (c *ClientWithResponses) PostNodeIdRotateCredentialsWithResponse(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*PostNodeIdRotateCredentialsResponse, error) {
+	rsp, err := c.PostNodeIdRotateCredentials(ctx, id, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostNodeIdRotateCredentialsResponse(rsp)
+}
+
+// GetNodeIdSecretWithResponse request returning *GetNodeIdSecretResponse
+func (c *ClientWithResponses) GetNodeIdSecretWithResponse(ctx context.Context, id string, params *GetNodeIdSecretParams, reqEditors ...RequestEditorFn) (*GetNodeIdSecretResponse, error) {
+	rsp, err := c.GetNodeIdSecret(ctx, id, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetNodeIdSecretResponse(rsp)
+}
+
+// PostNodeIdStatusWithBodyWithResponse request with arbitrary body returning *PostNodeIdStatusResponse
+func (c *ClientWithResponses) PostNodeIdStatusWithBodyWithResponse(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostNodeIdStatusResponse, error) {
+	rsp, err := c.PostNodeIdStatusWithBody(ctx, id, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostNodeIdStatusResponse(rsp)
+}
+
+func (c *ClientWithResponses) PostNodeIdStatusWithResponse(ctx context.Context, id string, body PostNodeIdStatusJSONRequestBody, reqEditors ...RequestEditorFn) (*PostNodeIdStatusResponse, error) {
+	rsp, err := c.PostNodeIdStatus(ctx, id, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostNodeIdStatusResponse(rsp)
+}
+
+// PostNodeIdTokenWithBodyWithResponse request with arbitrary body returning *PostNodeIdTokenResponse
+func (c *ClientWithResponses) PostNodeIdTokenWithBodyWithResponse(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostNodeIdTokenResponse, error) {
+	rsp, err := c.PostNodeIdTokenWithBody(ctx, id, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostNodeIdTokenResponse(rsp)
+}
+
+func (c *ClientWithResponses) PostNodeIdTokenWithResponse(ctx context.Context, id string, body PostNodeIdTokenJSONRequestBody, reqEditors ...RequestEditorFn) (*PostNodeIdTokenResponse, error) {
+	rsp, err := c.PostNodeIdToken(ctx, id, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostNodeIdTokenResponse(rsp)
+}
+
+// ParsePostEnrollResponse parses an HTTP response from a PostEnrollWithResponse call
+func ParsePostEnrollResponse(rsp *http.Response) (*PostEnrollResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &PostEnrollResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest EnrollResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest ErrorResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON401 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseGetGithubAccessTokenResponse parses an HTTP response from a GetGithubAccessTokenWithResponse call
+func ParseGetGithubAccessTokenResponse(rsp *http.Response) (*GetGithubAccessTokenResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetGithubAccessTokenResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest GetGitHubAccessTokenResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest ErrorResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
 		}
+		response.JSON401 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseGetNodeIdCommandsResponse parses an HTTP response from a GetNodeIdCommandsWithResponse call
+func ParseGetNodeIdCommandsResponse(rsp *http.Response) (*GetNodeIdCommandsResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetNodeIdCommandsResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
 	}
-	for _, r := range additionalEditors {
-		if err := r(ctx, req); err != nil {
-			return err
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest GetNodeCommandsResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest ErrorResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
 		}
+		response.JSON401 = &dest
+
 	}
-	return nil
-}
 
-// ClientWithResponses builds on ClientInterface to offer response payloads
-type ClientWithResponses struct {
-	ClientInterface
+	return response, nil
 }
 
-// NewClientWithResponses creates a new ClientWithResponses, which wraps
-// Client with return type handling
-func NewClientWithResponses(server string, opts ...ClientOption) (*ClientWithResponses, error) {
-	client, err := NewClient(server, opts...)
+// ParsePostNodeIdCommandsCommandIdResultResponse parses an HTTP response from a PostNodeIdCommandsCommandIdResultWithResponse call
+func ParsePostNodeIdCommandsCommandIdResultResponse(rsp *http.Response) (*PostNodeIdCommandsCommandIdResultResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
-	return &ClientWithResponses{client}, nil
-}
 
-// WithBaseURL overrides the baseURL.
-func WithBaseURL(baseURL string) ClientOption {
-	return func(c *Client) error {
-		newBaseURL, err := url.Parse(baseURL)
-		if err != nil {
-			return err
-		}
-		c.Server = newBaseURL.String()
-		return nil
+	response := &PostNodeIdCommandsCommandIdResultResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
 	}
-}
 
-// ClientWithResponsesInterface is the interface specification for the client with responses above.
-type ClientWithResponsesInterface interface {
-	// GetGithubAccessTokenWithResponse request
-	GetGithubAccessTokenWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetGithubAccessTokenResponse, error)
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest ErrorResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON401 = &dest
 
-	// GetNodeIdGetConfigWithResponse request
-	GetNodeIdGetConfigWithResponse(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*GetNodeIdGetConfigResponse, error)
+	}
 
-	// GetNodeIdPingWithResponse request
-	GetNodeIdPingWithResponse(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*GetNodeIdPingResponse, error)
+	return response, nil
 }
 
-type GetGithubAccessTokenResponse struct {
-	Body         []byte
-	HTTPResponse *http.Response
-	JSON200      *GetGitHubAccessTokenResponse
-	JSON401      *ErrorResponse
-}
+// ParsePostNodeIdConfigAckResponse parses an HTTP response from a PostNodeIdConfigAckWithResponse call
+func ParsePostNodeIdConfigAckResponse(rsp *http.Response) (*PostNodeIdConfigAckResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
 
-// Status returns HTTPResponse.Status
-func (r GetGithubAccessTokenResponse) Status() string {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.Status
+	response := &PostNodeIdConfigAckResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
 	}
-	return http.StatusText(0)
-}
 
-// StatusCode returns HTTPResponse.StatusCode
-func (r GetGithubAccessTokenResponse) StatusCode() int {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.StatusCode
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest ErrorResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON401 = &dest
+
 	}
-	return 0
-}
 
-type GetNodeIdGetConfigResponse struct {
-	Body         []byte
-	HTTPResponse *http.Response
-	JSON200      *GetNodeConfigResponse
-	JSON401      *ErrorResponse
+	return response, nil
 }
 
-// Status returns HTTPResponse.Status
-func (r GetNodeIdGetConfigResponse) Status() string {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.Status
+// ParseGetNodeIdGetConfigResponse parses an HTTP response from a GetNodeIdGetConfigWithResponse call
+func ParseGetNodeIdGetConfigResponse(rsp *http.Response) (*GetNodeIdGetConfigResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
 	}
-	return http.StatusText(0)
-}
 
-// StatusCode returns HTTPResponse.StatusCode
-func (r GetNodeIdGetConfigResponse) StatusCode() int {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.StatusCode
+	response := &GetNodeIdGetConfigResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
 	}
-	return 0
-}
 
-type GetNodeIdPingResponse struct {
-	Body         []byte
-	HTTPResponse *http.Response
-	JSON200      *GetNodePingResponse
-	JSON401      *ErrorResponse
-}
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest GetNodeConfigResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
 
-// Status returns HTTPResponse.Status
-func (r GetNodeIdPingResponse) Status() string {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.Status
-	}
-	return http.StatusText(0)
-}
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest ErrorResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON401 = &dest
 
-// StatusCode returns HTTPResponse.StatusCode
-func (r GetNodeIdPingResponse) StatusCode() int {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.StatusCode
 	}
-	return 0
+
+	return response, nil
 }
 
-// GetGithubAccessTokenWithResponse request returning *GetGithubAccessTokenResponse
-func (c *ClientWithResponses) GetGithubAccessTokenWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetGithubAccessTokenResponse, error) {
-	rsp, err := c.GetGithubAccessToken(ctx, reqEditors...)
+// ParsePostNodeIdLogsResponse parses an HTTP response from a PostNodeIdLogsWithResponse call
+func ParsePostNodeIdLogsResponse(rsp *http.Response) (*PostNodeIdLogsResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
-	return ParseGetGithubAccessTokenResponse(rsp)
-}
 
-// GetNodeIdGetConfigWithResponse request returning *GetNodeIdGetConfigResponse
-func (c *ClientWithResponses) GetNodeIdGetConfigWithResponse(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*GetNodeIdGetConfigResponse, error) {
-	rsp, err := c.GetNodeIdGetConfig(ctx, id, reqEditors...)
-	if err != nil {
-		return nil, err
+	response := &PostNodeIdLogsResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
 	}
-	return ParseGetNodeIdGetConfigResponse(rsp)
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest ErrorResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON401 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 413:
+		var dest ErrorResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON413 = &dest
+
+	}
+
+	return response, nil
 }
 
-// GetNodeIdPingWithResponse request returning *GetNodeIdPingResponse
-func (c *ClientWithResponses) GetNodeIdPingWithResponse(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*GetNodeIdPingResponse, error) {
-	rsp, err := c.GetNodeIdPing(ctx, id, reqEditors...)
+// ParseGetNodeIdPingResponse parses an HTTP response from a GetNodeIdPingWithResponse call
+func ParseGetNodeIdPingResponse(rsp *http.Response) (*GetNodeIdPingResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
-	return ParseGetNodeIdPingResponse(rsp)
+
+	response := &GetNodeIdPingResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest GetNodePingResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest ErrorResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON401 = &dest
+
+	}
+
+	return response, nil
 }
 
-// ParseGetGithubAccessTokenResponse parses an HTTP response from a GetGithubAccessTokenWithResponse call
-func ParseGetGithubAccessTokenResponse(rsp *http.Response) (*GetGithubAccessTokenResponse, error) {
+// ParsePostNodeIdRotateCredentialsResponse parses an HTTP response from a PostNodeIdRotateCredentialsWithResponse call
+func ParsePostNodeIdRotateCredentialsResponse(rsp *http.Response) (*PostNodeIdRotateCredentialsResponse, error) {
 	bodyBytes, err := io.ReadAll(rsp.Body)
 	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
 
-	response := &GetGithubAccessTokenResponse{
+	response := &PostNodeIdRotateCredentialsResponse{
 		Body:         bodyBytes,
 		HTTPResponse: rsp,
 	}
 
 	switch {
 	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
-		var dest GetGitHubAccessTokenResponse
+		var dest NodeCredentials
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
 			return nil, err
 		}
@@ -492,22 +2234,22 @@ func ParseGetGithubAccessTokenResponse(rsp *http.Response) (*GetGithubAccessToke
 	return response, nil
 }
 
-// ParseGetNodeIdGetConfigResponse parses an HTTP response from a GetNodeIdGetConfigWithResponse call
-func ParseGetNodeIdGetConfigResponse(rsp *http.Response) (*GetNodeIdGetConfigResponse, error) {
+// ParseGetNodeIdSecretResponse parses an HTTP response from a GetNodeIdSecretWithResponse call
+func ParseGetNodeIdSecretResponse(rsp *http.Response) (*GetNodeIdSecretResponse, error) {
 	bodyBytes, err := io.ReadAll(rsp.Body)
 	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
 
-	response := &GetNodeIdGetConfigResponse{
+	response := &GetNodeIdSecretResponse{
 		Body:         bodyBytes,
 		HTTPResponse: rsp,
 	}
 
 	switch {
 	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
-		var dest GetNodeConfigResponse
+		var dest GetSecretResponse
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
 			return nil, err
 		}
@@ -520,27 +2262,60 @@ func ParseGetNodeIdGetConfigResponse(rsp *http.Response) (*GetNodeIdGetConfigRes
 		}
 		response.JSON401 = &dest
 
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 404:
+		var dest ErrorResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON404 = &dest
+
 	}
 
 	return response, nil
 }
 
-// ParseGetNodeIdPingResponse parses an HTTP response from a GetNodeIdPingWithResponse call
-func ParseGetNodeIdPingResponse(rsp *http.Response) (*GetNodeIdPingResponse, error) {
+// ParsePostNodeIdStatusResponse parses an HTTP response from a PostNodeIdStatusWithResponse call
+func ParsePostNodeIdStatusResponse(rsp *http.Response) (*PostNodeIdStatusResponse, error) {
 	bodyBytes, err := io.ReadAll(rsp.Body)
 	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
 
-	response := &GetNodeIdPingResponse{
+	response := &PostNodeIdStatusResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 401:
+		var dest ErrorResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON401 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParsePostNodeIdTokenResponse parses an HTTP response from a PostNodeIdTokenWithResponse call
+func ParsePostNodeIdTokenResponse(rsp *http.Response) (*PostNodeIdTokenResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &PostNodeIdTokenResponse{
 		Body:         bodyBytes,
 		HTTPResponse: rsp,
 	}
 
 	switch {
 	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
-		var dest GetNodePingResponse
+		var dest NodeCredentials
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
 			return nil, err
 		}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"vinr.eu/vanguard/internal/citadel"
+	"vinr.eu/vanguard/internal/redact"
+)
+
+// These tests run the vanguard Citadel client against the mock, using its
+// fault rules to exercise retries, the circuit breaker and error decoding.
+
+const pingPath = "/node/*/ping"
+
+func startMock(t *testing.T, faults ...*Fault) (*Server, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	s := NewServer()
+	s.faultRules = faults
+	srv := httptest.NewServer(s.router(gin.Recovery()))
+	t.Cleanup(srv.Close)
+	return s, srv.URL
+}
+
+func newClient(t *testing.T, url string, opts ...citadel.Option) *citadel.Client {
+	t.Helper()
+	c, err := citadel.NewClient(url, append([]citadel.Option{
+		citadel.WithAPIKey("node-1-key"),
+		citadel.WithNodeID("node-1"),
+		citadel.WithTimeout(time.Second),
+		citadel.WithRetryPolicy(citadel.RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}),
+	}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// calls counts the recorded calls to pingPath, and how many hit a fault.
+func calls(s *Server) (total, faulted int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.requests {
+		if r.Method == http.MethodGet && r.Path == "/node/node-1/ping" {
+			total++
+			if r.Fault != "" {
+				faulted++
+			}
+		}
+	}
+	return total, faulted
+}
+
+func TestRetryTransientFaults(t *testing.T) {
+	tests := []struct {
+		name  string
+		fault *Fault
+	}{
+		{"service unavailable", &Fault{ID: "f", Path: pingPath, Status: http.StatusServiceUnavailable, Count: 2}},
+		{"too many requests", &Fault{ID: "f", Path: pingPath, Status: http.StatusTooManyRequests, Count: 2}},
+		{"dropped connection", &Fault{ID: "f", Path: pingPath, Drop: true, Count: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, url := startMock(t, tt.fault)
+			c := newClient(t, url)
+			if _, err := c.Ping(context.Background(), "node-1"); err != nil {
+				t.Fatalf("Ping: %v", err)
+			}
+			if total, faulted := calls(s); total != 3 || faulted != 2 {
+				t.Errorf("calls = %d (%d faulted), want 3 (2 faulted)", total, faulted)
+			}
+		})
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	s, url := startMock(t, &Fault{ID: "f", Path: pingPath, Status: http.StatusBadGateway})
+	c := newClient(t, url, citadel.WithCircuitBreaker(0, 0))
+	_, err := c.Ping(context.Background(), "node-1")
+	if !errors.Is(err, citadel.ErrApiFailure) {
+		t.Fatalf("err = %v, want ErrApiFailure", err)
+	}
+	if total, _ := calls(s); total != 4 {
+		t.Errorf("calls = %d, want MaxAttempts 4", total)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	s, url := startMock(t, &Fault{ID: "f", Path: pingPath, Status: http.StatusServiceUnavailable, RetryAfter: 1, Count: 1})
+	c := newClient(t, url, citadel.WithRetryPolicy(citadel.RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Second}))
+	start := time.Now()
+	if _, err := c.Ping(context.Background(), "node-1"); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want Retry-After of 1s", elapsed)
+	}
+	if total, _ := calls(s); total != 2 {
+		t.Errorf("calls = %d, want 2", total)
+	}
+}
+
+func TestRetryAfterBeyondLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxDelay time.Duration
+		timeout  time.Duration
+	}{
+		{"max delay", 5 * time.Millisecond, 0},
+		{"context deadline", time.Hour, 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, url := startMock(t, &Fault{ID: "f", Path: pingPath, Status: http.StatusServiceUnavailable, RetryAfter: 3600})
+			c := newClient(t, url, citadel.WithRetryPolicy(citadel.RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: tt.maxDelay}))
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			start := time.Now()
+			_, err := c.Ping(ctx, "node-1")
+			if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+				t.Errorf("Ping took %v, want it to give up at once", elapsed)
+			}
+			var apiErr *citadel.APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+				t.Fatalf("err = %v, want the 503 from Citadel", err)
+			}
+			if total, _ := calls(s); total != 1 {
+				t.Errorf("calls = %d, want 1", total)
+			}
+		})
+	}
+}
+
+func TestAPIErrorDecoding(t *testing.T) {
+	tests := []struct {
+		status   int
+		sentinel error
+	}{
+		{http.StatusUnauthorized, citadel.ErrUnauthorized},
+		{http.StatusForbidden, citadel.ErrUnauthorized},
+		{http.StatusNotFound, citadel.ErrNotFound},
+		{http.StatusConflict, citadel.ErrApiFailure},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			s, url := startMock(t, &Fault{ID: "f", Path: pingPath, Status: tt.status, Message: "node is gone"})
+			c := newClient(t, url)
+			_, err := c.Ping(context.Background(), "node-1")
+			if !errors.Is(err, tt.sentinel) {
+				t.Fatalf("err = %v, want %v", err, tt.sentinel)
+			}
+			var apiErr *citadel.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %T, want *citadel.APIError", err)
+			}
+			if apiErr.Operation != "Ping" || apiErr.StatusCode != tt.status || apiErr.Code != tt.status || apiErr.Message != "node is gone" {
+				t.Errorf("APIError = %+v", apiErr)
+			}
+			if total, _ := calls(s); total != 1 {
+				t.Errorf("calls = %d, client errors must not be retried", total)
+			}
+		})
+	}
+}
+
+func TestRedactsReportedErrors(t *testing.T) {
+	s, url := startMock(t)
+	r := redact.New()
+	r.Add("hunter2-secret")
+	c := newClient(t, url, citadel.WithRedactor(r))
+	ctx := context.Background()
+	failure := errors.New("connect postgres://app:hunter2-secret@db/app: refused")
+	if err := c.AckConfig(ctx, "node-1", "rev-1", failure); err != nil {
+		t.Fatalf("AckConfig: %v", err)
+	}
+	now := time.Now()
+	result := citadel.CommandResult{
+		StartedAt:  now,
+		FinishedAt: now,
+		Err:        failure,
+		Logs:       []citadel.LogLine{{Time: now, Stream: "stdout", Text: "password=hunter2-secret"}},
+	}
+	if err := c.PostCommandResult(ctx, "node-1", "cmd-1", result); err != nil {
+		t.Fatalf("PostCommandResult: %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) != 2 {
+		t.Fatalf("recorded %d requests, want 2", len(s.requests))
+	}
+	for _, req := range s.requests {
+		if strings.Contains(req.Body, "hunter2-secret") || !strings.Contains(req.Body, redact.Mask) {
+			t.Errorf("%s body = %s, want the secret masked", req.Path, req.Body)
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	const cooldown = 100 * time.Millisecond
+	s, url := startMock(t, &Fault{ID: "f", Path: pingPath, Status: http.StatusInternalServerError})
+	c := newClient(t, url,
+		citadel.WithRetryPolicy(citadel.RetryPolicy{MaxAttempts: 1}),
+		citadel.WithCircuitBreaker(2, cooldown),
+	)
+	ctx := context.Background()
+	for range 2 {
+		if _, err := c.Ping(ctx, "node-1"); !errors.Is(err, citadel.ErrApiFailure) {
+			t.Fatalf("err = %v, want ErrApiFailure", err)
+		}
+	}
+
+	// Open: calls fail fast without reaching Citadel.
+	if _, err := c.Ping(ctx, "node-1"); !errors.Is(err, citadel.ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if total, _ := calls(s); total != 2 {
+		t.Fatalf("calls = %d, the open breaker let a call through", total)
+	}
+
+	// Half-open: a failed trial opens the breaker again.
+	time.Sleep(cooldown + 20*time.Millisecond)
+	if _, err := c.Ping(ctx, "node-1"); !errors.Is(err, citadel.ErrApiFailure) {
+		t.Fatalf("trial err = %v, want ErrApiFailure", err)
+	}
+	if _, err := c.Ping(ctx, "node-1"); !errors.Is(err, citadel.ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen after failed trial", err)
+	}
+
+	// Half-open: a successful trial closes it.
+	s.mu.Lock()
+	s.faultRules = nil
+	s.mu.Unlock()
+	time.Sleep(cooldown + 20*time.Millisecond)
+	for range 3 {
+		if _, err := c.Ping(ctx, "node-1"); err != nil {
+			t.Fatalf("Ping after recovery: %v", err)
+		}
+	}
+	if total, _ := calls(s); total != 6 {
+		t.Errorf("calls = %d, want 6", total)
+	}
+}
+
+func TestRotationFromAnotherProcess(t *testing.T) {
+	_, url := startMock(t)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	anon, err := citadel.NewClient(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enrolled, err := anon.Enroll(ctx, "enroll-me", path)
+	if err != nil {
+		t.Fatalf("Enroll: %v", err)
+	}
+	nodeID := enrolled.NodeID()
+
+	// The daemon holds the refresh token in memory, and needs a new access
+	// token after the CLI has rotated it.
+	daemonCreds, err := citadel.LoadCredentials(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemon, err := citadel.NewClient(url, citadel.WithCredentials(daemonCreds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cliCreds, err := citadel.LoadCredentials(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli, err := citadel.NewClient(url, citadel.WithCredentials(cliCreds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.RotateCredentials(ctx); err != nil {
+		t.Fatalf("RotateCredentials: %v", err)
+	}
+
+	if _, err := daemon.Ping(ctx, nodeID); err != nil {
+		t.Fatalf("Ping after rotation: %v", err)
+	}
+}
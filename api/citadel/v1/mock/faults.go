@@ -0,0 +1,174 @@
+package main
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ctxKeyFault = "mock.fault"
+
+// Fault is an injection rule for API calls, e.g.
+// {"path": "/node/*/ping", "status": 401, "count": 2}.
+type Fault struct {
+	ID string `json:"id"`
+	// Method and Path narrow the rule; Path is a path.Match pattern. Empty
+	// values match every call.
+	Method string `json:"method,omitempty"`
+	Path   string `json:"path,omitempty"`
+	// Status answers the call with an ErrorResponse; zero lets it through
+	// unless Drop is set, which closes the connection instead.
+	Status  int    `json:"status,omitempty"`
+	Drop    bool   `json:"drop,omitempty"`
+	Message string `json:"message,omitempty"`
+	// RetryAfter sets the Retry-After header, in seconds, of Status answers.
+	RetryAfter int `json:"retryAfter,omitempty"`
+	// Latency is a duration such as "2s" added before answering.
+	Latency string `json:"latency,omitempty"`
+	// Rate is the probability that a matching call is hit; zero means always.
+	Rate float64 `json:"rate,omitempty"`
+	// Count is how many more calls the rule hits; zero means unlimited.
+	Count int `json:"count,omitempty"`
+
+	latency time.Duration
+}
+
+func (f *Fault) matches(r *http.Request) bool {
+	if f.Method != "" && !strings.EqualFold(f.Method, r.Method) {
+		return false
+	}
+	if f.Path != "" {
+		if ok, _ := path.Match(f.Path, r.URL.Path); !ok {
+			return false
+		}
+	}
+	return f.Rate <= 0 || rand.Float64() < f.Rate
+}
+
+// envFaults turns the MOCK_FAULT_RATE, MOCK_FAULT_STATUS (0 drops the
+// connection) and MOCK_LATENCY variables into rules.
+func envFaults() []*Fault {
+	var faults []*Fault
+	if latency, err := time.ParseDuration(os.Getenv("MOCK_LATENCY")); err == nil && latency > 0 {
+		faults = append(faults, &Fault{ID: "env-latency", Latency: latency.String(), latency: latency})
+	}
+	if rate, _ := strconv.ParseFloat(os.Getenv("MOCK_FAULT_RATE"), 64); rate > 0 {
+		f := &Fault{ID: "env-fault", Status: http.StatusServiceUnavailable, Rate: rate}
+		if v, err := strconv.Atoi(os.Getenv("MOCK_FAULT_STATUS")); err == nil {
+			f.Status = v
+		}
+		f.Drop = f.Status == 0
+		faults = append(faults, f)
+	}
+	return faults
+}
+
+// faults applies the first matching rule with an outcome; latency-only rules
+// add up and let the call continue to the next rule.
+func (s *Server) faults() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isAdmin(c) {
+			c.Next()
+			return
+		}
+		var delay time.Duration
+		var hit *Fault
+		s.mu.Lock()
+		for _, f := range s.faultRules {
+			if !f.matches(c.Request) {
+				continue
+			}
+			if f.Count > 0 {
+				f.Count--
+				if f.Count == 0 {
+					s.faultRules = slices.DeleteFunc(s.faultRules, func(x *Fault) bool { return x == f })
+				}
+			}
+			delay += f.latency
+			if f.Status != 0 || f.Drop {
+				hit = f
+				break
+			}
+		}
+		s.mu.Unlock()
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-c.Request.Context().Done():
+				c.Abort()
+				return
+			}
+		}
+		if hit == nil {
+			c.Next()
+			return
+		}
+		c.Set(ctxKeyFault, hit.ID)
+		if hit.Drop {
+			if conn, _, err := http.NewResponseController(c.Writer).Hijack(); err == nil {
+				conn.Close()
+			}
+			c.Abort()
+			return
+		}
+		msg := hit.Message
+		if msg == "" {
+			msg = "injected fault"
+		}
+		if hit.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(hit.RetryAfter))
+		}
+		c.AbortWithStatusJSON(hit.Status, ErrorResponse{Code: hit.Status, Message: msg})
+	}
+}
+
+func (s *Server) addFault(c *gin.Context) {
+	var f Fault
+	if err := c.ShouldBindJSON(&f); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	if f.Latency != "" {
+		d, err := time.ParseDuration(f.Latency)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		f.latency = d
+	}
+	if _, err := path.Match(f.Path, ""); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	f.ID = randomToken()[:8]
+	s.mu.Lock()
+	s.faultRules = append(s.faultRules, &f)
+	s.mu.Unlock()
+	c.JSON(http.StatusCreated, f)
+}
+
+func (s *Server) listFaults(c *gin.Context) {
+	s.mu.Lock()
+	out := make([]Fault, len(s.faultRules))
+	for i, f := range s.faultRules {
+		out[i] = *f
+	}
+	s.mu.Unlock()
+	c.JSON(http.StatusOK, out)
+}
+
+// deleteFaults removes one rule by ?id= or all of them.
+func (s *Server) deleteFaults(c *gin.Context) {
+	id := c.Query("id")
+	s.mu.Lock()
+	s.faultRules = slices.DeleteFunc(s.faultRules, func(f *Fault) bool { return id == "" || f.ID == id })
+	s.mu.Unlock()
+	c.Status(http.StatusNoContent)
+}
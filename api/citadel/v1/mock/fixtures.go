@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-yaml"
+)
+
+// defaultFixture names the fixture served to nodes without one of their own.
+const defaultFixture = "default"
+
+// Fixture describes how the mock answers a single node. Every field is
+// optional; missing ones fall back to the built-in behaviour.
+type Fixture struct {
+	NodeID string `json:"nodeId,omitempty"`
+	// APIKey identifies the node on calls without a node ID in the path.
+	APIKey string `json:"apiKey,omitempty"`
+	// EnrollmentToken enrolls a node under NodeID instead of a random ID.
+	EnrollmentToken string                 `json:"enrollmentToken,omitempty"`
+	GitHubToken     string                 `json:"githubToken,omitempty"`
+	Ping            *FixturePing           `json:"ping,omitempty"`
+	Config          *GetNodeConfigResponse `json:"config,omitempty"`
+	// Secrets are served to citadel/ refs by name.
+	Secrets map[string]string `json:"secrets,omitempty"`
+}
+
+type FixturePing struct {
+	Status  string `json:"status,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// loadFixtures reads one fixture per .yaml, .yml or .json file from path,
+// which may be a directory or a single file. A fixture without a nodeId is
+// keyed by its file name.
+func loadFixtures(path string) (map[string]*Fixture, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, e := range entries {
+			switch filepath.Ext(e.Name()) {
+			case ".yaml", ".yml", ".json":
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+	fixtures := make(map[string]*Fixture, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		f, err := decodeFixture(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if f.NodeID == "" {
+			f.NodeID = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		}
+		fixtures[f.NodeID] = f
+	}
+	return fixtures, nil
+}
+
+// decodeFixture accepts YAML or JSON; the generated API types only carry
+// JSON tags, so YAML is converted first.
+func decodeFixture(data []byte) (*Fixture, error) {
+	data, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// builtinFixture is served when the mock runs without fixtures.
+func builtinFixture() *Fixture {
+	ingressHost := "vinr.local"
+	runScript := "npm run dev"
+	port := "3000"
+	return &Fixture{
+		Config: &GetNodeConfigResponse{
+			Type: Vanguard,
+			ServiceDeployments: []ServiceDeployment{
+				{
+					Kind:        "service",
+					DefVersion:  "v1",
+					Name:        "next-js-app",
+					GitUrl:      "https://github.com/richy-vinr/next-js-app",
+					Branch:      "main",
+					Port:        3000,
+					IngressHost: &ingressHost,
+					RunScript:   &runScript,
+					Runtime: RuntimeSpec{
+						Engine:  "node",
+						Version: "24.13.1",
+					},
+					Variables: &[]EnvironmentVariable{
+						{
+							Name:  "PORT",
+							Value: &port,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// fixture returns the fixture for a node: its own, the default one, or the
+// built-in one when no fixtures are loaded at all.
+func (s *Server) fixture(nodeID string) (*Fixture, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.fixtures[nodeID]; ok {
+		return f, true
+	}
+	if f, ok := s.fixtures[defaultFixture]; ok {
+		return f, true
+	}
+	if len(s.fixtures) == 0 {
+		return builtinFixture(), true
+	}
+	return nil, false
+}
+
+// callerNode identifies the node behind a call from its access token or API
+// key.
+func (s *Server) callerNode(c *gin.Context) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		return s.tokenNode(token)
+	}
+	if key := c.GetHeader("x-api-key"); key != "" {
+		for id, f := range s.fixtures {
+			if f.APIKey == key {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// setFixtures must be called with s.mu held.
+func (s *Server) setFixtures(fixtures map[string]*Fixture) {
+	s.fixtures = fixtures
+	for _, f := range fixtures {
+		if f.EnrollmentToken != "" {
+			s.enrollmentTokens[f.EnrollmentToken] = f.NodeID
+		}
+	}
+	s.bump()
+}
+
+// putFixture replaces the fixture of a node with the YAML or JSON body.
+func (s *Server) putFixture(c *gin.Context) {
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	f, err := decodeFixture(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	f.NodeID = c.Param("id")
+	s.mu.Lock()
+	fixtures := make(map[string]*Fixture, len(s.fixtures)+1)
+	for id, existing := range s.fixtures {
+		fixtures[id] = existing
+	}
+	fixtures[f.NodeID] = f
+	s.setFixtures(fixtures)
+	revision := s.revision
+	s.mu.Unlock()
+	c.JSON(http.StatusOK, gin.H{"revision": fmt.Sprint(revision)})
+}
+
+// reloadFixtures re-reads MOCK_FIXTURES from disk.
+func (s *Server) reloadFixtures(c *gin.Context) {
+	if s.fixturePath == "" {
+		c.JSON(http.StatusConflict, ErrorResponse{Code: http.StatusConflict, Message: "MOCK_FIXTURES is not set"})
+		return
+	}
+	fixtures, err := loadFixtures(s.fixturePath)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	s.mu.Lock()
+	s.setFixtures(fixtures)
+	revision := s.revision
+	s.mu.Unlock()
+	c.JSON(http.StatusOK, gin.H{"revision": fmt.Sprint(revision), "fixtures": len(fixtures)})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxRecorded     = 1000
+	maxRecordedBody = 1 << 20
+)
+
+type RecordedRequest struct {
+	Time    time.Time   `json:"time"`
+	Method  string      `json:"method"`
+	Path    string      `json:"path"`
+	Query   string      `json:"query,omitempty"`
+	Headers http.Header `json:"headers"`
+	// Body is decompressed when the request was gzip-encoded.
+	Body   string `json:"body,omitempty"`
+	Status int    `json:"status"`
+	Fault  string `json:"fault,omitempty"`
+}
+
+func isAdmin(c *gin.Context) bool {
+	return strings.HasPrefix(c.Request.URL.Path, "/_mock/")
+}
+
+// record keeps the most recent API calls for tests to assert on.
+func (s *Server) record() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isAdmin(c) {
+			c.Next()
+			return
+		}
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = io.ReadAll(io.LimitReader(c.Request.Body, maxRecordedBody))
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		if c.GetHeader("Content-Encoding") == "gzip" {
+			if zr, err := gzip.NewReader(bytes.NewReader(body)); err == nil {
+				if plain, err := io.ReadAll(io.LimitReader(zr, maxRecordedBody)); err == nil {
+					body = plain
+				}
+			}
+		}
+		req := RecordedRequest{
+			Time:    time.Now(),
+			Method:  c.Request.Method,
+			Path:    c.Request.URL.Path,
+			Query:   c.Request.URL.RawQuery,
+			Headers: c.Request.Header.Clone(),
+			Body:    string(body),
+		}
+		c.Next()
+		req.Status = c.Writer.Status()
+		req.Fault = c.GetString(ctxKeyFault)
+		s.mu.Lock()
+		s.requests = append(s.requests, req)
+		if len(s.requests) > maxRecorded {
+			s.requests = s.requests[len(s.requests)-maxRecorded:]
+		}
+		s.mu.Unlock()
+	}
+}
+
+// listRequests returns recorded calls, optionally filtered by method and a
+// path.Match pattern, e.g. ?method=GET&path=/node/*/ping.
+func (s *Server) listRequests(c *gin.Context) {
+	method, pattern := c.Query("method"), c.Query("path")
+	s.mu.Lock()
+	out := make([]RecordedRequest, 0, len(s.requests))
+	for _, req := range s.requests {
+		if method != "" && !strings.EqualFold(method, req.Method) {
+			continue
+		}
+		if pattern != "" {
+			if ok, _ := path.Match(pattern, req.Path); !ok {
+				continue
+			}
+		}
+		out = append(out, req)
+	}
+	s.mu.Unlock()
+	c.JSON(http.StatusOK, out)
+}
+
+func (s *Server) clearRequests(c *gin.Context) {
+	s.mu.Lock()
+	s.requests = nil
+	s.mu.Unlock()
+	c.Status(http.StatusNoContent)
+}
@@ -14,6 +14,52 @@ import (
 
 const (
 	ApiKeyAuthScopes = "ApiKeyAuth.Scopes"
+	BearerAuthScopes = "BearerAuth.Scopes"
+)
+
+// Defines values for CommandStatus.
+const (
+	CommandStatusFailed    CommandStatus = "failed"
+	CommandStatusSucceeded CommandStatus = "succeeded"
+)
+
+// Defines values for CommandType.
+const (
+	CommandTypeDiagnose CommandType = "diagnose"
+	CommandTypeLogs     CommandType = "logs"
+	CommandTypeRedeploy CommandType = "redeploy"
+	CommandTypeRestart  CommandType = "restart"
+)
+
+// Defines values for ConfigAckStatus.
+const (
+	ConfigAckStatusApplied ConfigAckStatus = "applied"
+	ConfigAckStatusFailed  ConfigAckStatus = "failed"
+)
+
+// Defines values for EnvironmentVariableMode.
+const (
+	Expand EnvironmentVariableMode = "expand"
+	Raw    EnvironmentVariableMode = "raw"
+)
+
+// Defines values for EnvironmentVariableOnChange.
+const (
+	EnvironmentVariableOnChangeNone    EnvironmentVariableOnChange = "none"
+	EnvironmentVariableOnChangeRestart EnvironmentVariableOnChange = "restart"
+	EnvironmentVariableOnChangeSignal  EnvironmentVariableOnChange = "signal"
+)
+
+// Defines values for LogEntryStream.
+const (
+	LogEntryStreamStderr LogEntryStream = "stderr"
+	LogEntryStreamStdout LogEntryStream = "stdout"
+)
+
+// Defines values for LogLineStream.
+const (
+	LogLineStreamStderr LogLineStream = "stderr"
+	LogLineStreamStdout LogLineStream = "stdout"
 )
 
 // Defines values for NodeType.
@@ -23,17 +69,159 @@ const (
 	Vanguard NodeType = "vanguard"
 )
 
+// Defines values for ServiceHealth.
+const (
+	Healthy   ServiceHealth = "healthy"
+	Unhealthy ServiceHealth = "unhealthy"
+	Unknown   ServiceHealth = "unknown"
+)
+
+// Defines values for ServiceState.
+const (
+	Exited  ServiceState = "exited"
+	Failed  ServiceState = "failed"
+	Pending ServiceState = "pending"
+	Running ServiceState = "running"
+	Stopped ServiceState = "stopped"
+)
+
+// APIKeyAuth defines model for APIKeyAuth.
+type APIKeyAuth struct {
+	Header *string       `json:"header,omitempty"`
+	Keys   []SecretValue `json:"keys"`
+}
+
+// BasicAuth defines model for BasicAuth.
+type BasicAuth struct {
+	Realm *string     `json:"realm,omitempty"`
+	Users []BasicUser `json:"users"`
+}
+
+// BasicUser defines model for BasicUser.
+type BasicUser struct {
+	// Password A literal value or a reference resolved by the node.
+	Password SecretValue `json:"password"`
+	Username string      `json:"username"`
+}
+
+// BodyLimitMiddleware defines model for BodyLimitMiddleware.
+type BodyLimitMiddleware struct {
+	MaxBytes int64 `json:"maxBytes"`
+}
+
+// CORSMiddleware defines model for CORSMiddleware.
+type CORSMiddleware struct {
+	AllowCredentials *bool     `json:"allowCredentials,omitempty"`
+	AllowHeaders     *[]string `json:"allowHeaders,omitempty"`
+	AllowMethods     *[]string `json:"allowMethods,omitempty"`
+	AllowOrigins     []string  `json:"allowOrigins"`
+	ExposeHeaders    *[]string `json:"exposeHeaders,omitempty"`
+	MaxAge           *int      `json:"maxAge,omitempty"`
+}
+
+// Command defines model for Command.
+type Command struct {
+	// Commit Commit to redeploy; the branch head when absent
+	Commit *string `json:"commit,omitempty"`
+
+	// Id Unique ID of the command
+	Id       string    `json:"id"`
+	IssuedAt time.Time `json:"issuedAt"`
+
+	// Lines Number of log lines to return
+	Lines *int `json:"lines,omitempty"`
+
+	// Service Target service for restart, redeploy and logs
+	Service *string     `json:"service,omitempty"`
+	Type    CommandType `json:"type"`
+}
+
+// CommandResult defines model for CommandResult.
+type CommandResult struct {
+	Diagnostics *Diagnostics `json:"diagnostics,omitempty"`
+
+	// Error Why the command failed
+	Error      *string   `json:"error,omitempty"`
+	FinishedAt time.Time `json:"finishedAt"`
+
+	// Logs Log lines returned by a logs command, oldest first
+	Logs      *[]LogLine    `json:"logs,omitempty"`
+	StartedAt time.Time     `json:"startedAt"`
+	Status    CommandStatus `json:"status"`
+}
+
+// CommandStatus defines model for CommandStatus.
+type CommandStatus string
+
+// CommandType defines model for CommandType.
+type CommandType string
+
+// CompressMiddleware defines model for CompressMiddleware.
+type CompressMiddleware struct {
+	Algorithms   *[]string `json:"algorithms,omitempty"`
+	ContentTypes *[]string `json:"contentTypes,omitempty"`
+	MinSize      *int      `json:"minSize,omitempty"`
+}
+
+// ConfigAckStatus defines model for ConfigAckStatus.
+type ConfigAckStatus string
+
+// Diagnostics defines model for Diagnostics.
+type Diagnostics struct {
+	Goroutines int    `json:"goroutines"`
+	Hostname   string `json:"hostname"`
+
+	// MemoryBytes Memory obtained from the OS by the agent
+	MemoryBytes   int64           `json:"memoryBytes"`
+	Services      []ServiceStatus `json:"services"`
+	UptimeSeconds int             `json:"uptimeSeconds"`
+
+	// Version Go runtime version of the agent
+	Version string `json:"version"`
+}
+
+// EnrollRequest defines model for EnrollRequest.
+type EnrollRequest struct {
+	// EnrollmentToken One-time token issued by Citadel for this node
+	EnrollmentToken string `json:"enrollmentToken"`
+
+	// Hostname Hostname of the machine enrolling
+	Hostname *string `json:"hostname,omitempty"`
+}
+
+// EnrollResponse defines model for EnrollResponse.
+type EnrollResponse struct {
+	Credentials NodeCredentials `json:"credentials"`
+
+	// NodeId Unique ID assigned to the node
+	NodeId string `json:"nodeId"`
+}
+
 // EnvironmentVariable defines model for EnvironmentVariable.
 type EnvironmentVariable struct {
-	Name string `json:"name"`
+	// Keys Maps dotted JSON paths of the secret to variable names. Only mapped fields are set.
+	Keys *map[string]string `json:"keys,omitempty"`
+
+	// Mode How a JSON object secret becomes variables: 'expand' adds one NAME_KEY variable per key, 'raw' keeps the JSON as the value.
+	Mode *EnvironmentVariableMode `json:"mode,omitempty"`
+	Name string                   `json:"name"`
+
+	// OnChange What happens to the running service when the secret behind ref changes: 'restart' restarts it, 'signal' sends it SIGHUP and 'none' only applies the new value on its next start.
+	OnChange *EnvironmentVariableOnChange `json:"onChange,omitempty"`
 
-	// Ref Reference to an external secret store path.
+	// Ref Reference to an external secret store path. A '#' suffix selects a field of a JSON secret by its dotted path, e.g. '#db.password'.
 	Ref *string `json:"ref,omitempty"`
 
 	// Value Literal string value.
 	Value *string `json:"value,omitempty"`
 }
 
+// EnvironmentVariableMode How a JSON object secret becomes variables: 'expand' adds one NAME_KEY variable per key, 'raw' keeps the JSON as the value.
+type EnvironmentVariableMode string
+
+// EnvironmentVariableOnChange What happens to the running service when the secret behind ref changes: 'restart' restarts it, 'signal' sends it SIGHUP and 'none' only applies the new value on its next start.
+type EnvironmentVariableOnChange string
+
 // ErrorResponse defines model for ErrorResponse.
 type ErrorResponse struct {
 	Code    int    `json:"code"`
@@ -44,10 +232,21 @@ type ErrorResponse struct {
 type GetGitHubAccessTokenResponse struct {
 	// AccessToken The GitHub access token
 	AccessToken string `json:"accessToken"`
+
+	// ExpiresAt When the access token stops being valid. Absent for tokens without a known expiry.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// GetNodeCommandsResponse defines model for GetNodeCommandsResponse.
+type GetNodeCommandsResponse struct {
+	Commands []Command `json:"commands"`
 }
 
 // GetNodeConfigResponse defines model for GetNodeConfigResponse.
 type GetNodeConfigResponse struct {
+	// Revision Opaque identifier of this configuration revision
+	Revision string `json:"revision"`
+
 	// ServiceDeployments A list of all services assigned to this node with their final overridden configurations.
 	ServiceDeployments []ServiceDeployment `json:"serviceDeployments"`
 	Type               NodeType            `json:"type"`
@@ -65,44 +264,310 @@ type GetNodePingResponse struct {
 	Version *string `json:"version,omitempty"`
 }
 
+// GetSecretResponse defines model for GetSecretResponse.
+type GetSecretResponse struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+
+	// Version Changes whenever the secret is rotated
+	Version *string `json:"version,omitempty"`
+}
+
+// HeaderRules defines model for HeaderRules.
+type HeaderRules struct {
+	Add    *map[string]string `json:"add,omitempty"`
+	Remove *[]string          `json:"remove,omitempty"`
+	Set    *map[string]string `json:"set,omitempty"`
+}
+
+// HeadersMiddleware defines model for HeadersMiddleware.
+type HeadersMiddleware struct {
+	Request  *HeaderRules `json:"request,omitempty"`
+	Response *HeaderRules `json:"response,omitempty"`
+}
+
+// IngestLogsRequest defines model for IngestLogsRequest.
+type IngestLogsRequest struct {
+	// BatchId Unique ID of the batch, stable across retries
+	BatchId string     `json:"batchId"`
+	Entries []LogEntry `json:"entries"`
+	NodeId  string     `json:"nodeId"`
+}
+
+// Ingress defines model for Ingress.
+type Ingress struct {
+	Auth        *IngressAuth  `json:"auth,omitempty"`
+	Middlewares *[]Middleware `json:"middlewares,omitempty"`
+}
+
+// IngressAuth defines model for IngressAuth.
+type IngressAuth struct {
+	ApiKey      *APIKeyAuth `json:"apiKey,omitempty"`
+	Basic       *BasicAuth  `json:"basic,omitempty"`
+	IpAllowlist *[]string   `json:"ipAllowlist,omitempty"`
+	Oidc        *OIDCAuth   `json:"oidc,omitempty"`
+}
+
+// LogEntry defines model for LogEntry.
+type LogEntry struct {
+	Service string         `json:"service"`
+	Stream  LogEntryStream `json:"stream"`
+	Text    string         `json:"text"`
+	Time    time.Time      `json:"time"`
+}
+
+// LogEntryStream defines model for LogEntry.Stream.
+type LogEntryStream string
+
+// LogLine defines model for LogLine.
+type LogLine struct {
+	Stream LogLineStream `json:"stream"`
+	Text   string        `json:"text"`
+	Time   time.Time     `json:"time"`
+}
+
+// LogLineStream defines model for LogLine.Stream.
+type LogLineStream string
+
+// Middleware Exactly one of the properties is set.
+type Middleware struct {
+	BodyLimit *BodyLimitMiddleware `json:"bodyLimit,omitempty"`
+	Compress  *CompressMiddleware  `json:"compress,omitempty"`
+	Cors      *CORSMiddleware      `json:"cors,omitempty"`
+	Headers   *HeadersMiddleware   `json:"headers,omitempty"`
+	RateLimit *RateLimitMiddleware `json:"rateLimit,omitempty"`
+	Timeout   *TimeoutMiddleware   `json:"timeout,omitempty"`
+}
+
+// NodeCredentials defines model for NodeCredentials.
+type NodeCredentials struct {
+	// AccessToken Signed bearer token for API calls
+	AccessToken string `json:"accessToken"`
+
+	// ExpiresAt When the access token expires
+	ExpiresAt time.Time `json:"expiresAt"`
+
+	// RefreshToken Long-lived token to obtain new access tokens. Only present when it was issued or rotated.
+	RefreshToken *string `json:"refreshToken,omitempty"`
+}
+
 // NodeType defines model for NodeType.
 type NodeType string
 
-// ServiceDeployment defines model for ServiceDeployment.
+// OIDCAuth defines model for OIDCAuth.
+type OIDCAuth struct {
+	AllowedDomains *[]string `json:"allowedDomains,omitempty"`
+	AllowedEmails  *[]string `json:"allowedEmails,omitempty"`
+	ClientID       string    `json:"clientID"`
+
+	// ClientSecret A literal value or a reference resolved by the node.
+	ClientSecret SecretValue `json:"clientSecret"`
+
+	// CookieSecret A literal value or a reference resolved by the node.
+	CookieSecret SecretValue `json:"cookieSecret"`
+	IssuerURL    string      `json:"issuerURL"`
+	RedirectURL  *string     `json:"redirectURL,omitempty"`
+	Scopes       *[]string   `json:"scopes,omitempty"`
+}
+
+// PostConfigAckRequest defines model for PostConfigAckRequest.
+type PostConfigAckRequest struct {
+	AppliedAt time.Time `json:"appliedAt"`
+
+	// Error Why the revision was not fully applied
+	Error *string `json:"error,omitempty"`
+
+	// Revision The revision the node attempted to apply
+	Revision string          `json:"revision"`
+	Status   ConfigAckStatus `json:"status"`
+}
+
+// PostNodeStatusRequest defines model for PostNodeStatusRequest.
+type PostNodeStatusRequest struct {
+	// ReportedAt The node time when the report was taken
+	ReportedAt time.Time `json:"reportedAt"`
+
+	// Services Status of every service assigned to the node.
+	Services []ServiceStatus `json:"services"`
+}
+
+// RateLimitMiddleware defines model for RateLimitMiddleware.
+type RateLimitMiddleware struct {
+	Burst             *int    `json:"burst,omitempty"`
+	KeyHeader         *string `json:"keyHeader,omitempty"`
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+}
+
+// RefreshTokenRequest defines model for RefreshTokenRequest.
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// RuntimeSpec defines model for RuntimeSpec.
+type RuntimeSpec struct {
+	Engine  string `json:"engine"`
+	Version string `json:"version"`
+}
+
+// SecretValue A literal value or a reference resolved by the node.
+type SecretValue struct {
+	Ref   *string `json:"ref,omitempty"`
+	Value *string `json:"value,omitempty"`
+}
+
+// ServiceDeployment A service definition with environment overrides applied. It mirrors the v1 Service definition field for field.
 type ServiceDeployment struct {
 	// Branch The git branch derived from environment overrides.
-	Branch     string `json:"branch"`
-	DefVersion string `json:"defVersion"`
-	GitUrl     string `json:"gitUrl"`
+	Branch     string         `json:"branch"`
+	DefVersion string         `json:"defVersion"`
+	Files      *[]ServiceFile `json:"files,omitempty"`
+	GitUrl     string         `json:"gitUrl"`
+	Ingress    *Ingress       `json:"ingress,omitempty"`
 
 	// IngressHost Optional host mapping from environment overrides.
 	IngressHost *string `json:"ingressHost,omitempty"`
 	Kind        string  `json:"kind"`
 	Name        string  `json:"name"`
 
+	// Path Directory of the service within the repository, for monorepos.
+	Path *string `json:"path,omitempty"`
+
 	// Port The final overridden port number.
-	Port      int     `json:"port"`
-	RunScript *string `json:"runScript,omitempty"`
-	Runtime   struct {
-		Engine  string `json:"engine"`
-		Version string `json:"version"`
-	} `json:"runtime"`
+	Port      int         `json:"port"`
+	RunScript *string     `json:"runScript,omitempty"`
+	Runtime   RuntimeSpec `json:"runtime"`
 
 	// Variables Final list of environment variables including secrets.
 	Variables *[]EnvironmentVariable `json:"variables,omitempty"`
 }
 
+// ServiceFile A file rendered for the service before it starts, from either template or ref. Files are written with mode 0600.
+type ServiceFile struct {
+	// Env Variable that receives the file's absolute path. Defaults to VANGUARD_FILE_ followed by the upper-cased name.
+	Env  *string `json:"env,omitempty"`
+	Name string  `json:"name"`
+
+	// Path Location relative to the service checkout. Without one the file goes to a private directory.
+	Path *string `json:"path,omitempty"`
+
+	// Ref Reference to an external secret store path whose value is the file content.
+	Ref *string `json:"ref,omitempty"`
+
+	// Template Go text/template over the service's variables, e.g. '{{ .DB_PASSWORD }}'.
+	Template *string `json:"template,omitempty"`
+}
+
+// ServiceHealth defines model for ServiceHealth.
+type ServiceHealth string
+
+// ServiceState defines model for ServiceState.
+type ServiceState string
+
+// ServiceStatus defines model for ServiceStatus.
+type ServiceStatus struct {
+	// Commit The git commit the running service was built from.
+	Commit *string `json:"commit,omitempty"`
+
+	// Error The last deployment error, if any.
+	Error *string `json:"error,omitempty"`
+
+	// ExitCode Exit code of the last process exit, -1 if killed by a signal.
+	ExitCode *int          `json:"exitCode,omitempty"`
+	Health   ServiceHealth `json:"health"`
+	Name     string        `json:"name"`
+
+	// Pid Process ID while the service is running.
+	Pid  *int `json:"pid,omitempty"`
+	Port int  `json:"port"`
+
+	// Restarts Number of times the service process was restarted.
+	Restarts  int          `json:"restarts"`
+	StartedAt *time.Time   `json:"startedAt,omitempty"`
+	State     ServiceState `json:"state"`
+}
+
+// TimeoutMiddleware defines model for TimeoutMiddleware.
+type TimeoutMiddleware struct {
+	Duration string `json:"duration"`
+}
+
+// GetNodeIdCommandsParams defines parameters for GetNodeIdCommands.
+type GetNodeIdCommandsParams struct {
+	// Wait Maximum number of seconds to hold the request open waiting for commands
+	Wait *int `form:"wait,omitempty" json:"wait,omitempty"`
+}
+
+// GetNodeIdGetConfigParams defines parameters for GetNodeIdGetConfig.
+type GetNodeIdGetConfigParams struct {
+	// Wait Maximum number of seconds to hold the request open waiting for a new revision
+	Wait *int `form:"wait,omitempty" json:"wait,omitempty"`
+
+	// IfNoneMatch Revision already applied by the node
+	IfNoneMatch *string `json:"If-None-Match,omitempty"`
+}
+
+// GetNodeIdSecretParams defines parameters for GetNodeIdSecret.
+type GetNodeIdSecretParams struct {
+	// Name Name of the secret, the path of a citadel/ ref
+	Name string `form:"name" json:"name"`
+}
+
+// PostEnrollJSONRequestBody defines body for PostEnroll for application/json ContentType.
+type PostEnrollJSONRequestBody = EnrollRequest
+
+// PostNodeIdCommandsCommandIdResultJSONRequestBody defines body for PostNodeIdCommandsCommandIdResult for application/json ContentType.
+type PostNodeIdCommandsCommandIdResultJSONRequestBody = CommandResult
+
+// PostNodeIdConfigAckJSONRequestBody defines body for PostNodeIdConfigAck for application/json ContentType.
+type PostNodeIdConfigAckJSONRequestBody = PostConfigAckRequest
+
+// PostNodeIdLogsJSONRequestBody defines body for PostNodeIdLogs for application/json ContentType.
+type PostNodeIdLogsJSONRequestBody = IngestLogsRequest
+
+// PostNodeIdStatusJSONRequestBody defines body for PostNodeIdStatus for application/json ContentType.
+type PostNodeIdStatusJSONRequestBody = PostNodeStatusRequest
+
+// PostNodeIdTokenJSONRequestBody defines body for PostNodeIdToken for application/json ContentType.
+type PostNodeIdTokenJSONRequestBody = RefreshTokenRequest
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// Exchange a one-time enrollment token for node credentials
+	// (POST /enroll)
+	PostEnroll(c *gin.Context)
 	// Retrieve a GitHub access token
 	// (GET /github/access-token)
 	GetGithubAccessToken(c *gin.Context)
+	// Wait for commands queued for the node
+	// (GET /node/{id}/commands)
+	GetNodeIdCommands(c *gin.Context, id string, params GetNodeIdCommandsParams)
+	// Report the outcome of a command
+	// (POST /node/{id}/commands/{commandId}/result)
+	PostNodeIdCommandsCommandIdResult(c *gin.Context, id string, commandId string)
+	// Acknowledge that a configuration revision was applied
+	// (POST /node/{id}/config-ack)
+	PostNodeIdConfigAck(c *gin.Context, id string)
 	// Get node configuration with service deployments
 	// (GET /node/{id}/get-config)
-	GetNodeIdGetConfig(c *gin.Context, id string)
+	GetNodeIdGetConfig(c *gin.Context, id string, params GetNodeIdGetConfigParams)
+	// Ingest a batch of service log lines
+	// (POST /node/{id}/logs)
+	PostNodeIdLogs(c *gin.Context, id string)
 	// Send ping for the current node
 	// (GET /node/{id}/ping)
 	GetNodeIdPing(c *gin.Context, id string)
+	// Issue a new refresh token and revoke the current one
+	// (POST /node/{id}/rotate-credentials)
+	PostNodeIdRotateCredentials(c *gin.Context, id string)
+	// Resolve a secret brokered by Citadel
+	// (GET /node/{id}/secret)
+	GetNodeIdSecret(c *gin.Context, id string, params GetNodeIdSecretParams)
+	// Report the status of services running on the node
+	// (POST /node/{id}/status)
+	PostNodeIdStatus(c *gin.Context, id string)
+	// Exchange a refresh token for a new access token
+	// (POST /node/{id}/token)
+	PostNodeIdToken(c *gin.Context, id string)
 }
 
 // ServerInterfaceWrapper converts contexts to parameters.
@@ -114,11 +579,26 @@ type ServerInterfaceWrapper struct {
 
 type MiddlewareFunc func(c *gin.Context)
 
+// PostEnroll operation middleware
+func (siw *ServerInterfaceWrapper) PostEnroll(c *gin.Context) {
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+		if c.IsAborted() {
+			return
+		}
+	}
+
+	siw.Handler.PostEnroll(c)
+}
+
 // GetGithubAccessToken operation middleware
 func (siw *ServerInterfaceWrapper) GetGithubAccessToken(c *gin.Context) {
 
 	c.Set(ApiKeyAuthScopes, []string{})
 
+	c.Set(BearerAuthScopes, []string{})
+
 	for _, middleware := range siw.HandlerMiddlewares {
 		middleware(c)
 		if c.IsAborted() {
@@ -129,6 +609,110 @@ func (siw *ServerInterfaceWrapper) GetGithubAccessToken(c *gin.Context) {
 	siw.Handler.GetGithubAccessToken(c)
 }
 
+// GetNodeIdCommands operation middleware
+func (siw *ServerInterfaceWrapper) GetNodeIdCommands(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", c.Param("id"), &id, runtime.BindStyledParameterOptions{Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter id: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	c.Set(ApiKeyAuthScopes, []string{})
+
+	c.Set(BearerAuthScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetNodeIdCommandsParams
+
+	// ------------- Optional query parameter "wait" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "wait", c.Request.URL.Query(), &params.Wait)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter wait: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+		if c.IsAborted() {
+			return
+		}
+	}
+
+	siw.Handler.GetNodeIdCommands(c, id, params)
+}
+
+// PostNodeIdCommandsCommandIdResult operation middleware
+func (siw *ServerInterfaceWrapper) PostNodeIdCommandsCommandIdResult(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", c.Param("id"), &id, runtime.BindStyledParameterOptions{Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter id: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	// ------------- Path parameter "commandId" -------------
+	var commandId string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "commandId", c.Param("commandId"), &commandId, runtime.BindStyledParameterOptions{Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter commandId: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	c.Set(ApiKeyAuthScopes, []string{})
+
+	c.Set(BearerAuthScopes, []string{})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+		if c.IsAborted() {
+			return
+		}
+	}
+
+	siw.Handler.PostNodeIdCommandsCommandIdResult(c, id, commandId)
+}
+
+// PostNodeIdConfigAck operation middleware
+func (siw *ServerInterfaceWrapper) PostNodeIdConfigAck(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", c.Param("id"), &id, runtime.BindStyledParameterOptions{Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter id: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	c.Set(ApiKeyAuthScopes, []string{})
+
+	c.Set(BearerAuthScopes, []string{})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+		if c.IsAborted() {
+			return
+		}
+	}
+
+	siw.Handler.PostNodeIdConfigAck(c, id)
+}
+
 // GetNodeIdGetConfig operation middleware
 func (siw *ServerInterfaceWrapper) GetNodeIdGetConfig(c *gin.Context) {
 
@@ -145,6 +729,68 @@ func (siw *ServerInterfaceWrapper) GetNodeIdGetConfig(c *gin.Context) {
 
 	c.Set(ApiKeyAuthScopes, []string{})
 
+	c.Set(BearerAuthScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetNodeIdGetConfigParams
+
+	// ------------- Optional query parameter "wait" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "wait", c.Request.URL.Query(), &params.Wait)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter wait: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	headers := c.Request.Header
+
+	// ------------- Optional header parameter "If-None-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-None-Match")]; found {
+		var IfNoneMatch string
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandler(c, fmt.Errorf("Expected one value for If-None-Match, got %d", n), http.StatusBadRequest)
+			return
+		}
+
+		err = runtime.BindStyledParameterWithOptions("simple", "If-None-Match", valueList[0], &IfNoneMatch, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationHeader, Explode: false, Required: false})
+		if err != nil {
+			siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter If-None-Match: %w", err), http.StatusBadRequest)
+			return
+		}
+
+		params.IfNoneMatch = &IfNoneMatch
+
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+		if c.IsAborted() {
+			return
+		}
+	}
+
+	siw.Handler.GetNodeIdGetConfig(c, id, params)
+}
+
+// PostNodeIdLogs operation middleware
+func (siw *ServerInterfaceWrapper) PostNodeIdLogs(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", c.Param("id"), &id, runtime.BindStyledParameterOptions{Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter id: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	c.Set(ApiKeyAuthScopes, []string{})
+
+	c.Set(BearerAuthScopes, []string{})
+
 	for _, middleware := range siw.HandlerMiddlewares {
 		middleware(c)
 		if c.IsAborted() {
@@ -152,7 +798,7 @@ func (siw *ServerInterfaceWrapper) GetNodeIdGetConfig(c *gin.Context) {
 		}
 	}
 
-	siw.Handler.GetNodeIdGetConfig(c, id)
+	siw.Handler.PostNodeIdLogs(c, id)
 }
 
 // GetNodeIdPing operation middleware
@@ -171,6 +817,8 @@ func (siw *ServerInterfaceWrapper) GetNodeIdPing(c *gin.Context) {
 
 	c.Set(ApiKeyAuthScopes, []string{})
 
+	c.Set(BearerAuthScopes, []string{})
+
 	for _, middleware := range siw.HandlerMiddlewares {
 		middleware(c)
 		if c.IsAborted() {
@@ -181,6 +829,132 @@ func (siw *ServerInterfaceWrapper) GetNodeIdPing(c *gin.Context) {
 	siw.Handler.GetNodeIdPing(c, id)
 }
 
+// PostNodeIdRotateCredentials operation middleware
+func (siw *ServerInterfaceWrapper) PostNodeIdRotateCredentials(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", c.Param("id"), &id, runtime.BindStyledParameterOptions{Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter id: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	c.Set(ApiKeyAuthScopes, []string{})
+
+	c.Set(BearerAuthScopes, []string{})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+		if c.IsAborted() {
+			return
+		}
+	}
+
+	siw.Handler.PostNodeIdRotateCredentials(c, id)
+}
+
+// GetNodeIdSecret operation middleware
+func (siw *ServerInterfaceWrapper) GetNodeIdSecret(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", c.Param("id"), &id, runtime.BindStyledParameterOptions{Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter id: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	c.Set(ApiKeyAuthScopes, []string{})
+
+	c.Set(BearerAuthScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetNodeIdSecretParams
+
+	// ------------- Required query parameter "name" -------------
+
+	if paramValue := c.Query("name"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandler(c, fmt.Errorf("Query argument name is required, but not found"), http.StatusBadRequest)
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "name", c.Request.URL.Query(), &params.Name)
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter name: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+		if c.IsAborted() {
+			return
+		}
+	}
+
+	siw.Handler.GetNodeIdSecret(c, id, params)
+}
+
+// PostNodeIdStatus operation middleware
+func (siw *ServerInterfaceWrapper) PostNodeIdStatus(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", c.Param("id"), &id, runtime.BindStyledParameterOptions{Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter id: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	c.Set(ApiKeyAuthScopes, []string{})
+
+	c.Set(BearerAuthScopes, []string{})
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+		if c.IsAborted() {
+			return
+		}
+	}
+
+	siw.Handler.PostNodeIdStatus(c, id)
+}
+
+// PostNodeIdToken operation middleware
+func (siw *ServerInterfaceWrapper) PostNodeIdToken(c *gin.Context) {
+
+	var err error
+
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", c.Param("id"), &id, runtime.BindStyledParameterOptions{Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandler(c, fmt.Errorf("Invalid format for parameter id: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		middleware(c)
+		if c.IsAborted() {
+			return
+		}
+	}
+
+	siw.Handler.PostNodeIdToken(c, id)
+}
+
 // GinServerOptions provides options for the Gin server.
 type GinServerOptions struct {
 	BaseURL      string
@@ -208,7 +982,16 @@ func RegisterHandlersWithOptions(router gin.IRouter, si ServerInterface, options
 		ErrorHandler:       errorHandler,
 	}
 
+	router.POST(options.BaseURL+"/enroll", wrapper.PostEnroll)
 	router.GET(options.BaseURL+"/github/access-token", wrapper.GetGithubAccessToken)
+	router.GET(options.BaseURL+"/node/:id/commands", wrapper.GetNodeIdCommands)
+	router.POST(options.BaseURL+"/node/:id/commands/:commandId/result", wrapper.PostNodeIdCommandsCommandIdResult)
+	router.POST(options.BaseURL+"/node/:id/config-ack", wrapper.PostNodeIdConfigAck)
 	router.GET(options.BaseURL+"/node/:id/get-config", wrapper.GetNodeIdGetConfig)
+	router.POST(options.BaseURL+"/node/:id/logs", wrapper.PostNodeIdLogs)
 	router.GET(options.BaseURL+"/node/:id/ping", wrapper.GetNodeIdPing)
+	router.POST(options.BaseURL+"/node/:id/rotate-credentials", wrapper.PostNodeIdRotateCredentials)
+	router.GET(options.BaseURL+"/node/:id/secret", wrapper.GetNodeIdSecret)
+	router.POST(options.BaseURL+"/node/:id/status", wrapper.PostNodeIdStatus)
+	router.POST(options.BaseURL+"/node/:id/token", wrapper.PostNodeIdToken)
 }
@@ -1,83 +1,444 @@
 package main
 
 import (
+	"cmp"
+	"compress/gzip"
 	"context"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-type Server struct{}
-
-func (s Server) GetNodeIdGetConfig(c *gin.Context, _ string) {
-	ingressHost := "vinr.local"
-	runScript := "npm run dev"
-	port := "3000"
-	resp := GetNodeConfigResponse{
-		Type: Vanguard,
-		ServiceDeployments: []ServiceDeployment{
-			{
-				Kind:        "service",
-				DefVersion:  "v1",
-				Name:        "next-js-app",
-				GitUrl:      "https://github.com/richy-vinr/next-js-app",
-				Branch:      "main",
-				Port:        3000,
-				IngressHost: &ingressHost,
-				RunScript:   &runScript,
-				Runtime: struct {
-					Engine  string `json:"engine"`
-					Version string `json:"version"`
-				}{
-					Engine:  "node",
-					Version: "24.13.1",
-				},
-				Variables: &[]EnvironmentVariable{
-					{
-						Name:  "PORT",
-						Value: &port,
-					},
-				},
-			},
-		},
+type Server struct {
+	mu       sync.Mutex
+	revision int
+	changed  chan struct{}
+
+	signingKey []byte
+	// enrollmentTokens maps one-time tokens to the node ID to enroll as; an
+	// empty ID picks a random one.
+	enrollmentTokens map[string]string
+	refreshTokens    map[string]string
+
+	fixturePath string
+	fixtures    map[string]*Fixture
+	requests    []RecordedRequest
+	faultRules  []*Fault
+
+	commands map[string][]Command
+	queued   chan struct{}
+
+	logBatches map[string]bool
+}
+
+const accessTokenTTL = 15 * time.Minute
+
+// PostEnroll accepts each enrollment token once. Tokens come from
+// MOCK_ENROLLMENT_TOKENS (comma separated, default "enroll-me") and from
+// fixtures, which also fix the node ID.
+func (s *Server) PostEnroll(c *gin.Context) {
+	var req EnrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nodeID, ok := s.enrollmentTokens[req.EnrollmentToken]
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Code: http.StatusUnauthorized, Message: "invalid enrollment token"})
+		return
+	}
+	delete(s.enrollmentTokens, req.EnrollmentToken)
+	if nodeID == "" {
+		nodeID = "node-" + randomToken()[:8]
+	}
+	hostname := ""
+	if req.Hostname != nil {
+		hostname = *req.Hostname
+	}
+	log.Printf("enrolled node=%s hostname=%s", nodeID, hostname)
+	c.JSON(http.StatusOK, EnrollResponse{
+		NodeId:      nodeID,
+		Credentials: s.issue(nodeID, true),
+	})
+}
+
+func (s *Server) PostNodeIdToken(c *gin.Context, id string) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refreshTokens[req.RefreshToken] != id {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Code: http.StatusUnauthorized, Message: "invalid refresh token"})
+		return
+	}
+	c.JSON(http.StatusOK, s.issue(id, false))
+}
+
+func (s *Server) PostNodeIdRotateCredentials(c *gin.Context, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tokenNode(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")) != id {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Code: http.StatusUnauthorized, Message: "invalid access token"})
+		return
+	}
+	for token, node := range s.refreshTokens {
+		if node == id {
+			delete(s.refreshTokens, token)
+		}
+	}
+	log.Printf("rotated credentials node=%s", id)
+	c.JSON(http.StatusOK, s.issue(id, true))
+}
+
+// issue must be called with s.mu held. Access tokens are
+// "<node>.<expiry>.<hmac>" so they can be checked without server state.
+func (s *Server) issue(nodeID string, withRefresh bool) NodeCredentials {
+	expiresAt := time.Now().Add(accessTokenTTL)
+	payload := nodeID + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	creds := NodeCredentials{
+		AccessToken: payload + "." + s.sign(payload),
+		ExpiresAt:   expiresAt,
 	}
+	if withRefresh {
+		refresh := randomToken()
+		s.refreshTokens[refresh] = nodeID
+		creds.RefreshToken = &refresh
+	}
+	return creds
+}
+
+func (s *Server) tokenNode(token string) string {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 || !hmac.Equal([]byte(token[i+1:]), []byte(s.sign(token[:i]))) {
+		return ""
+	}
+	nodeID, exp, ok := strings.Cut(token[:i], ".")
+	if unix, err := strconv.ParseInt(exp, 10, 64); !ok || err != nil || time.Now().Unix() > unix {
+		return ""
+	}
+	return nodeID
+}
+
+func (s *Server) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func randomToken() string {
+	b := make([]byte, 24)
+	_, _ = crand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// GetNodeIdGetConfig serves the node's fixture and holds the request open
+// while the caller already has the current revision, up to the requested
+// wait.
+func (s *Server) GetNodeIdGetConfig(c *gin.Context, id string, params GetNodeIdGetConfigParams) {
+	s.mu.Lock()
+	revision, changed := strconv.Itoa(s.revision), s.changed
+	s.mu.Unlock()
+	if params.IfNoneMatch != nil && *params.IfNoneMatch == revision {
+		wait := 0
+		if params.Wait != nil {
+			wait = *params.Wait
+		}
+		select {
+		case <-changed:
+		case <-time.After(time.Duration(wait) * time.Second):
+			c.Status(http.StatusNotModified)
+			return
+		case <-c.Request.Context().Done():
+			return
+		}
+		s.mu.Lock()
+		revision = strconv.Itoa(s.revision)
+		s.mu.Unlock()
+	}
+
+	f, ok := s.fixture(id)
+	if !ok || f.Config == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: http.StatusNotFound, Message: "no config for node " + id})
+		return
+	}
+	resp := *f.Config
+	resp.Revision = revision
+	if resp.Type == "" {
+		resp.Type = Vanguard
+	}
+	if resp.ServiceDeployments == nil {
+		resp.ServiceDeployments = []ServiceDeployment{}
+	}
+	c.Header("ETag", revision)
 	c.JSON(http.StatusOK, resp)
 }
 
-func (s Server) GetNodeIdPing(c *gin.Context, _ string) {
-	version := "1.0.0"
+// publish bumps the revision and wakes up pending long-polls.
+func (s *Server) publish(c *gin.Context) {
+	s.mu.Lock()
+	s.bump()
+	revision := s.revision
+	s.mu.Unlock()
+	c.JSON(http.StatusOK, gin.H{"revision": strconv.Itoa(revision)})
+}
+
+// bump must be called with s.mu held.
+func (s *Server) bump() {
+	s.revision++
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Server) PostNodeIdConfigAck(c *gin.Context, id string) {
+	var req PostConfigAckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	msg := ""
+	if req.Error != nil {
+		msg = *req.Error
+	}
+	log.Printf("config ack node=%s revision=%s status=%s error=%q", id, req.Revision, req.Status, msg)
+	c.Status(http.StatusNoContent)
+}
+
+// GetNodeIdCommands returns the commands queued for the node that have no
+// result yet, holding the request open up to the requested wait when there
+// are none.
+func (s *Server) GetNodeIdCommands(c *gin.Context, id string, params GetNodeIdCommandsParams) {
+	s.mu.Lock()
+	pending, queued := len(s.commands[id]), s.queued
+	s.mu.Unlock()
+	if pending == 0 {
+		wait := 0
+		if params.Wait != nil {
+			wait = *params.Wait
+		}
+		select {
+		case <-queued:
+		case <-time.After(time.Duration(wait) * time.Second):
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+	s.mu.Lock()
+	commands := append([]Command{}, s.commands[id]...)
+	s.mu.Unlock()
+	c.JSON(http.StatusOK, GetNodeCommandsResponse{Commands: commands})
+}
+
+func (s *Server) PostNodeIdCommandsCommandIdResult(c *gin.Context, id string, commandId string) {
+	var req CommandResult
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	s.mu.Lock()
+	s.commands[id] = slices.DeleteFunc(s.commands[id], func(cmd Command) bool {
+		return cmd.Id == commandId
+	})
+	s.mu.Unlock()
+	msg := ""
+	if req.Error != nil {
+		msg = *req.Error
+	}
+	lines := 0
+	if req.Logs != nil {
+		lines = len(*req.Logs)
+	}
+	log.Printf("command result node=%s command=%s status=%s took=%s logs=%d error=%q",
+		id, commandId, req.Status, req.FinishedAt.Sub(req.StartedAt), lines, msg)
+	if req.Diagnostics != nil {
+		d := req.Diagnostics
+		log.Printf("diagnostics node=%s host=%s version=%s uptime=%ds goroutines=%d memory=%d services=%d",
+			id, d.Hostname, d.Version, d.UptimeSeconds, d.Goroutines, d.MemoryBytes, len(d.Services))
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// enqueue queues a command for a node, e.g.
+// {"type": "redeploy", "service": "next-js-app"}.
+func (s *Server) enqueue(c *gin.Context) {
+	var cmd Command
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	cmd.Id = randomToken()[:16]
+	cmd.IssuedAt = time.Now()
+	id := c.Param("id")
+	s.mu.Lock()
+	s.commands[id] = append(s.commands[id], cmd)
+	close(s.queued)
+	s.queued = make(chan struct{})
+	s.mu.Unlock()
+	c.JSON(http.StatusAccepted, cmd)
+}
+
+// PostNodeIdLogs prints ingested lines, ignoring batches it has already seen.
+func (s *Server) PostNodeIdLogs(c *gin.Context, id string) {
+	if c.GetHeader("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		defer zr.Close()
+		c.Request.Body = zr
+	}
+	var req IngestLogsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	s.mu.Lock()
+	seen := s.logBatches[req.BatchId]
+	s.logBatches[req.BatchId] = true
+	s.mu.Unlock()
+	if !seen {
+		for _, e := range req.Entries {
+			log.Printf("log node=%s service=%s stream=%s time=%s %s", id, e.Service, e.Stream, e.Time.Format(time.RFC3339Nano), e.Text)
+		}
+	}
+	c.Status(http.StatusAccepted)
+}
+
+func (s *Server) GetNodeIdPing(c *gin.Context, id string) {
+	status, version := "ok", "1.0.0"
+	if f, ok := s.fixture(id); ok && f.Ping != nil {
+		status = cmp.Or(f.Ping.Status, status)
+		version = cmp.Or(f.Ping.Version, version)
+	}
 	resp := GetNodePingResponse{
-		Status:    "ok",
+		Status:    status,
 		Timestamp: time.Now(),
 		Version:   &version,
 	}
 	c.JSON(http.StatusOK, resp)
 }
 
-func (s Server) GetGithubAccessToken(c *gin.Context) {
+func (s *Server) PostNodeIdStatus(c *gin.Context, id string) {
+	var req PostNodeStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	for _, svc := range req.Services {
+		commit := ""
+		if svc.Commit != nil {
+			commit = *svc.Commit
+		}
+		log.Printf("status node=%s service=%s state=%s health=%s restarts=%d commit=%s", id, svc.Name, svc.State, svc.Health, svc.Restarts, commit)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) GetNodeIdSecret(c *gin.Context, id string, params GetNodeIdSecretParams) {
+	f, ok := s.fixture(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: http.StatusNotFound, Message: "unknown node " + id})
+		return
+	}
+	value, ok := f.Secrets[params.Name]
+	if !ok {
+		c.JSON(http.StatusNotFound, ErrorResponse{Code: http.StatusNotFound, Message: "unknown secret " + params.Name})
+		return
+	}
+	sum := sha256.Sum256([]byte(value))
+	version := hex.EncodeToString(sum[:8])
+	c.JSON(http.StatusOK, GetSecretResponse{Name: params.Name, Value: value, Version: &version})
+}
+
+func (s *Server) GetGithubAccessToken(c *gin.Context) {
 	accessToken := os.Getenv("GITHUB_TOKEN")
+	if f, ok := s.fixture(s.callerNode(c)); ok && f.GitHubToken != "" {
+		accessToken = f.GitHubToken
+	}
+	expiresAt := time.Now().Add(time.Hour)
 	resp := GetGitHubAccessTokenResponse{
 		AccessToken: accessToken,
+		ExpiresAt:   &expiresAt,
 	}
 	c.JSON(http.StatusOK, resp)
 }
 
-func NewServer() Server {
-	return Server{}
+func NewServer() *Server {
+	s := &Server{
+		revision:         1,
+		changed:          make(chan struct{}),
+		signingKey:       []byte(randomToken()),
+		enrollmentTokens: make(map[string]string),
+		refreshTokens:    make(map[string]string),
+		commands:         make(map[string][]Command),
+		queued:           make(chan struct{}),
+		logBatches:       make(map[string]bool),
+	}
+	tokens := os.Getenv("MOCK_ENROLLMENT_TOKENS")
+	if tokens == "" {
+		tokens = "enroll-me"
+	}
+	for _, t := range strings.Split(tokens, ",") {
+		s.enrollmentTokens[strings.TrimSpace(t)] = ""
+	}
+	s.faultRules = envFaults()
+	return s
+}
+
+// router serves the API behind the recorder and fault rules, plus the
+// /_mock admin routes.
+func (s *Server) router(middleware ...gin.HandlerFunc) *gin.Engine {
+	router := gin.New()
+	router.Use(middleware...)
+	router.Use(s.record(), s.faults())
+	RegisterHandlers(router, s)
+	router.POST("/_mock/publish", s.publish)
+	router.POST("/_mock/nodes/:id/commands", s.enqueue)
+	router.PUT("/_mock/nodes/:id/fixture", s.putFixture)
+	router.POST("/_mock/fixtures/reload", s.reloadFixtures)
+	router.GET("/_mock/requests", s.listRequests)
+	router.DELETE("/_mock/requests", s.clearRequests)
+	router.GET("/_mock/faults", s.listFaults)
+	router.POST("/_mock/faults", s.addFault)
+	router.DELETE("/_mock/faults", s.deleteFaults)
+	return router
 }
 
 func main() {
-	router := gin.Default()
 	server := NewServer()
-	RegisterHandlers(router, server)
+	if path := os.Getenv("MOCK_FIXTURES"); path != "" {
+		fixtures, err := loadFixtures(path)
+		if err != nil {
+			log.Fatalf("fixtures: %s\n", err)
+		}
+		server.fixturePath = path
+		server.mu.Lock()
+		server.setFixtures(fixtures)
+		server.mu.Unlock()
+		log.Printf("loaded %d fixtures from %s", len(fixtures), path)
+	}
 	srv := &http.Server{
-		Handler: router,
+		Handler: server.router(gin.Logger(), gin.Recovery()),
 		Addr:    "0.0.0.0:9080",
 	}
 	go func() {
@@ -2,32 +2,63 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
-	"net/http/httputil"
-	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/autotls"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/acme/autocert"
 
+	"vinr.eu/vanguard/internal/accesslog"
 	"vinr.eu/vanguard/internal/aws"
 	"vinr.eu/vanguard/internal/citadel"
+	"vinr.eu/vanguard/internal/command"
 	"vinr.eu/vanguard/internal/config"
-	"vinr.eu/vanguard/internal/defs"
+	"vinr.eu/vanguard/internal/deployment"
 	"vinr.eu/vanguard/internal/environment"
+	"vinr.eu/vanguard/internal/ingress"
+	"vinr.eu/vanguard/internal/metrics"
+	"vinr.eu/vanguard/internal/redact"
+	"vinr.eu/vanguard/internal/secrets"
+	"vinr.eu/vanguard/internal/sops"
 	"vinr.eu/vanguard/internal/source"
+	"vinr.eu/vanguard/internal/telemetry"
+)
+
+const (
+	ctxKeyService         = "vanguard.service"
+	ctxKeyUpstreamPort    = "vanguard.upstream_port"
+	ctxKeyUpstreamLatency = "vanguard.upstream_latency"
+	ctxKeyRequestID       = "vanguard.request_id"
+	ctxKeyBytesIn         = "vanguard.bytes_in"
+
+	headerRequestID = "X-Request-ID"
 )
 
 func main() {
 	ctx := context.Background()
 
+	// Mask resolved secrets in everything vanguard logs
+	redactor := redact.New()
+	slog.SetDefault(slog.New(redact.NewHandler(slog.NewTextHandler(os.Stderr, nil), redactor)))
+
 	// Load config
 	cfg, err := config.Load()
 	if err != nil {
@@ -39,35 +70,88 @@ func main() {
 	var githubTokenProvider source.TokenProvider
 	var citadelClient *citadel.Client
 	if cfg.Mode == "local" {
-		githubTokenProvider = func(ctx context.Context) (string, error) {
-			return os.Getenv("GITHUB_TOKEN"), nil
+		githubTokenProvider, err = localTokenProvider(cfg)
+		if err != nil {
+			slog.Error("Failed to set up GitHub credentials", "error", err)
+			os.Exit(1)
 		}
 	} else {
 		// Load Citadel client
 		var err error
-		citadelClient, err = citadel.NewClient(
-			cfg.CitadelURL,
-			citadel.WithAPIKey(cfg.CitadelAPIKey),
-			citadel.WithNodeID(cfg.CitadelNodeID),
-			citadel.WithTimeout(5*time.Second),
-		)
+		citadelClient, err = setupCitadel(ctx, cfg, redactor)
 		if err != nil {
 			slog.Error("Failed to init citadel client", "error", err)
 			os.Exit(1)
 		}
+		if len(os.Args) > 1 && os.Args[1] == "rotate-credentials" {
+			if err := citadelClient.RotateCredentials(ctx); err != nil {
+				slog.Error("Failed to rotate citadel credentials", "error", err)
+				os.Exit(1)
+			}
+			slog.Info("Rotated citadel credentials", "node", cfg.CitadelNodeID)
+			return
+		}
 		githubTokenProvider = citadelClient.GetGithubAccessToken
 	}
+	githubTokenProvider = source.NewCachingTokenProvider(githubTokenProvider)
+
+	// Set up tracing once enrollment has settled the node ID; spans are only
+	// exported when an OTLP endpoint is configured
+	shutdownTracing, err := telemetry.Setup(ctx, cfg.OTLPEndpoint,
+		attribute.String("vanguard.mode", cfg.Mode),
+		attribute.String("vanguard.node_id", cfg.CitadelNodeID),
+	)
+	if err != nil {
+		slog.Error("Failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+
+	// Register the secret backends that variable refs may point to
+	var citadelSecrets *citadel.SecretCache
+	if citadelClient != nil {
+		citadelSecrets = citadelClient.SecretCache(cfg.CitadelNodeID)
+	}
+	secretRegistry, err := setupSecrets(cfg, citadelSecrets)
+	if err != nil {
+		slog.Error("Failed to set up secret providers", "error", err)
+		os.Exit(1)
+	}
 
-	// Load AWS configuration and initialize the Secrets Manager client
-	awsCfg, err := aws.LoadServiceConfig(ctx, "SM")
+	decrypter, err := sops.LoadDecrypter(cfg.AgeKey, cfg.AgeKeyFile)
 	if err != nil {
-		slog.Error("Failed to load AWS config", "error", err)
+		slog.Error("Failed to load age key", "error", err)
 		os.Exit(1)
 	}
-	smClient := aws.NewSecretsManagerClient(awsCfg)
 
 	// Load environment manager and Boot the environment
-	manager := environment.NewManager(cfg.WorkspaceDir, githubTokenProvider, smClient)
+	manager := environment.NewManager(cfg.WorkspaceDir, githubTokenProvider, secretRegistry).
+		WithDecrypter(decrypter).
+		WithRedactor(redactor)
+
+	// Ship service output to Citadel; shipping outlives the signal context so
+	// the output of services shutting down is spooled too.
+	shipCtx, stopShipping := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopShipping()
+	shipped := make(chan struct{})
+	if citadelClient != nil && cfg.LogShipping {
+		shipper, err := citadelClient.NewLogShipper(filepath.Join(cfg.WorkspaceDir, "citadel", "logs"), int64(cfg.LogShippingBufferMB)<<20, redactor)
+		if err != nil {
+			slog.Error("Failed to set up log shipping", "error", err)
+			os.Exit(1)
+		}
+		manager.WithLogSink(func(service string, line deployment.LogLine) {
+			shipper.Add(citadel.LogEntry{Service: service, Stream: line.Stream, Time: line.Time, Text: line.Text})
+		})
+		go func() {
+			defer close(shipped)
+			shipper.Run(shipCtx)
+		}()
+	} else {
+		close(shipped)
+	}
+
+	var bootRevision string
+	saveSnapshot := func(*citadel.NodeConfig) {}
 	if cfg.Mode == "local" {
 		if err := manager.Boot(ctx, cfg.EnvDefsGitURL, cfg.EnvDefsDir); err != nil {
 			slog.Error("Failed to boot engine", "error", err)
@@ -79,22 +163,131 @@ func main() {
 			slog.Error("Citadel client is not initialized in server mode")
 			os.Exit(1)
 		}
-		services, err := citadelClient.GetNodeConfig(ctx, cfg.CitadelNodeID)
+		snapshot, err := citadel.NewSnapshot(filepath.Join(cfg.WorkspaceDir, "citadel", "node-config.snapshot"), cfg.CitadelSnapshotKey, cfg.CitadelNodeID)
 		if err != nil {
-			slog.Error("Failed to get node config from citadel", "error", err)
+			slog.Error("Failed to init config snapshot", "error", err)
 			os.Exit(1)
 		}
-		if err := manager.BootWithConfig(ctx, services); err != nil {
+		saveSnapshot = func(nodeConfig *citadel.NodeConfig) {
+			if err := snapshot.Save(nodeConfig, citadelSecrets.Values()); err != nil {
+				slog.Warn("Failed to save config snapshot", "revision", nodeConfig.Revision, "error", err)
+			}
+		}
+		online := true
+		nodeConfig, err := citadelClient.GetNodeConfig(ctx, cfg.CitadelNodeID)
+		if err != nil {
+			// Keep serving the last known good config; the config watch
+			// reconciles once Citadel is reachable again.
+			slog.Error("Failed to get node config from citadel, falling back to snapshot", "error", err)
+			online = false
+			var lastSecrets map[string]string
+			nodeConfig, lastSecrets, err = snapshot.Load()
+			if err != nil {
+				slog.Error("Failed to load config snapshot", "error", err)
+				os.Exit(1)
+			}
+			citadelSecrets.Restore(lastSecrets)
+			slog.Warn("Booting offline from config snapshot", "revision", nodeConfig.Revision)
+		}
+		boot := manager.BootWithConfig
+		if !online {
+			boot = manager.BootOffline
+		}
+		if err := boot(ctx, nodeConfig.Services); err != nil {
 			slog.Error("Failed to boot engine with citadel config", "error", err)
+			if online {
+				if ackErr := citadelClient.AckConfig(ctx, cfg.CitadelNodeID, nodeConfig.Revision, err); ackErr != nil {
+					slog.Warn("Failed to report boot failure", "revision", nodeConfig.Revision, "error", ackErr)
+				}
+			}
 			os.Exit(1)
 		}
+		// Offline, the watch applies the current revision as soon as Citadel
+		// is back, retrying the services that could not start.
+		if online {
+			bootRevision = nodeConfig.Revision
+			saveSnapshot(nodeConfig)
+			if err := citadelClient.AckConfig(ctx, cfg.CitadelNodeID, bootRevision, nil); err != nil {
+				slog.Warn("Failed to acknowledge boot config", "revision", bootRevision, "error", err)
+			}
+		}
+	}
+
+	// Report liveness and service status to Citadel; health is probed once
+	// per heartbeat and read from there by diagnostics and metrics
+	citadelCtx, stopCitadel := context.WithCancel(ctx)
+	defer stopCitadel()
+	if citadelClient != nil {
+		go citadelClient.RunHeartbeat(citadelCtx, cfg.HeartbeatInterval, func() []citadel.ServiceStatus {
+			manager.ProbeHealth()
+			return citadelStatuses(manager)
+		})
+	}
+
+	// Pick up rotated secrets
+	if cfg.SecretsRefreshInterval > 0 {
+		go manager.WatchSecrets(ctx, cfg.SecretsRefreshInterval)
+	}
+
+	// Set up the admin listener
+	metrics.RegisterServiceProcesses(func() []metrics.ServiceProcess {
+		return serviceProcesses(manager)
+	})
+	adminSrv := &http.Server{
+		Handler: setupAdmin(redactor),
+		Addr:    cfg.AdminAddr,
+	}
+	go func() {
+		slog.Info("Starting admin HTTP server", "addr", cfg.AdminAddr)
+		if err := adminSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Failed to listen on admin address", "error", err)
+		}
+	}()
+
+	// Open the access log
+	accessLog, err := accesslog.Open(accesslog.Options{
+		Path:       cfg.AccessLogFile,
+		MaxSizeMB:  cfg.AccessLogMaxSizeMB,
+		MaxBackups: cfg.AccessLogMaxBackups,
+		MaxAgeDays: cfg.AccessLogMaxAgeDays,
+		Compress:   cfg.AccessLogCompress,
+	})
+	if err != nil {
+		slog.Error("Failed to open access log", "error", err)
+		os.Exit(1)
 	}
+	defer accessLog.Close()
 
 	// Set up the reverse proxy
 	router := gin.New()
-	setupLogging(router)
+	setupRequestID(router)
+	setupLogging(router, cfg.AccessLogFormat, redact.Writer(accessLog, redactor), accesslog.IsTerminal(accessLog))
+	setupMetrics(router)
+	setupTracing(router)
 	router.Use(gin.Recovery())
-	setupReverseProxy(router, manager.GetServices())
+	proxies := ingress.NewRouter()
+	if err := proxies.Update(ctx, manager.GetServices()); err != nil {
+		slog.Error("Failed to set up some ingress routes", "error", err)
+	}
+	setupReverseProxy(router, proxies)
+
+	// Apply configuration revisions and run commands pushed by Citadel
+	if citadelClient != nil {
+		go citadelClient.WatchConfig(citadelCtx, bootRevision, func(ctx context.Context, nodeConfig *citadel.NodeConfig) error {
+			err := errors.Join(
+				manager.Apply(ctx, nodeConfig.Services),
+				proxies.Update(ctx, manager.GetServices()),
+			)
+			if err == nil {
+				saveSnapshot(nodeConfig)
+			}
+			return err
+		})
+		commands := command.NewHandler(manager, func() []citadel.ServiceStatus {
+			return citadelStatuses(manager)
+		})
+		go citadelClient.RunCommands(citadelCtx, commands.Handle)
+	}
 
 	// Variable to hold the local server for graceful shutdown
 	var localSrv *http.Server
@@ -111,11 +304,16 @@ func main() {
 			}
 		}()
 	} else {
-		domains := getDomains(manager.GetServices())
+		domains := proxies.Hosts()
 		m := autocert.Manager{
-			Prompt:     autocert.AcceptTOS,
-			HostPolicy: autocert.HostWhitelist(domains...),
-			Cache:      autocert.DirCache("/var/www/.cache"),
+			Prompt: autocert.AcceptTOS,
+			HostPolicy: func(_ context.Context, host string) error {
+				if _, ok := proxies.Match(host); !ok {
+					return fmt.Errorf("acme/autocert: host %q not configured", host)
+				}
+				return nil
+			},
+			Cache: autocert.DirCache("/var/www/.cache"),
 		}
 
 		go func() {
@@ -141,85 +339,370 @@ func main() {
 			slog.Error("Failed to shutdown local server", "error", err)
 		}
 	}
+	if err := adminSrv.Shutdown(ctxTimeout); err != nil {
+		slog.Error("Failed to shutdown admin server", "error", err)
+	}
 
 	// Shut down the environment manager
+	stopCitadel()
 	manager.Shutdown()
+	stopShipping()
+	<-shipped
+	if err := shutdownTracing(ctxTimeout); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
 	slog.Info("Server exiting")
 }
 
-func setupLogging(router *gin.Engine) {
-	router.Use(gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		var statusColor, methodColor, resetColor string
-		if param.IsOutputColor() {
-			statusColor = param.StatusCodeColor()
-			methodColor = param.MethodColor()
-			resetColor = param.ResetColor()
+func setupRequestID(router *gin.Engine) {
+	router.Use(func(c *gin.Context) {
+		id := c.GetHeader(headerRequestID)
+		if id == "" {
+			id = newRequestID()
+			c.Request.Header.Set(headerRequestID, id)
 		}
+		c.Set(ctxKeyRequestID, id)
+		c.Header(headerRequestID, id)
+		body := &countingBody{ReadCloser: c.Request.Body}
+		c.Request.Body = body
+		c.Set(ctxKeyBytesIn, body)
+	})
+}
+
+// setupLogging writes the access log to out. The text format is coloured when
+// color is set, as out may wrap the terminal gin would otherwise detect.
+func setupLogging(router *gin.Engine, format string, out io.Writer, color bool) {
+	if format == "json" {
+		logger := accesslog.NewJSONLogger(out)
+		router.Use(func(c *gin.Context) {
+			start := time.Now()
+			c.Next()
+			if err := logger.Log(accessLogEntry(c, start)); err != nil {
+				slog.Error("Failed to write access log", "error", err)
+			}
+		})
+		return
+	}
+	router.Use(gin.LoggerWithConfig(gin.LoggerConfig{
+		Output: out,
+		Formatter: func(param gin.LogFormatterParams) string {
+			var statusColor, methodColor, resetColor string
+			if color || param.IsOutputColor() {
+				statusColor = param.StatusCodeColor()
+				methodColor = param.MethodColor()
+				resetColor = param.ResetColor()
+			}
+
+			if param.Latency > time.Minute {
+				param.Latency = param.Latency.Truncate(time.Second)
+			}
 
-		if param.Latency > time.Minute {
-			param.Latency = param.Latency.Truncate(time.Second)
+			host := ""
+			if param.Request != nil {
+				host = param.Request.Host
+			}
+
+			return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s | %s |%s %-7s %s %#v\n%s",
+				param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+				statusColor, param.StatusCode, resetColor,
+				param.Latency,
+				param.ClientIP,
+				host,
+				methodColor, param.Method, resetColor,
+				param.Path,
+				param.ErrorMessage,
+			)
+		},
+	}))
+}
+
+func accessLogEntry(c *gin.Context, start time.Time) accesslog.Entry {
+	req := c.Request
+	entry := accesslog.Entry{
+		Time:      start,
+		RequestID: c.GetString(ctxKeyRequestID),
+		Method:    req.Method,
+		Host:      req.Host,
+		Path:      req.URL.Path,
+		Query:     req.URL.RawQuery,
+		Protocol:  req.Proto,
+		Status:    c.Writer.Status(),
+		ClientIP:  c.ClientIP(),
+		UserAgent: req.UserAgent(),
+		Referer:   req.Referer(),
+		Service:   c.GetString(ctxKeyService),
+		LatencyMs: accesslog.Millis(time.Since(start)),
+		Error:     c.Errors.ByType(gin.ErrorTypePrivate).String(),
+	}
+	if entry.Service != "" {
+		entry.UpstreamPort = c.GetInt(ctxKeyUpstreamPort)
+		entry.UpstreamLatencyMs = accesslog.Millis(c.GetDuration(ctxKeyUpstreamLatency))
+	}
+	if body, ok := c.Value(ctxKeyBytesIn).(*countingBody); ok {
+		entry.BytesIn = body.n
+	}
+	if size := c.Writer.Size(); size > 0 {
+		entry.BytesOut = int64(size)
+	}
+	if req.TLS != nil {
+		entry.TLS = &accesslog.TLSInfo{
+			Version:     tls.VersionName(req.TLS.Version),
+			CipherSuite: tls.CipherSuiteName(req.TLS.CipherSuite),
+			ServerName:  req.TLS.ServerName,
+			Resumed:     req.TLS.DidResume,
 		}
+	}
+	return entry
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
 
-		host := ""
-		if param.Request != nil {
-			host = param.Request.Host
+func setupMetrics(router *gin.Engine) {
+	router.Use(func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		ingress, service := "unmatched", "unmatched"
+		if name := c.GetString(ctxKeyService); name != "" {
+			ingress, service = c.Request.Host, name
 		}
+		metrics.ObserveRequest(ingress, service, c.Request.Method, c.Writer.Status(), time.Since(start))
+	})
+}
 
-		return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s | %s |%s %-7s %s %#v\n%s",
-			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
-			statusColor, param.StatusCode, resetColor,
-			param.Latency,
-			param.ClientIP,
-			host,
-			methodColor, param.Method, resetColor,
-			param.Path,
-			param.ErrorMessage,
+func setupTracing(router *gin.Engine) {
+	router.Use(func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := telemetry.Tracer().Start(ctx, "proxy "+c.Request.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.ServerAddress(c.Request.Host),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+			),
 		)
-	}))
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if name := c.GetString(ctxKeyService); name != "" {
+			span.SetAttributes(attribute.String("vanguard.service", name))
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
 }
 
-func getDomains(services map[string]*defs.Service) []string {
-	domains := make([]string, 0, len(services))
-	for _, svc := range services {
-		if svc.IngressHost == nil {
-			continue
-		}
-		domains = append(domains, *svc.IngressHost)
+func setupAdmin(redactor *redact.Redactor) *gin.Engine {
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(redactResponses(redactor))
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	return router
+}
+
+type redactingWriter struct {
+	gin.ResponseWriter
+	redactor *redact.Redactor
+}
+
+func (w *redactingWriter) Write(p []byte) (int, error) {
+	if w.Header().Get("Content-Encoding") != "" {
+		return w.ResponseWriter.Write(p)
 	}
-	return domains
+	// Masking changes the length of the body.
+	w.Header().Del("Content-Length")
+	if _, err := w.ResponseWriter.WriteString(w.redactor.String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *redactingWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
 }
 
-func setupReverseProxy(router *gin.Engine, services map[string]*defs.Service) {
-	proxies := make(map[string]*httputil.ReverseProxy)
+// redactResponses masks secrets in admin responses. Compressed bodies are
+// passed through as they are.
+func redactResponses(redactor *redact.Redactor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer = &redactingWriter{ResponseWriter: c.Writer, redactor: redactor}
+		c.Next()
+	}
+}
 
-	for _, svc := range services {
-		if svc.IngressHost == nil {
-			continue
+func serviceProcesses(manager *environment.Manager) []metrics.ServiceProcess {
+	statuses := manager.Statuses()
+	procs := make([]metrics.ServiceProcess, len(statuses))
+	for i, st := range statuses {
+		procs[i] = metrics.ServiceProcess{
+			Service:  st.Name,
+			PID:      st.PID,
+			Running:  st.State == deployment.StateRunning,
+			Restarts: st.Restarts,
+			ExitCode: st.ExitCode,
 		}
+	}
+	return procs
+}
 
-		port := fmt.Sprintf("%d", svc.Port)
+// setupCitadel authenticates with CITADEL_API_KEY when set. Otherwise it uses
+// the credentials the node enrolled with, enrolling first when it has none yet.
+func setupCitadel(ctx context.Context, cfg *config.Config, redactor *redact.Redactor) (*citadel.Client, error) {
+	opts := []citadel.Option{
+		citadel.WithRedactor(redactor),
+		citadel.WithTimeout(5 * time.Second),
+		citadel.WithRetryPolicy(citadel.RetryPolicy{
+			MaxAttempts: cfg.CitadelRetryMaxAttempts,
+			BaseDelay:   cfg.CitadelRetryBaseDelay,
+			MaxDelay:    cfg.CitadelRetryMaxDelay,
+		}),
+		citadel.WithCircuitBreaker(cfg.CitadelBreakerThreshold, cfg.CitadelBreakerCooldown),
+	}
+	if cfg.CitadelAPIKey != "" {
+		return citadel.NewClient(cfg.CitadelURL, append(opts,
+			citadel.WithAPIKey(cfg.CitadelAPIKey),
+			citadel.WithNodeID(cfg.CitadelNodeID),
+		)...)
+	}
+	path := filepath.Join(cfg.WorkspaceDir, "citadel", "credentials.json")
+	store, err := citadel.LoadCredentials(path)
+	if errors.Is(err, citadel.ErrNoCredentials) && cfg.CitadelEnrollmentToken != "" {
+		var client *citadel.Client
+		if client, err = citadel.NewClient(cfg.CitadelURL, opts...); err != nil {
+			return nil, err
+		}
+		store, err = client.Enroll(ctx, cfg.CitadelEnrollmentToken, path)
+		if err == nil {
+			slog.Info("Enrolled node with citadel", "node", store.NodeID())
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cfg.CitadelNodeID != "" && cfg.CitadelNodeID != store.NodeID() {
+		return nil, fmt.Errorf("CITADEL_NODE_ID %q does not match enrolled node %q", cfg.CitadelNodeID, store.NodeID())
+	}
+	cfg.CitadelNodeID = store.NodeID()
+	if cfg.CitadelSnapshotKey == "" {
+		cfg.CitadelSnapshotKey = store.SnapshotKey()
+	}
+	return citadel.NewClient(cfg.CitadelURL, append(opts, citadel.WithCredentials(store))...)
+}
 
-		target, err := url.Parse("http://localhost:" + port)
+// localTokenProvider looks for GitHub credentials the way a developer's
+// machine usually has them, ending with an org-owned GitHub App if configured.
+func localTokenProvider(cfg *config.Config) (source.TokenProvider, error) {
+	providers := []source.TokenProvider{
+		source.EnvTokenProvider("GITHUB_TOKEN", "GH_TOKEN"),
+		source.GHCLITokenProvider(),
+		source.GitCredentialTokenProvider(),
+		source.NetrcTokenProvider(),
+	}
+	if cfg.GitHubAppID != 0 {
+		key := []byte(cfg.GitHubAppPrivateKey)
+		if cfg.GitHubAppPrivateKeyFile != "" {
+			var err error
+			if key, err = os.ReadFile(cfg.GitHubAppPrivateKeyFile); err != nil {
+				return nil, err
+			}
+		}
+		app, err := source.NewGitHubApp(int64(cfg.GitHubAppID), int64(cfg.GitHubAppInstallationID), key)
 		if err != nil {
-			slog.Error("Failed to parse target URL", "service", svc.Name, "error", err)
-			continue
+			return nil, err
 		}
+		providers = append(providers, app.TokenProvider())
+	}
+	return source.ChainTokenProvider(providers...), nil
+}
 
-		proxy := httputil.NewSingleHostReverseProxy(target)
-		host := *svc.IngressHost
-
-		slog.Info("Setting up reverse proxy", "service", svc.Name, "host", host, "port", port)
-		proxies[host] = proxy
+// setupSecrets registers every supported scheme. Backends without settings
+// stay registered so refs to them fail with a hint instead of as unknown.
+func setupSecrets(cfg *config.Config, citadelSecrets *citadel.SecretCache) (*secrets.Registry, error) {
+	smPool, err := aws.NewPool("SM", aws.NewSecretsManagerClient)
+	if err != nil {
+		return nil, err
+	}
+	ssmPool, err := aws.NewPool("SSM", aws.NewSSMClient)
+	if err != nil {
+		return nil, err
+	}
+	registry := secrets.NewRegistry().
+		WithCache(cfg.SecretsCacheTTL).
+		Register("aws/secrets", secrets.AWSSecretsManager(smPool)).
+		Register("aws/ssm", secrets.AWSParameterStore(ssmPool)).
+		Register("env", secrets.Env()).
+		Register("file", secrets.Files(cfg.SecretsFileDir))
+	if cfg.VaultAddr != "" {
+		registry.Register("vault", secrets.Vault(secrets.VaultOptions{
+			Addr:      cfg.VaultAddr,
+			Token:     cfg.VaultToken,
+			Namespace: cfg.VaultNamespace,
+		}))
+	} else {
+		registry.Register("vault", secrets.Unconfigured("VAULT_ADDR is not set"))
+	}
+	if citadelSecrets != nil {
+		registry.Register("citadel", citadelSecrets)
+	} else {
+		registry.Register("citadel", secrets.Unconfigured("citadel secrets need server mode"))
 	}
+	// Sidecar files are relative to the definitions root, so loading local
+	// definitions replaces these.
+	sidecars := secrets.Unconfigured("sops and age refs need local definitions")
+	registry.Register("sops", sidecars).Register("age", sidecars)
+	return registry, nil
+}
 
-	if len(proxies) > 0 {
-		router.Any("/*proxyPath", func(c *gin.Context) {
-			if proxy, ok := proxies[c.Request.Host]; ok {
-				proxy.ServeHTTP(c.Writer, c.Request)
-				c.Abort()
-				return
-			}
-			c.Next()
-		})
+func citadelStatuses(manager *environment.Manager) []citadel.ServiceStatus {
+	statuses := manager.Statuses()
+	out := make([]citadel.ServiceStatus, len(statuses))
+	for i, st := range statuses {
+		out[i] = citadel.ServiceStatus{
+			Name:      st.Name,
+			State:     st.State,
+			PID:       st.PID,
+			Port:      st.Port,
+			Restarts:  st.Restarts,
+			ExitCode:  st.ExitCode,
+			Commit:    st.Commit,
+			Health:    st.Health,
+			StartedAt: st.StartedAt,
+			Error:     st.Error,
+		}
 	}
+	return out
+}
+
+func setupReverseProxy(router *gin.Engine, proxies *ingress.Router) {
+	router.Any("/*proxyPath", func(c *gin.Context) {
+		r, ok := proxies.Match(c.Request.Host)
+		if !ok {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.Set(ctxKeyService, r.Service)
+		c.Set(ctxKeyUpstreamPort, r.Port)
+		ctx, stats := ingress.WithStats(c.Request.Context())
+		r.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+		c.Set(ctxKeyUpstreamLatency, stats.UpstreamLatency)
+		c.Abort()
+	})
 }
@@ -0,0 +1,32 @@
+// Command oidc-stub serves a local OpenID provider for ingress OIDC login:
+//
+//	OIDC_STUB_ADDR=127.0.0.1:9090 go run ./examples/oidc-stub
+//
+// and point an ingress at it with issuerURL http://127.0.0.1:9090 and any
+// clientID and clientSecret.
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"vinr.eu/vanguard/internal/oidcstub"
+)
+
+func main() {
+	addr := getEnv("OIDC_STUB_ADDR", "127.0.0.1:9090")
+	provider, err := oidcstub.New(getEnv("OIDC_STUB_SUBJECT", "dev"), getEnv("OIDC_STUB_EMAIL", "dev@example.com"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("oidc stub listening on http://%s", addr)
+	log.Fatal(http.ListenAndServe(addr, provider))
+}
+
+func getEnv(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return fallback
+}
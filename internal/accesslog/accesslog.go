@@ -0,0 +1,114 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"vinr.eu/vanguard/internal/errs"
+)
+
+var (
+	ErrOpenFailed = errors.New("accesslog: failed to open log file")
+)
+
+type Options struct {
+	Path       string
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
+	Compress   bool
+}
+
+type TLSInfo struct {
+	Version     string `json:"version"`
+	CipherSuite string `json:"cipher_suite"`
+	ServerName  string `json:"server_name,omitempty"`
+	Resumed     bool   `json:"resumed"`
+}
+
+type Entry struct {
+	Time              time.Time `json:"time"`
+	RequestID         string    `json:"request_id"`
+	Method            string    `json:"method"`
+	Host              string    `json:"host"`
+	Path              string    `json:"path"`
+	Query             string    `json:"query,omitempty"`
+	Protocol          string    `json:"protocol"`
+	Status            int       `json:"status"`
+	ClientIP          string    `json:"client_ip"`
+	UserAgent         string    `json:"user_agent,omitempty"`
+	Referer           string    `json:"referer,omitempty"`
+	Service           string    `json:"service,omitempty"`
+	UpstreamPort      int       `json:"upstream_port,omitempty"`
+	LatencyMs         float64   `json:"latency_ms"`
+	UpstreamLatencyMs float64   `json:"upstream_latency_ms,omitempty"`
+	BytesIn           int64     `json:"bytes_in"`
+	BytesOut          int64     `json:"bytes_out"`
+	TLS               *TLSInfo  `json:"tls,omitempty"`
+	Error             string    `json:"error,omitempty"`
+}
+
+func Open(opts Options) (io.WriteCloser, error) {
+	if opts.Path == "" {
+		return nopCloser{os.Stdout}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0755); err != nil {
+		return nil, errs.WrapMsgErr(ErrOpenFailed, opts.Path, err)
+	}
+	return &lumberjack.Logger{
+		Filename:   opts.Path,
+		MaxSize:    opts.MaxSizeMB,
+		MaxBackups: opts.MaxBackups,
+		MaxAge:     opts.MaxAgeDays,
+		Compress:   opts.Compress,
+	}, nil
+}
+
+// IsTerminal reports whether w, as returned by Open, writes to a terminal,
+// using the same checks gin applies to decide on colours.
+func IsTerminal(w io.Writer) bool {
+	if nc, ok := w.(nopCloser); ok {
+		w = nc.Writer
+	}
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+type JSONLogger struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewJSONLogger(w io.Writer) *JSONLogger {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &JSONLogger{enc: enc}
+}
+
+func (l *JSONLogger) Log(e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enc.Encode(e)
+}
+
+func Millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -4,17 +4,25 @@ import (
 	"context"
 	"errors"
 	"os"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"vinr.eu/vanguard/internal/errs"
 )
 
 var (
-	ErrInvalidMode = errors.New("aws/config: MODE must be 'local' or 'server'")
+	ErrInvalidMode  = errors.New("aws/config: MODE must be 'local' or 'server'")
+	ErrInvalidRoles = errors.New("aws/config: AWS_ROLES must be a list of alias=arn pairs")
+	ErrUnknownRole  = errors.New("aws/config: unknown role alias")
 )
 
-func LoadServiceConfig(ctx context.Context, prefix string) (aws.Config, error) {
+// loadServiceConfig loads the configuration of the AWS backend whose
+// settings are prefixed with prefix, e.g. SM_AWS_REGION, falling back to the
+// plain AWS variables.
+func loadServiceConfig(ctx context.Context, prefix string) (aws.Config, error) {
 	mode := os.Getenv("MODE")
 	switch mode {
 	case "local", "":
@@ -26,23 +34,39 @@ func LoadServiceConfig(ctx context.Context, prefix string) (aws.Config, error) {
 	}
 }
 
+// loadServiceLocal uses static credentials, "test" unless given, when an
+// endpoint override such as LocalStack is configured. Otherwise it reads the
+// shared config of AWS_PROFILE, so SSO profiles work after "aws sso login".
 func loadServiceLocal(ctx context.Context, prefix string) (aws.Config, error) {
 	endpoint := getServiceEnv(prefix, "AWS_ENDPOINT_URL", "")
 	opts := []func(*config.LoadOptions) error{
-		config.WithRegion(getServiceEnv(prefix, "AWS_REGION", "us-east-1")),
-		config.WithCredentialsProvider(
-			aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
-				return aws.Credentials{
-					AccessKeyID:     getServiceEnv(prefix, "AWS_ACCESS_KEY_ID", "test"),
-					SecretAccessKey: getServiceEnv(prefix, "AWS_SECRET_ACCESS_KEY", "test"),
-				}, nil
-			}),
-		),
+		config.WithRegion(getServiceEnv(prefix, "AWS_REGION", "")),
+	}
+	if profile := getServiceEnv(prefix, "AWS_PROFILE", ""); profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(profile))
 	}
 	if endpoint != "" {
-		opts = append(opts, config.WithBaseEndpoint(endpoint))
+		opts = append(opts,
+			config.WithBaseEndpoint(endpoint),
+			config.WithCredentialsProvider(
+				aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+					return aws.Credentials{
+						AccessKeyID:     getServiceEnv(prefix, "AWS_ACCESS_KEY_ID", "test"),
+						SecretAccessKey: getServiceEnv(prefix, "AWS_SECRET_ACCESS_KEY", "test"),
+						SessionToken:    getServiceEnv(prefix, "AWS_SESSION_TOKEN", ""),
+					}, nil
+				}),
+			),
+		)
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, err
 	}
-	return config.LoadDefaultConfig(ctx, opts...)
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return cfg, nil
 }
 
 func loadServiceRemote(ctx context.Context, prefix string) (aws.Config, error) {
@@ -51,6 +75,40 @@ func loadServiceRemote(ctx context.Context, prefix string) (aws.Config, error) {
 	)
 }
 
+// AssumeRole returns a copy of cfg whose credentials come from assuming
+// roleARN with the credentials of cfg. They are cached until they expire.
+func AssumeRole(cfg aws.Config, roleARN, session string) aws.Config {
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = session
+	})
+	assumed := cfg.Copy()
+	assumed.Credentials = aws.NewCredentialsCache(provider)
+	return assumed
+}
+
+// loadRoles parses AWS_ROLES, e.g. "prod-ro=arn:aws:iam::123:role/ro,...",
+// into the aliases refs may assume.
+func loadRoles(prefix string) (map[string]string, error) {
+	roles := make(map[string]string)
+	for pair := range strings.SplitSeq(getServiceEnv(prefix, "AWS_ROLES", ""), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		alias, arn, ok := strings.Cut(pair, "=")
+		alias, arn = strings.TrimSpace(alias), strings.TrimSpace(arn)
+		if !ok || alias == "" || !strings.HasPrefix(arn, "arn:") {
+			return nil, errs.WrapMsg(ErrInvalidRoles, "got "+pair)
+		}
+		roles[alias] = arn
+	}
+	return roles, nil
+}
+
+func sessionName(prefix string) string {
+	return getServiceEnv(prefix, "AWS_ROLE_SESSION_NAME", "vanguard")
+}
+
 func getServiceEnv(prefix, key, fallback string) string {
 	if v, ok := os.LookupEnv(prefix + "_" + key); ok {
 		return v
@@ -0,0 +1,82 @@
+package aws
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"vinr.eu/vanguard/internal/errs"
+)
+
+// Pool hands out clients of one AWS backend, one per region and role. The
+// configuration is loaded when the first client is needed, so nothing is
+// read or assumed unless a ref uses the backend. A role alias from AWS_ROLES
+// is assumed with the source credentials, in place of AWS_ASSUME_ROLE_ARN.
+type Pool[T any] struct {
+	prefix    string
+	roles     map[string]string
+	newClient func(aws.Config) T
+	mu        sync.Mutex
+	source    *aws.Config
+	clients   map[target]T
+}
+
+type target struct {
+	region string
+	alias  string
+}
+
+func NewPool[T any](prefix string, newClient func(aws.Config) T) (*Pool[T], error) {
+	roles, err := loadRoles(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return &Pool[T]{
+		prefix:    prefix,
+		roles:     roles,
+		newClient: newClient,
+		clients:   make(map[target]T),
+	}, nil
+}
+
+// HasRole reports whether alias is configured in AWS_ROLES.
+func (p *Pool[T]) HasRole(alias string) bool {
+	_, ok := p.roles[alias]
+	return ok
+}
+
+// Client returns the client for region and role alias. Empty values stand
+// for the configured region and role.
+func (p *Pool[T]) Client(ctx context.Context, region, alias string) (T, error) {
+	var zero T
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := target{region: region, alias: alias}
+	if client, ok := p.clients[key]; ok {
+		return client, nil
+	}
+	arn := getServiceEnv(p.prefix, "AWS_ASSUME_ROLE_ARN", "")
+	if alias != "" {
+		var ok bool
+		if arn, ok = p.roles[alias]; !ok {
+			return zero, errs.WrapMsg(ErrUnknownRole, alias+" is not in "+p.prefix+"_AWS_ROLES or AWS_ROLES")
+		}
+	}
+	if p.source == nil {
+		source, err := loadServiceConfig(ctx, p.prefix)
+		if err != nil {
+			return zero, err
+		}
+		p.source = &source
+	}
+	cfg := p.source.Copy()
+	if region != "" {
+		cfg.Region = region
+	}
+	if arn != "" {
+		cfg = AssumeRole(cfg, arn, sessionName(p.prefix))
+	}
+	client := p.newClient(cfg)
+	p.clients[key] = client
+	return client, nil
+}
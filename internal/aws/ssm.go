@@ -0,0 +1,40 @@
+package aws
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"vinr.eu/vanguard/internal/errs"
+)
+
+var (
+	ErrSSMGetParameter = errors.New("aws/ssm: failed to get parameter")
+)
+
+type SSMClient struct {
+	client *ssm.Client
+}
+
+func NewSSMClient(cfg aws.Config) *SSMClient {
+	return &SSMClient{
+		client: ssm.NewFromConfig(cfg),
+	}
+}
+
+// GetParameter returns the value of a parameter, decrypting SecureString
+// parameters.
+func (s *SSMClient) GetParameter(ctx context.Context, name string) (string, error) {
+	out, err := s.client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", errs.WrapMsgErr(ErrSSMGetParameter, name, err)
+	}
+	if out.Parameter == nil || out.Parameter.Value == nil {
+		return "", nil
+	}
+	return *out.Parameter.Value, nil
+}
@@ -2,13 +2,19 @@ package citadel
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	gen "vinr.eu/vanguard/api/citadel/v1"
 	"vinr.eu/vanguard/internal/defs"
+	"vinr.eu/vanguard/internal/defs/v1"
 	"vinr.eu/vanguard/internal/errs"
+	"vinr.eu/vanguard/internal/metrics"
+	"vinr.eu/vanguard/internal/redact"
+	"vinr.eu/vanguard/internal/source"
 )
 
 var (
@@ -19,13 +25,43 @@ var (
 	ErrUnauthorized  = errors.New("citadel: unauthorized (401)")
 	ErrNotFound      = errors.New("citadel: not found (404)")
 	ErrApiFailure    = errors.New("citadel: unexpected api response")
+	ErrNotModified   = errors.New("citadel: not modified (304)")
+	ErrInvalidConfig = errors.New("citadel: invalid node config")
 )
 
+// APIError carries the decoded ErrorResponse of a failed call. It unwraps to
+// the sentinel matching its HTTP status.
+type APIError struct {
+	Operation  string
+	StatusCode int
+	Code       int
+	Message    string
+	sentinel   error
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%v: %s: status %d", e.sentinel, e.Operation, e.StatusCode)
+	}
+	return fmt.Sprintf("%v: %s: status %d: code %d: %s", e.sentinel, e.Operation, e.StatusCode, e.Code, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.sentinel
+}
+
 type Client struct {
-	api     *gen.ClientWithResponses
-	apiKey  string
-	nodeID  string
-	timeout time.Duration
+	api              *gen.ClientWithResponses
+	poll             *gen.ClientWithResponses
+	anon             *gen.ClientWithResponses
+	apiKey           string
+	creds            *CredentialStore
+	nodeID           string
+	timeout          time.Duration
+	retry            RetryPolicy
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	redactor         *redact.Redactor
 }
 
 type Option func(*Client)
@@ -48,15 +84,58 @@ func WithNodeID(id string) Option {
 	}
 }
 
+// WithCredentials authenticates with access tokens derived from enrolled node
+// credentials instead of a static API key.
+func WithCredentials(store *CredentialStore) Option {
+	return func(c *Client) {
+		c.creds = store
+		if c.nodeID == "" {
+			c.nodeID = store.NodeID()
+		}
+	}
+}
+
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// WithCircuitBreaker fails calls fast after threshold consecutive failures
+// until cooldown has passed. A threshold of zero disables the breaker.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *Client) {
+		c.breakerThreshold = threshold
+		c.breakerCooldown = cooldown
+	}
+}
+
+// WithRedactor masks known secret values in the error messages and log lines
+// reported to Citadel.
+func WithRedactor(r *redact.Redactor) Option {
+	return func(c *Client) {
+		c.redactor = r
+	}
+}
+
 func NewClient(baseURL string, opts ...Option) (*Client, error) {
 	c := &Client{
-		timeout: 10 * time.Second,
+		timeout:          10 * time.Second,
+		retry:            DefaultRetryPolicy,
+		breakerThreshold: 5,
+		breakerCooldown:  30 * time.Second,
 	}
 	for _, opt := range opts {
 		opt(c)
 	}
+	br := newBreaker(c.breakerThreshold, c.breakerCooldown)
 	httpClient := &http.Client{
-		Timeout: c.timeout,
+		Transport: &transport{
+			base:    http.DefaultTransport,
+			policy:  c.retry,
+			breaker: br,
+			timeout: c.timeout,
+		},
 	}
 	apiClient, err := gen.NewClientWithResponses(
 		baseURL,
@@ -67,11 +146,37 @@ func NewClient(baseURL string, opts ...Option) (*Client, error) {
 		return nil, errs.Wrap(ErrInitFailed, err)
 	}
 	c.api = apiClient
+	// Long-polls are bounded per request by their context and retried by the
+	// watch loop itself.
+	pollClient, err := gen.NewClientWithResponses(
+		baseURL,
+		gen.WithHTTPClient(&http.Client{
+			Transport: &transport{base: http.DefaultTransport, breaker: br},
+		}),
+		gen.WithRequestEditorFn(c.authenticate),
+	)
+	if err != nil {
+		return nil, errs.Wrap(ErrInitFailed, err)
+	}
+	c.poll = pollClient
+	// Enrollment and token refresh carry their own secrets in the body.
+	anonClient, err := gen.NewClientWithResponses(baseURL, gen.WithHTTPClient(httpClient))
+	if err != nil {
+		return nil, errs.Wrap(ErrInitFailed, err)
+	}
+	c.anon = anonClient
 	return c, nil
 }
 
-func (c *Client) authenticate(_ context.Context, req *http.Request) error {
-	if c.apiKey != "" {
+func (c *Client) authenticate(ctx context.Context, req *http.Request) error {
+	switch {
+	case c.creds != nil:
+		token, err := c.accessToken(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case c.apiKey != "":
 		req.Header.Set("x-api-key", c.apiKey)
 	}
 	if c.nodeID != "" {
@@ -88,75 +193,254 @@ type statusCoder interface {
 	StatusCode() int
 }
 
-func (c *Client) GetGithubAccessToken(ctx context.Context) (string, error) {
+func (c *Client) GetGithubAccessToken(ctx context.Context) (source.Token, error) {
 	resp, err := c.api.GetGithubAccessTokenWithResponse(ctx)
-	if err := validateResponse(err, resp, func() bool { return resp.JSON200 != nil }); err != nil {
+	err = validateResponse("GetGithubAccessToken", err, resp, func() []byte { return resp.Body }, func() bool { return resp.JSON200 != nil })
+	observe("GetGithubAccessToken", err)
+	if err != nil {
+		return source.Token{}, err
+	}
+	token := source.Token{Value: resp.JSON200.AccessToken}
+	if resp.JSON200.ExpiresAt != nil {
+		token.ExpiresAt = *resp.JSON200.ExpiresAt
+	}
+	return token, nil
+}
+
+// GetSecret resolves a secret brokered by Citadel for the node.
+func (c *Client) GetSecret(ctx context.Context, id, name string) (string, error) {
+	resp, err := c.api.GetNodeIdSecretWithResponse(ctx, id, &gen.GetNodeIdSecretParams{Name: name})
+	err = validateResponse("GetSecret", err, resp, func() []byte { return resp.Body }, func() bool { return resp.JSON200 != nil })
+	observe("GetSecret", err)
+	if err != nil {
 		return "", err
 	}
-	return resp.JSON200.AccessToken, nil
+	return resp.JSON200.Value, nil
 }
 
-func (c *Client) GetNodeConfig(ctx context.Context, id string) ([]*defs.Service, error) {
-	resp, err := c.api.GetNodeIdGetConfigWithResponse(ctx, id)
-	if err := validateResponse(err, resp, func() bool { return resp.JSON200 != nil }); err != nil {
+type NodeConfig struct {
+	Revision string
+	Services []*defs.Service
+	raw      *gen.GetNodeConfigResponse
+}
+
+func (c *Client) GetNodeConfig(ctx context.Context, id string) (*NodeConfig, error) {
+	resp, err := c.api.GetNodeIdGetConfigWithResponse(ctx, id, &gen.GetNodeIdGetConfigParams{})
+	err = validateResponse("GetNodeConfig", err, resp, func() []byte { return resp.Body }, func() bool { return resp.JSON200 != nil })
+	observe("GetNodeConfig", err)
+	if err != nil {
 		return nil, err
 	}
+	return mapNodeConfig(resp.JSON200)
+}
 
-	services := make([]*defs.Service, len(resp.JSON200.ServiceDeployments))
-	for i, s := range resp.JSON200.ServiceDeployments {
-		var runScript string
-		if s.RunScript != nil {
-			runScript = *s.RunScript
-		}
+// WaitNodeConfig long-polls for a revision newer than the given one. It
+// returns ErrNotModified when Citadel has nothing newer within the wait.
+func (c *Client) WaitNodeConfig(ctx context.Context, id, revision string, wait time.Duration) (*NodeConfig, error) {
+	ctx, cancel := context.WithTimeout(ctx, wait+c.timeout)
+	defer cancel()
+	seconds := int(wait.Seconds())
+	params := &gen.GetNodeIdGetConfigParams{Wait: &seconds}
+	if revision != "" {
+		params.IfNoneMatch = &revision
+	}
+	resp, err := c.poll.GetNodeIdGetConfigWithResponse(ctx, id, params)
+	err = validateResponse("WaitNodeConfig", err, resp, func() []byte { return resp.Body }, func() bool { return resp.JSON200 != nil })
+	observe("WaitNodeConfig", err)
+	if err != nil {
+		return nil, err
+	}
+	return mapNodeConfig(resp.JSON200)
+}
 
-		var vars []defs.Variable
-		if s.Variables != nil {
-			vars = make([]defs.Variable, len(*s.Variables))
-			for j, v := range *s.Variables {
-				vars[j] = defs.Variable{
-					Name:  v.Name,
-					Value: v.Value,
-					Ref:   v.Ref,
-				}
-			}
-		}
+func (c *Client) AckConfig(ctx context.Context, id, revision string, applyErr error) error {
+	body := gen.PostConfigAckRequest{
+		Revision:  revision,
+		Status:    gen.ConfigAckStatusApplied,
+		AppliedAt: time.Now().UTC(),
+	}
+	if applyErr != nil {
+		msg := c.redactor.String(applyErr.Error())
+		body.Status = gen.ConfigAckStatusFailed
+		body.Error = &msg
+	}
+	resp, err := c.api.PostNodeIdConfigAckWithResponse(idempotent(ctx), id, body)
+	err = validateResponse("AckConfig", err, resp, func() []byte { return resp.Body }, func() bool { return true })
+	observe("AckConfig", err)
+	return err
+}
 
-		services[i] = &defs.Service{
-			Name: s.Name,
-			Runtime: defs.RuntimeSpec{
-				Engine:  s.Runtime.Engine,
-				Version: s.Runtime.Version,
-			},
-			GitURL:      s.GitUrl,
-			Branch:      s.Branch,
-			Port:        s.Port,
-			RunScript:   runScript,
-			IngressHost: s.IngressHost,
-			Variables:   vars,
+func mapNodeConfig(cfg *gen.GetNodeConfigResponse) (*NodeConfig, error) {
+	services := make([]*defs.Service, len(cfg.ServiceDeployments))
+	for i, s := range cfg.ServiceDeployments {
+		svc, err := serviceV1(s)
+		if err != nil {
+			return nil, errs.WrapMsgErr(ErrInvalidConfig, s.Name, err)
 		}
+		services[i] = defs.MapServiceV1(svc)
 	}
+	return &NodeConfig{
+		Revision: cfg.Revision,
+		Services: services,
+		raw:      cfg,
+	}, nil
+}
 
-	return services, nil
+// serviceV1 converts a ServiceDeployment to the v1 definition model. The
+// schema mirrors v1.Service field for field, so a JSON round trip carries
+// every field, including ones added later.
+func serviceV1(s gen.ServiceDeployment) (*v1.Service, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	var svc v1.Service
+	if err := json.Unmarshal(data, &svc); err != nil {
+		return nil, err
+	}
+	// The API spells it gitUrl.
+	svc.GitURL = s.GitUrl
+	return &svc, nil
+}
+
+type PingResult struct {
+	Status    string
+	Timestamp time.Time
+	Version   string
 }
 
-func validateResponse(err error, resp statusCoder, hasPayload func() bool) error {
+func (c *Client) Ping(ctx context.Context, id string) (*PingResult, error) {
+	resp, err := c.api.GetNodeIdPingWithResponse(ctx, id)
+	err = validateResponse("Ping", err, resp, func() []byte { return resp.Body }, func() bool { return resp.JSON200 != nil })
+	observe("Ping", err)
 	if err != nil {
-		return ErrNetwork
+		return nil, err
+	}
+	result := &PingResult{
+		Status:    resp.JSON200.Status,
+		Timestamp: resp.JSON200.Timestamp,
+	}
+	if resp.JSON200.Version != nil {
+		result.Version = *resp.JSON200.Version
+	}
+	return result, nil
+}
+
+type ServiceStatus struct {
+	Name      string
+	State     string
+	PID       int
+	Port      int
+	Restarts  int
+	ExitCode  int
+	Commit    string
+	Health    string
+	StartedAt time.Time
+	Error     string
+}
+
+func (c *Client) ReportStatus(ctx context.Context, id string, statuses []ServiceStatus) error {
+	body := gen.PostNodeStatusRequest{
+		ReportedAt: time.Now().UTC(),
+		Services:   make([]gen.ServiceStatus, len(statuses)),
+	}
+	for i, st := range statuses {
+		body.Services[i] = mapServiceStatus(st)
+	}
+	resp, err := c.api.PostNodeIdStatusWithResponse(idempotent(ctx), id, body)
+	err = validateResponse("ReportStatus", err, resp, func() []byte { return resp.Body }, func() bool { return true })
+	observe("ReportStatus", err)
+	return err
+}
+
+func mapServiceStatus(st ServiceStatus) gen.ServiceStatus {
+	svc := gen.ServiceStatus{
+		Name:     st.Name,
+		State:    gen.ServiceState(st.State),
+		Port:     st.Port,
+		Restarts: st.Restarts,
+		Health:   gen.ServiceHealth(st.Health),
+	}
+	if st.PID != 0 {
+		svc.Pid = &st.PID
+	}
+	if st.State == string(gen.Exited) {
+		svc.ExitCode = &st.ExitCode
+	}
+	if st.Commit != "" {
+		svc.Commit = &st.Commit
+	}
+	if !st.StartedAt.IsZero() {
+		svc.StartedAt = &st.StartedAt
+	}
+	if st.Error != "" {
+		svc.Error = &st.Error
+	}
+	return svc
+}
+
+func validateResponse(operation string, err error, resp statusCoder, body func() []byte, hasPayload func() bool) error {
+	if err != nil {
+		// Failures to obtain an access token are already classified.
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			return err
+		}
+		if errors.Is(err, ErrCircuitOpen) {
+			return fmt.Errorf("%w: %s", ErrCircuitOpen, operation)
+		}
+		return fmt.Errorf("%w: %s: %w", ErrNetwork, operation, err)
 	}
 	if resp == nil {
 		return ErrEmptyResponse
 	}
+	var sentinel error
 	switch resp.StatusCode() {
-	case 200, 201, 204:
+	case 200, 201, 202, 204:
 		if !hasPayload() {
 			return ErrPayloadNil
 		}
 		return nil
+	case 304:
+		return ErrNotModified
 	case 401, 403:
-		return ErrUnauthorized
+		sentinel = ErrUnauthorized
 	case 404:
-		return ErrNotFound
+		sentinel = ErrNotFound
+	default:
+		sentinel = ErrApiFailure
+	}
+	apiErr := &APIError{
+		Operation:  operation,
+		StatusCode: resp.StatusCode(),
+		sentinel:   sentinel,
+	}
+	var decoded gen.ErrorResponse
+	if raw := body(); len(raw) > 0 && json.Unmarshal(raw, &decoded) == nil {
+		apiErr.Code = decoded.Code
+		apiErr.Message = decoded.Message
+	}
+	return apiErr
+}
+
+func observe(operation string, err error) {
+	result := "ok"
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrCircuitOpen):
+		result = "circuit_open"
+	case errors.Is(err, ErrNetwork):
+		result = "network_error"
+	case errors.Is(err, ErrUnauthorized):
+		result = "unauthorized"
+	case errors.Is(err, ErrNotFound):
+		result = "not_found"
+	case errors.Is(err, ErrNotModified):
+		result = "not_modified"
+	case errors.Is(err, ErrEmptyResponse), errors.Is(err, ErrPayloadNil):
+		result = "empty_response"
 	default:
-		return ErrApiFailure
+		result = "api_error"
 	}
+	metrics.ObserveCitadelCall(operation, result)
 }
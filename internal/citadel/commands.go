@@ -0,0 +1,172 @@
+package citadel
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	gen "vinr.eu/vanguard/api/citadel/v1"
+)
+
+const (
+	CommandRestart  = "restart"
+	CommandRedeploy = "redeploy"
+	CommandLogs     = "logs"
+	CommandDiagnose = "diagnose"
+
+	commandWait = 55 * time.Second
+	// seenCommands bounds how many executed command IDs are remembered to
+	// skip redeliveries.
+	seenCommands = 256
+)
+
+type Command struct {
+	ID       string
+	Type     string
+	Service  string
+	Commit   string
+	Lines    int
+	IssuedAt time.Time
+}
+
+type LogLine struct {
+	Time   time.Time
+	Stream string
+	Text   string
+}
+
+type Diagnostics struct {
+	Hostname    string
+	Version     string
+	Uptime      time.Duration
+	Goroutines  int
+	MemoryBytes int64
+	Services    []ServiceStatus
+}
+
+type CommandResult struct {
+	StartedAt   time.Time
+	FinishedAt  time.Time
+	Err         error
+	Logs        []LogLine
+	Diagnostics *Diagnostics
+}
+
+type CommandHandler func(ctx context.Context, cmd Command) CommandResult
+
+// WaitCommands long-polls for commands queued for the node. It returns an
+// empty list when none arrive within the wait.
+func (c *Client) WaitCommands(ctx context.Context, id string, wait time.Duration) ([]Command, error) {
+	ctx, cancel := context.WithTimeout(ctx, wait+c.timeout)
+	defer cancel()
+	seconds := int(wait.Seconds())
+	resp, err := c.poll.GetNodeIdCommandsWithResponse(ctx, id, &gen.GetNodeIdCommandsParams{Wait: &seconds})
+	err = validateResponse("WaitCommands", err, resp, func() []byte { return resp.Body }, func() bool { return resp.JSON200 != nil })
+	observe("WaitCommands", err)
+	if err != nil {
+		return nil, err
+	}
+	commands := make([]Command, len(resp.JSON200.Commands))
+	for i, cmd := range resp.JSON200.Commands {
+		commands[i] = Command{
+			ID:       cmd.Id,
+			Type:     string(cmd.Type),
+			IssuedAt: cmd.IssuedAt,
+		}
+		if cmd.Service != nil {
+			commands[i].Service = *cmd.Service
+		}
+		if cmd.Commit != nil {
+			commands[i].Commit = *cmd.Commit
+		}
+		if cmd.Lines != nil {
+			commands[i].Lines = *cmd.Lines
+		}
+	}
+	return commands, nil
+}
+
+func (c *Client) PostCommandResult(ctx context.Context, id, commandID string, result CommandResult) error {
+	body := gen.CommandResult{
+		Status:     gen.CommandStatusSucceeded,
+		StartedAt:  result.StartedAt.UTC(),
+		FinishedAt: result.FinishedAt.UTC(),
+	}
+	if result.Err != nil {
+		msg := c.redactor.String(result.Err.Error())
+		body.Status = gen.CommandStatusFailed
+		body.Error = &msg
+	}
+	if result.Logs != nil {
+		logs := make([]gen.LogLine, len(result.Logs))
+		for i, l := range result.Logs {
+			logs[i] = gen.LogLine{Time: l.Time, Stream: gen.LogLineStream(l.Stream), Text: c.redactor.String(l.Text)}
+		}
+		body.Logs = &logs
+	}
+	if d := result.Diagnostics; d != nil {
+		services := make([]gen.ServiceStatus, len(d.Services))
+		for i, st := range d.Services {
+			services[i] = mapServiceStatus(st)
+		}
+		body.Diagnostics = &gen.Diagnostics{
+			Hostname:      d.Hostname,
+			Version:       d.Version,
+			UptimeSeconds: int(d.Uptime.Seconds()),
+			Goroutines:    d.Goroutines,
+			MemoryBytes:   d.MemoryBytes,
+			Services:      services,
+		}
+	}
+	resp, err := c.api.PostNodeIdCommandsCommandIdResultWithResponse(idempotent(ctx), id, commandID, body)
+	err = validateResponse("PostCommandResult", err, resp, func() []byte { return resp.Body }, func() bool { return true })
+	observe("PostCommandResult", err)
+	return err
+}
+
+// RunCommands polls Citadel for commands, runs them one at a time through
+// handle and posts each result back.
+func (c *Client) RunCommands(ctx context.Context, handle CommandHandler) {
+	backoff := initWatchBackoff
+	seen := make(map[string]bool)
+	var order []string
+	for {
+		started := time.Now()
+		commands, err := c.WaitCommands(ctx, c.nodeID, commandWait)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "citadel command poll failed", "node", c.nodeID, "retry_in", backoff, "error", err)
+			if !sleep(ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, maxWatchBackoff)
+			continue
+		}
+		backoff = initWatchBackoff
+		if len(commands) == 0 && !sleep(ctx, minPollInterval-time.Since(started)) {
+			return
+		}
+		for _, cmd := range commands {
+			if seen[cmd.ID] {
+				slog.InfoContext(ctx, "skipping redelivered command", "command", cmd.ID)
+				continue
+			}
+			seen[cmd.ID] = true
+			order = append(order, cmd.ID)
+			if len(order) > seenCommands {
+				delete(seen, order[0])
+				order = order[1:]
+			}
+			slog.InfoContext(ctx, "executing citadel command", "command", cmd.ID, "type", cmd.Type, "service", cmd.Service)
+			result := handle(ctx, cmd)
+			if result.Err != nil {
+				slog.WarnContext(ctx, "citadel command failed", "command", cmd.ID, "type", cmd.Type, "error", result.Err)
+			}
+			if err := c.PostCommandResult(ctx, c.nodeID, cmd.ID, result); err != nil {
+				slog.WarnContext(ctx, "citadel command result not delivered", "command", cmd.ID, "error", err)
+			}
+		}
+	}
+}
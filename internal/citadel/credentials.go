@@ -0,0 +1,217 @@
+package citadel
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	gen "vinr.eu/vanguard/api/citadel/v1"
+	"vinr.eu/vanguard/internal/errs"
+)
+
+var (
+	ErrNoCredentials     = errors.New("citadel: no node credentials")
+	ErrCredentialsFailed = errors.New("citadel: node credentials failed")
+	ErrEnrollFailed      = errors.New("citadel: enrollment failed")
+)
+
+// accessTokenMargin renews access tokens this long before they expire.
+const accessTokenMargin = time.Minute
+
+type credentials struct {
+	NodeID       string `json:"nodeId"`
+	RefreshToken string `json:"refreshToken"`
+	// SnapshotKey seals the config snapshot; it survives credential rotation.
+	SnapshotKey string `json:"snapshotKey"`
+}
+
+// CredentialStore holds the credentials a node obtained by enrolling. The
+// refresh token is kept on disk; access tokens only live in memory.
+type CredentialStore struct {
+	path        string
+	mu          sync.Mutex
+	creds       credentials
+	accessToken string
+	expiresAt   time.Time
+}
+
+func LoadCredentials(path string) (*CredentialStore, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNoCredentials
+	}
+	if err != nil {
+		return nil, errs.Wrap(ErrCredentialsFailed, err)
+	}
+	s := &CredentialStore{path: path}
+	if err := json.Unmarshal(data, &s.creds); err != nil {
+		return nil, errs.Wrap(ErrCredentialsFailed, err)
+	}
+	if s.creds.NodeID == "" || s.creds.RefreshToken == "" {
+		return nil, errs.WrapMsg(ErrCredentialsFailed, "incomplete credentials in "+path)
+	}
+	return s, nil
+}
+
+func (s *CredentialStore) NodeID() string {
+	return s.creds.NodeID
+}
+
+func (s *CredentialStore) SnapshotKey() string {
+	return s.creds.SnapshotKey
+}
+
+func (s *CredentialStore) String() string {
+	return "citadel.CredentialStore{redacted}"
+}
+
+// update must be called with s.mu held.
+func (s *CredentialStore) update(nc *gen.NodeCredentials) error {
+	s.accessToken = nc.AccessToken
+	s.expiresAt = nc.ExpiresAt
+	if nc.RefreshToken == nil || *nc.RefreshToken == s.creds.RefreshToken {
+		return nil
+	}
+	s.creds.RefreshToken = *nc.RefreshToken
+	return s.save()
+}
+
+func (s *CredentialStore) save() error {
+	data, err := json.MarshalIndent(s.creds, "", "  ")
+	if err != nil {
+		return errs.Wrap(ErrCredentialsFailed, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return errs.Wrap(ErrCredentialsFailed, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".credentials-*")
+	if err != nil {
+		return errs.Wrap(ErrCredentialsFailed, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errs.Wrap(ErrCredentialsFailed, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errs.Wrap(ErrCredentialsFailed, err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return errs.Wrap(ErrCredentialsFailed, err)
+	}
+	return nil
+}
+
+// Enroll exchanges a one-time enrollment token for node credentials and
+// stores them at path.
+func (c *Client) Enroll(ctx context.Context, enrollmentToken, path string) (*CredentialStore, error) {
+	body := gen.EnrollRequest{EnrollmentToken: enrollmentToken}
+	if hostname, err := os.Hostname(); err == nil {
+		body.Hostname = &hostname
+	}
+	resp, err := c.anon.PostEnrollWithResponse(ctx, body)
+	err = validateResponse("Enroll", err, resp, func() []byte { return resp.Body }, func() bool { return resp.JSON200 != nil })
+	observe("Enroll", err)
+	if err != nil {
+		return nil, errs.Wrap(ErrEnrollFailed, err)
+	}
+	nc := resp.JSON200.Credentials
+	if nc.RefreshToken == nil {
+		return nil, errs.WrapMsg(ErrEnrollFailed, "no refresh token issued")
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, errs.Wrap(ErrEnrollFailed, err)
+	}
+	s := &CredentialStore{
+		path: path,
+		creds: credentials{
+			NodeID:       resp.JSON200.NodeId,
+			RefreshToken: *nc.RefreshToken,
+			SnapshotKey:  base64.StdEncoding.EncodeToString(key),
+		},
+		accessToken: nc.AccessToken,
+		expiresAt:   nc.ExpiresAt,
+	}
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// RotateCredentials replaces the node's refresh token; the old one is revoked
+// by Citadel.
+func (c *Client) RotateCredentials(ctx context.Context) error {
+	if c.creds == nil {
+		return ErrNoCredentials
+	}
+	resp, err := c.api.PostNodeIdRotateCredentialsWithResponse(ctx, c.nodeID)
+	err = validateResponse("RotateCredentials", err, resp, func() []byte { return resp.Body }, func() bool { return resp.JSON200 != nil })
+	observe("RotateCredentials", err)
+	if err != nil {
+		return err
+	}
+	if resp.JSON200.RefreshToken == nil {
+		return errs.WrapMsg(ErrCredentialsFailed, "no refresh token issued")
+	}
+	c.creds.mu.Lock()
+	defer c.creds.mu.Unlock()
+	return c.creds.update(resp.JSON200)
+}
+
+// reload picks up a refresh token written by another process, such as the
+// rotate-credentials command, and reports whether it changed. It must be
+// called with s.mu held.
+func (s *CredentialStore) reload() bool {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return false
+	}
+	var creds credentials
+	if err := json.Unmarshal(data, &creds); err != nil || creds.NodeID != s.creds.NodeID || creds.RefreshToken == "" {
+		return false
+	}
+	if creds.RefreshToken == s.creds.RefreshToken {
+		return false
+	}
+	s.creds.RefreshToken = creds.RefreshToken
+	return true
+}
+
+// accessToken returns a valid access token, refreshing it first when it is
+// about to expire. Holding the store lock coalesces concurrent refreshes.
+// A rejected refresh token is retried once with the one on disk, since a
+// rotation from another process revokes the token held here.
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	s := c.creds
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessToken != "" && time.Until(s.expiresAt) > accessTokenMargin {
+		return s.accessToken, nil
+	}
+	resp, err := c.refresh(ctx, s)
+	if errors.Is(err, ErrUnauthorized) && s.reload() {
+		resp, err = c.refresh(ctx, s)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := s.update(resp.JSON200); err != nil {
+		return "", err
+	}
+	return s.accessToken, nil
+}
+
+func (c *Client) refresh(ctx context.Context, s *CredentialStore) (*gen.PostNodeIdTokenResponse, error) {
+	resp, err := c.anon.PostNodeIdTokenWithResponse(ctx, s.creds.NodeID, gen.RefreshTokenRequest{RefreshToken: s.creds.RefreshToken})
+	err = validateResponse("RefreshToken", err, resp, func() []byte { return resp.Body }, func() bool { return resp.JSON200 != nil })
+	observe("RefreshToken", err)
+	return resp, err
+}
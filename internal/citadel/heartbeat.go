@@ -0,0 +1,43 @@
+package citadel
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type StatusFunc func() []ServiceStatus
+
+func (c *Client) RunHeartbeat(ctx context.Context, interval time.Duration, statuses StatusFunc) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	reachable := true
+	for {
+		err := c.beat(ctx, statuses)
+		if ctx.Err() != nil {
+			return
+		}
+		switch {
+		case err != nil && reachable:
+			slog.WarnContext(ctx, "citadel heartbeat failed", "node", c.nodeID, "error", err)
+			reachable = false
+		case err == nil && !reachable:
+			slog.InfoContext(ctx, "citadel heartbeat recovered", "node", c.nodeID)
+			reachable = true
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Client) beat(ctx context.Context, statuses StatusFunc) error {
+	ping, err := c.Ping(ctx, c.nodeID)
+	if err != nil {
+		return err
+	}
+	slog.DebugContext(ctx, "citadel ping", "status", ping.Status, "version", ping.Version)
+	return c.ReportStatus(ctx, c.nodeID, statuses())
+}
@@ -0,0 +1,265 @@
+package citadel
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	gen "vinr.eu/vanguard/api/citadel/v1"
+	"vinr.eu/vanguard/internal/errs"
+	"vinr.eu/vanguard/internal/redact"
+)
+
+var (
+	ErrSpoolFailed = errors.New("citadel: log spool failed")
+)
+
+const (
+	shipBatchLines    = 500
+	shipFlushInterval = 5 * time.Second
+	shipQueueLines    = 4096
+	spoolSuffix       = ".json.gz"
+)
+
+type LogEntry struct {
+	Service string
+	Stream  string
+	Time    time.Time
+	Text    string
+}
+
+// LogShipper batches service output, spools each batch gzip-compressed to
+// disk and ships the spool to Citadel oldest first. The spool survives
+// restarts and outages; once it outgrows its cap the oldest batches are
+// dropped.
+type LogShipper struct {
+	client   *Client
+	dir      string
+	maxBytes int64
+	redactor *redact.Redactor
+	queue    chan LogEntry
+	dropped  atomic.Int64
+}
+
+// NewLogShipper spools to dir, keeping at most maxBytes on disk. Secrets
+// known to redactor are masked again before a batch leaves the process, in
+// case they became known after the line was written.
+func (c *Client) NewLogShipper(dir string, maxBytes int64, redactor *redact.Redactor) (*LogShipper, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, errs.Wrap(ErrSpoolFailed, err)
+	}
+	// Batches are renamed into place once written, so a leftover temporary
+	// file is a write that was cut short by a crash.
+	partial, _ := filepath.Glob(filepath.Join(dir, "*"+spoolSuffix+".tmp"))
+	for _, tmp := range partial {
+		os.Remove(tmp)
+	}
+	return &LogShipper{
+		client:   c,
+		dir:      dir,
+		maxBytes: maxBytes,
+		redactor: redactor,
+		queue:    make(chan LogEntry, shipQueueLines),
+	}, nil
+}
+
+// Add queues a line without blocking; lines are dropped while the queue is
+// full.
+func (s *LogShipper) Add(entry LogEntry) {
+	select {
+	case s.queue <- entry:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Run ships until ctx is done, then spools whatever is still queued so it is
+// sent on the next start.
+func (s *LogShipper) Run(ctx context.Context) {
+	ticker := time.NewTicker(shipFlushInterval)
+	defer ticker.Stop()
+	var pending []LogEntry
+	flush := func() {
+		if n := s.dropped.Swap(0); n > 0 {
+			slog.Warn("log shipping queue full, lines dropped", "lines", n)
+		}
+		if len(pending) == 0 {
+			return
+		}
+		if err := s.spool(pending); err != nil {
+			slog.Warn("failed to spool logs", "lines", len(pending), "error", err)
+		}
+		pending = nil
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case entry := <-s.queue:
+					pending = append(pending, entry)
+				default:
+					flush()
+					return
+				}
+			}
+		case entry := <-s.queue:
+			pending = append(pending, entry)
+			if len(pending) >= shipBatchLines {
+				flush()
+				s.drain(ctx)
+			}
+		case <-ticker.C:
+			flush()
+			s.drain(ctx)
+		}
+	}
+}
+
+func (s *LogShipper) spool(entries []LogEntry) error {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return errs.Wrap(ErrSpoolFailed, err)
+	}
+	batch := gen.IngestLogsRequest{
+		BatchId: hex.EncodeToString(id),
+		NodeId:  s.client.nodeID,
+		Entries: make([]gen.LogEntry, len(entries)),
+	}
+	for i, e := range entries {
+		batch.Entries[i] = gen.LogEntry{
+			Service: e.Service,
+			Stream:  gen.LogEntryStream(e.Stream),
+			Time:    e.Time.UTC(),
+			Text:    s.redactor.String(e.Text),
+		}
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(batch); err != nil {
+		return errs.Wrap(ErrSpoolFailed, err)
+	}
+	if err := zw.Close(); err != nil {
+		return errs.Wrap(ErrSpoolFailed, err)
+	}
+	// Names sort in arrival order, which is the order batches are shipped in.
+	name := fmt.Sprintf("%020d-%s%s", time.Now().UnixNano(), batch.BatchId, spoolSuffix)
+	tmp := filepath.Join(s.dir, name+".tmp")
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return errs.Wrap(ErrSpoolFailed, err)
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		os.Remove(tmp)
+		return errs.Wrap(ErrSpoolFailed, err)
+	}
+	s.enforceCap()
+	return nil
+}
+
+type spoolFile struct {
+	path string
+	size int64
+}
+
+func (s *LogShipper) spooled() []spoolFile {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		slog.Warn("failed to read log spool", "dir", s.dir, "error", err)
+		return nil
+	}
+	var files []spoolFile
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), spoolSuffix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, spoolFile{path: filepath.Join(s.dir, e.Name()), size: info.Size()})
+	}
+	// ReadDir returns entries sorted by name, i.e. oldest first.
+	return files
+}
+
+func (s *LogShipper) enforceCap() {
+	files := s.spooled()
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	dropped := 0
+	for _, f := range files {
+		if total <= s.maxBytes {
+			break
+		}
+		if err := os.Remove(f.path); err != nil {
+			continue
+		}
+		total -= f.size
+		dropped++
+	}
+	if dropped > 0 {
+		slog.Warn("log spool over capacity, oldest batches dropped", "batches", dropped, "max_bytes", s.maxBytes)
+	}
+}
+
+// drain ships spooled batches oldest first and stops at the first failure;
+// the next flush tries again.
+func (s *LogShipper) drain(ctx context.Context) {
+	for _, f := range s.spooled() {
+		payload, err := os.ReadFile(f.path)
+		if err != nil {
+			slog.Warn("failed to read spooled logs", "path", f.path, "error", err)
+			continue
+		}
+		err = s.client.IngestLogs(ctx, s.client.nodeID, payload)
+		var apiErr *APIError
+		switch {
+		case err == nil:
+		case errors.As(err, &apiErr) && rejected(apiErr.StatusCode):
+			// Resending a batch Citadel refused will not change its mind.
+			slog.Warn("citadel rejected log batch, dropping it", "path", f.path, "error", err)
+		default:
+			if ctx.Err() == nil {
+				slog.Debug("log shipping failed, keeping spool", "error", err)
+			}
+			return
+		}
+		os.Remove(f.path)
+	}
+}
+
+func rejected(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return status >= 400 && status < 500
+}
+
+// IngestLogs sends a gzip-compressed IngestLogsRequest.
+func (c *Client) IngestLogs(ctx context.Context, id string, payload []byte) error {
+	// Batches carry a stable ID, so Citadel can drop duplicates of a retry.
+	resp, err := c.api.PostNodeIdLogsWithBodyWithResponse(idempotent(ctx), id, "application/json", bytes.NewReader(payload), gzipEncoded)
+	err = validateResponse("IngestLogs", err, resp, func() []byte { return resp.Body }, func() bool { return true })
+	observe("IngestLogs", err)
+	return err
+}
+
+func gzipEncoded(_ context.Context, req *http.Request) error {
+	req.Header.Set("Content-Encoding", "gzip")
+	return nil
+}
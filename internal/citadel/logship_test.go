@@ -0,0 +1,198 @@
+package citadel
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gen "vinr.eu/vanguard/api/citadel/v1"
+	"vinr.eu/vanguard/internal/redact"
+)
+
+// logSink accepts log batches like Citadel, answering 503 while down.
+type logSink struct {
+	mu      sync.Mutex
+	batches []gen.IngestLogsRequest
+	down    atomic.Bool
+}
+
+func (k *logSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if k.down.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var batch gen.IngestLogsRequest
+	zr, err := gzip.NewReader(r.Body)
+	if err == nil {
+		err = json.NewDecoder(zr).Decode(&batch)
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(gen.ErrorResponse{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	k.mu.Lock()
+	k.batches = append(k.batches, batch)
+	k.mu.Unlock()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// texts lists the first line of each received batch, in arrival order.
+func (k *logSink) texts() []string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var out []string
+	for _, b := range k.batches {
+		out = append(out, b.Entries[0].Text)
+	}
+	return out
+}
+
+func newTestShipper(t *testing.T, dir string, maxBytes int64) (*LogShipper, *logSink) {
+	t.Helper()
+	sink := &logSink{}
+	srv := httptest.NewServer(sink)
+	t.Cleanup(srv.Close)
+	c, err := NewClient(srv.URL,
+		WithAPIKey("node-1-key"),
+		WithNodeID("node-1"),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 1}),
+		WithCircuitBreaker(0, 0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := c.NewLogShipper(dir, maxBytes, redact.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, sink
+}
+
+// spoolBatch spools a batch whose first line is text, padded with random
+// lines that do not compress.
+func spoolBatch(t *testing.T, s *LogShipper, text string, padding int) {
+	t.Helper()
+	entries := []LogEntry{{Service: "api", Stream: "stdout", Time: time.Now(), Text: text}}
+	for range padding {
+		b := make([]byte, 32)
+		_, _ = rand.Read(b)
+		entries = append(entries, LogEntry{Service: "api", Stream: "stdout", Time: time.Now(), Text: hex.EncodeToString(b)})
+	}
+	if err := s.spool(entries); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func spoolSize(t *testing.T, s *LogShipper) (total int64, files int) {
+	t.Helper()
+	for _, f := range s.spooled() {
+		total += f.size
+		files++
+	}
+	return total, files
+}
+
+func TestLogSpoolCapDropsOldest(t *testing.T) {
+	const maxBytes = 16 << 10
+	s, sink := newTestShipper(t, t.TempDir(), maxBytes)
+	sink.down.Store(true)
+	var written []string
+	for i := range 20 {
+		text := "batch-" + string(rune('a'+i))
+		spoolBatch(t, s, text, 40)
+		written = append(written, text)
+		s.drain(context.Background())
+		if total, _ := spoolSize(t, s); total > maxBytes {
+			t.Fatalf("spool holds %d bytes after batch %d, cap is %d", total, i, maxBytes)
+		}
+	}
+	_, files := spoolSize(t, s)
+	if files == 0 || files == len(written) {
+		t.Fatalf("spool holds %d of %d batches, want the cap to have dropped some", files, len(written))
+	}
+
+	sink.down.Store(false)
+	s.drain(context.Background())
+	if want := written[len(written)-files:]; !slices.Equal(sink.texts(), want) {
+		t.Errorf("shipped %q, want the newest batches %q in order", sink.texts(), want)
+	}
+}
+
+func TestLogSpoolReplaysAfterFailedUpload(t *testing.T) {
+	s, sink := newTestShipper(t, t.TempDir(), 1<<20)
+	sink.down.Store(true)
+	for _, text := range []string{"first", "second", "third"} {
+		spoolBatch(t, s, text, 0)
+	}
+	s.drain(context.Background())
+	if _, files := spoolSize(t, s); files != 3 || len(sink.texts()) != 0 {
+		t.Fatalf("after a failed upload: %d spooled, %d shipped, want all 3 kept", files, len(sink.texts()))
+	}
+
+	sink.down.Store(false)
+	s.drain(context.Background())
+	if got := sink.texts(); !slices.Equal(got, []string{"first", "second", "third"}) {
+		t.Errorf("shipped %q, want the spool oldest first", got)
+	}
+	if _, files := spoolSize(t, s); files != 0 {
+		t.Errorf("%d batches left in the spool after shipping", files)
+	}
+	// Each batch keeps the ID it was spooled with, so Citadel can drop a
+	// duplicate of an upload that did arrive.
+	ids := make(map[string]bool)
+	sink.mu.Lock()
+	for _, b := range sink.batches {
+		ids[b.BatchId] = true
+	}
+	sink.mu.Unlock()
+	if len(ids) != 3 {
+		t.Errorf("batch IDs = %v, want 3 distinct", ids)
+	}
+}
+
+func TestLogSpoolRecoversPartialSegment(t *testing.T) {
+	dir := t.TempDir()
+	before, _ := newTestShipper(t, dir, 1<<20)
+	spoolBatch(t, before, "complete", 0)
+	spoolBatch(t, before, "cut short", 20)
+	files := before.spooled()
+	// A batch cut short on disk and a temporary file of a write in progress,
+	// as a crash leaves them.
+	data, err := os.ReadFile(files[1].path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(files[1].path, data[:len(data)/2], 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tmp := filepath.Join(dir, "99999999999999999999-partial"+spoolSuffix+".tmp")
+	if err := os.WriteFile(tmp, data[:10], 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, sink := newTestShipper(t, dir, 1<<20)
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("temporary file of an interrupted write kept: %v", err)
+	}
+	spoolBatch(t, s, "after restart", 0)
+	s.drain(context.Background())
+	if got := sink.texts(); !slices.Equal(got, []string{"complete", "after restart"}) {
+		t.Errorf("shipped %q, want the complete batches", got)
+	}
+	if _, files := spoolSize(t, s); files != 0 {
+		t.Errorf("%d batches left in the spool, want the truncated one dropped", files)
+	}
+}
@@ -0,0 +1,249 @@
+package citadel
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+
+	gen "vinr.eu/vanguard/api/citadel/v1"
+	"vinr.eu/vanguard/internal/defs"
+	"vinr.eu/vanguard/internal/defs/v1"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+// loadV1 decodes a service definition the way the local store does.
+func loadV1(t *testing.T, doc string) *defs.Service {
+	t.Helper()
+	data, err := yaml.YAMLToJSON([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var svc v1.Service
+	if err := json.Unmarshal(data, &svc); err != nil {
+		t.Fatal(err)
+	}
+	return defs.MapServiceV1(&svc)
+}
+
+// A service must map to the same definition whether it is read from a local
+// v1 file or delivered by Citadel as a ServiceDeployment.
+func TestServiceDeploymentMatchesV1(t *testing.T) {
+	tests := []struct {
+		name       string
+		yaml       string
+		deployment gen.ServiceDeployment
+	}{
+		{
+			name: "minimal",
+			yaml: `
+kind: Service
+defVersion: v1
+name: api
+runtime: {engine: node, version: "24.0.0"}
+gitURL: https://github.com/vinr-eu/api
+branch: main
+port: 3000
+runScript: node server.js
+`,
+			deployment: gen.ServiceDeployment{
+				Kind: "Service", DefVersion: "v1", Name: "api",
+				Runtime:   gen.RuntimeSpec{Engine: "node", Version: "24.0.0"},
+				GitUrl:    "https://github.com/vinr-eu/api",
+				Branch:    "main",
+				Port:      3000,
+				RunScript: ptr("node server.js"),
+			},
+		},
+		{
+			name: "path and files",
+			yaml: `
+kind: Service
+defVersion: v1
+name: billing
+runtime: {engine: openjdk, version: "21"}
+gitURL: https://github.com/vinr-eu/monorepo
+branch: release
+path: services/billing
+port: 8080
+runScript: java -jar target/billing.jar
+files:
+  - name: application.yaml
+    path: config/application.yaml
+    template: "db: {{ .DB_URL }}"
+  - name: tls-key
+    ref: vault/tls#key
+    env: TLS_KEY_FILE
+`,
+			deployment: gen.ServiceDeployment{
+				Kind: "Service", DefVersion: "v1", Name: "billing",
+				Runtime:   gen.RuntimeSpec{Engine: "openjdk", Version: "21"},
+				GitUrl:    "https://github.com/vinr-eu/monorepo",
+				Branch:    "release",
+				Path:      ptr("services/billing"),
+				Port:      8080,
+				RunScript: ptr("java -jar target/billing.jar"),
+				Files: &[]gen.ServiceFile{
+					{Name: "application.yaml", Path: ptr("config/application.yaml"), Template: ptr("db: {{ .DB_URL }}")},
+					{Name: "tls-key", Ref: ptr("vault/tls#key"), Env: ptr("TLS_KEY_FILE")},
+				},
+			},
+		},
+		{
+			name: "ingress",
+			yaml: `
+kind: Service
+defVersion: v1
+name: web
+runtime: {engine: node, version: "24.0.0"}
+gitURL: https://github.com/vinr-eu/web
+branch: main
+port: 3001
+runScript: npm start
+ingressHost: web.example.com
+ingress:
+  auth:
+    basic:
+      realm: staff
+      users:
+        - username: alice
+          password: {ref: citadel/alice-password}
+    apiKey:
+      header: X-Key
+      keys:
+        - value: key-1
+    ipAllowlist: [10.0.0.0/8]
+    oidc:
+      issuerURL: https://accounts.example.com
+      clientID: web
+      clientSecret: {ref: aws/web-oidc#secret}
+      cookieSecret: {value: cookie}
+      scopes: [openid, email]
+      allowedDomains: [example.com]
+  middlewares:
+    - headers:
+        response:
+          set: {X-Frame-Options: DENY}
+          remove: [Server]
+    - cors:
+        allowOrigins: ["https://example.com"]
+        allowCredentials: true
+        maxAge: 600
+    - rateLimit:
+        requestsPerSecond: 2.5
+        burst: 10
+    - timeout:
+        duration: 30s
+`,
+			deployment: gen.ServiceDeployment{
+				Kind: "Service", DefVersion: "v1", Name: "web",
+				Runtime:     gen.RuntimeSpec{Engine: "node", Version: "24.0.0"},
+				GitUrl:      "https://github.com/vinr-eu/web",
+				Branch:      "main",
+				Port:        3001,
+				RunScript:   ptr("npm start"),
+				IngressHost: ptr("web.example.com"),
+				Ingress: &gen.Ingress{
+					Auth: &gen.IngressAuth{
+						Basic: &gen.BasicAuth{
+							Realm: ptr("staff"),
+							Users: []gen.BasicUser{{Username: "alice", Password: gen.SecretValue{Ref: ptr("citadel/alice-password")}}},
+						},
+						ApiKey: &gen.APIKeyAuth{
+							Header: ptr("X-Key"),
+							Keys:   []gen.SecretValue{{Value: ptr("key-1")}},
+						},
+						IpAllowlist: &[]string{"10.0.0.0/8"},
+						Oidc: &gen.OIDCAuth{
+							IssuerURL:      "https://accounts.example.com",
+							ClientID:       "web",
+							ClientSecret:   gen.SecretValue{Ref: ptr("aws/web-oidc#secret")},
+							CookieSecret:   gen.SecretValue{Value: ptr("cookie")},
+							Scopes:         &[]string{"openid", "email"},
+							AllowedDomains: &[]string{"example.com"},
+						},
+					},
+					Middlewares: &[]gen.Middleware{
+						{Headers: &gen.HeadersMiddleware{Response: &gen.HeaderRules{
+							Set:    &map[string]string{"X-Frame-Options": "DENY"},
+							Remove: &[]string{"Server"},
+						}}},
+						{Cors: &gen.CORSMiddleware{
+							AllowOrigins:     []string{"https://example.com"},
+							AllowCredentials: ptr(true),
+							MaxAge:           ptr(600),
+						}},
+						{RateLimit: &gen.RateLimitMiddleware{RequestsPerSecond: 2.5, Burst: ptr(10)}},
+						{Timeout: &gen.TimeoutMiddleware{Duration: "30s"}},
+					},
+				},
+			},
+		},
+		{
+			name: "variables",
+			yaml: `
+kind: Service
+defVersion: v1
+name: worker
+runtime: {engine: node, version: "24.0.0"}
+gitURL: https://github.com/vinr-eu/worker
+branch: main
+port: 3002
+runScript: node worker.js
+variables:
+  - name: LOG_LEVEL
+    value: debug
+  - name: DB
+    ref: vault/db
+    keys: {username: DB_USER, password: DB_PASSWORD}
+    onChange: signal
+  - name: SERVICE_ACCOUNT
+    ref: aws/worker-sa
+    mode: raw
+    onChange: none
+`,
+			deployment: gen.ServiceDeployment{
+				Kind: "Service", DefVersion: "v1", Name: "worker",
+				Runtime:   gen.RuntimeSpec{Engine: "node", Version: "24.0.0"},
+				GitUrl:    "https://github.com/vinr-eu/worker",
+				Branch:    "main",
+				Port:      3002,
+				RunScript: ptr("node worker.js"),
+				Variables: &[]gen.EnvironmentVariable{
+					{Name: "LOG_LEVEL", Value: ptr("debug")},
+					{
+						Name:     "DB",
+						Ref:      ptr("vault/db"),
+						Keys:     &map[string]string{"username": "DB_USER", "password": "DB_PASSWORD"},
+						OnChange: ptr(gen.EnvironmentVariableOnChangeSignal),
+					},
+					{
+						Name:     "SERVICE_ACCOUNT",
+						Ref:      ptr("aws/worker-sa"),
+						Mode:     ptr(gen.Raw),
+						OnChange: ptr(gen.EnvironmentVariableOnChangeNone),
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := loadV1(t, tt.yaml)
+			cfg, err := mapNodeConfig(&gen.GetNodeConfigResponse{
+				Revision:           "1",
+				ServiceDeployments: []gen.ServiceDeployment{tt.deployment},
+			})
+			if err != nil {
+				t.Fatalf("mapNodeConfig: %v", err)
+			}
+			if got := cfg.Services[0]; !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.MarshalIndent(got, "", "  ")
+				wantJSON, _ := json.MarshalIndent(want, "", "  ")
+				t.Errorf("citadel service differs from v1 file\ngot:  %s\nwant: %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package citadel
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+	"sync"
+)
+
+// SecretCache resolves the secrets Citadel brokers for a node and remembers
+// the last value of each. The values travel with the config snapshot, so a
+// node booting while Citadel is unreachable still starts its services.
+type SecretCache struct {
+	client *Client
+	nodeID string
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (c *Client) SecretCache(nodeID string) *SecretCache {
+	return &SecretCache{client: c, nodeID: nodeID, values: make(map[string]string)}
+}
+
+// Resolve fetches name from Citadel, falling back to its last known value
+// when Citadel cannot be reached.
+func (s *SecretCache) Resolve(ctx context.Context, name string) (string, error) {
+	value, err := s.client.GetSecret(ctx, s.nodeID, name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		s.values[name] = value
+		return value, nil
+	}
+	if last, ok := s.values[name]; ok && unreachable(err) {
+		slog.WarnContext(ctx, "citadel unreachable, using last known secret", "name", name, "error", err)
+		return last, nil
+	}
+	return "", err
+}
+
+// Values returns the last known value of every secret resolved so far.
+func (s *SecretCache) Values() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.values)
+}
+
+// Restore seeds the last known values, e.g. from a snapshot. Values fetched
+// since take precedence.
+func (s *SecretCache) Restore(values map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, value := range values {
+		if _, ok := s.values[name]; !ok {
+			s.values[name] = value
+		}
+	}
+}
+
+// unreachable tells failures to reach Citadel apart from answers it gave.
+func unreachable(err error) bool {
+	if errors.Is(err, ErrNetwork) || errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode >= http.StatusInternalServerError
+}
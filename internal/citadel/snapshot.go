@@ -0,0 +1,123 @@
+package citadel
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	gen "vinr.eu/vanguard/api/citadel/v1"
+	"vinr.eu/vanguard/internal/errs"
+)
+
+var (
+	ErrNoSnapshot      = errors.New("citadel: no config snapshot")
+	ErrSnapshotFailed  = errors.New("citadel: config snapshot failed")
+	ErrSnapshotCorrupt = errors.New("citadel: config snapshot unreadable")
+)
+
+// Snapshot keeps the last applied node configuration on disk, together with
+// the last values of the secrets Citadel brokered for it, sealed with AES-GCM.
+// The node ID is bound as additional data so a snapshot cannot be replayed
+// onto another node.
+type Snapshot struct {
+	path   string
+	nodeID string
+	aead   cipher.AEAD
+}
+
+type snapshotData struct {
+	Config  *gen.GetNodeConfigResponse `json:"config"`
+	Secrets map[string]string          `json:"secrets,omitempty"`
+}
+
+func NewSnapshot(path, secret, nodeID string) (*Snapshot, error) {
+	key := sha256.Sum256([]byte("vanguard-config-snapshot:" + secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, errs.Wrap(ErrSnapshotFailed, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errs.Wrap(ErrSnapshotFailed, err)
+	}
+	return &Snapshot{path: path, nodeID: nodeID, aead: aead}, nil
+}
+
+func (s *Snapshot) Save(cfg *NodeConfig, secrets map[string]string) error {
+	if cfg.raw == nil {
+		return errs.WrapMsg(ErrSnapshotFailed, "config was not received from citadel")
+	}
+	plain, err := json.Marshal(snapshotData{Config: cfg.raw, Secrets: secrets})
+	if err != nil {
+		return errs.Wrap(ErrSnapshotFailed, err)
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return errs.Wrap(ErrSnapshotFailed, err)
+	}
+	sealed := s.aead.Seal(nonce, nonce, plain, []byte(s.nodeID))
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return errs.Wrap(ErrSnapshotFailed, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".snapshot-*")
+	if err != nil {
+		return errs.Wrap(ErrSnapshotFailed, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(sealed); err != nil {
+		tmp.Close()
+		return errs.Wrap(ErrSnapshotFailed, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errs.Wrap(ErrSnapshotFailed, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errs.Wrap(ErrSnapshotFailed, err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return errs.Wrap(ErrSnapshotFailed, err)
+	}
+	return nil
+}
+
+// Load returns the saved config and secret values.
+func (s *Snapshot) Load() (*NodeConfig, map[string]string, error) {
+	sealed, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, ErrNoSnapshot
+	}
+	if err != nil {
+		return nil, nil, errs.Wrap(ErrSnapshotFailed, err)
+	}
+	n := s.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, nil, errs.WrapMsg(ErrSnapshotCorrupt, "truncated")
+	}
+	plain, err := s.aead.Open(nil, sealed[:n], sealed[n:], []byte(s.nodeID))
+	if err != nil {
+		return nil, nil, errs.Wrap(ErrSnapshotCorrupt, err)
+	}
+	var data snapshotData
+	if err := json.Unmarshal(plain, &data); err != nil {
+		return nil, nil, errs.Wrap(ErrSnapshotCorrupt, err)
+	}
+	// Snapshots written before secrets were kept hold the bare config.
+	if data.Config == nil {
+		data.Config = &gen.GetNodeConfigResponse{}
+		if err := json.Unmarshal(plain, data.Config); err != nil {
+			return nil, nil, errs.Wrap(ErrSnapshotCorrupt, err)
+		}
+	}
+	cfg, err := mapNodeConfig(data.Config)
+	if err != nil {
+		return nil, nil, errs.Wrap(ErrSnapshotCorrupt, err)
+	}
+	return cfg, data.Secrets, nil
+}
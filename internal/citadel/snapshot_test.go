@@ -0,0 +1,172 @@
+package citadel
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gen "vinr.eu/vanguard/api/citadel/v1"
+)
+
+func testNodeConfig(t *testing.T) *NodeConfig {
+	t.Helper()
+	cfg, err := mapNodeConfig(&gen.GetNodeConfigResponse{
+		Revision: "rev-7",
+		Type:     gen.Vanguard,
+		ServiceDeployments: []gen.ServiceDeployment{{
+			Kind: "Service", DefVersion: "v1", Name: "api",
+			Runtime: gen.RuntimeSpec{Engine: "node", Version: "24.0.0"},
+			GitUrl:  "https://github.com/vinr-eu/api",
+			Branch:  "main",
+			Port:    3000,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func newTestSnapshot(t *testing.T, path, secret, nodeID string) *Snapshot {
+	t.Helper()
+	s, err := NewSnapshot(path, secret, nodeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "citadel", "node-config.snapshot")
+	s := newTestSnapshot(t, path, "snapshot-key", "node-1")
+	if _, _, err := s.Load(); !errors.Is(err, ErrNoSnapshot) {
+		t.Fatalf("Load before Save: err = %v, want ErrNoSnapshot", err)
+	}
+	secrets := map[string]string{"citadel/db": "hunter2-brokered"}
+	if err := s.Save(testNodeConfig(t), secrets); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("mode = %v, want 0600", mode)
+	}
+	sealed, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(sealed), "hunter2-brokered") || strings.Contains(string(sealed), "rev-7") {
+		t.Error("snapshot holds plaintext")
+	}
+
+	cfg, got, err := newTestSnapshot(t, path, "snapshot-key", "node-1").Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Revision != "rev-7" || len(cfg.Services) != 1 || cfg.Services[0].Name != "api" || cfg.Services[0].Port != 3000 {
+		t.Errorf("config = %+v", cfg)
+	}
+	if !maps.Equal(got, secrets) {
+		t.Errorf("secrets = %v, want %v", got, secrets)
+	}
+	if err := s.Save(cfg, nil); err != nil {
+		t.Errorf("re-saving a loaded config: %v", err)
+	}
+	if err := s.Save(&NodeConfig{Revision: "local"}, nil); !errors.Is(err, ErrSnapshotFailed) {
+		t.Errorf("Save without a Citadel config: err = %v, want ErrSnapshotFailed", err)
+	}
+}
+
+func TestSnapshotWrongKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node-config.snapshot")
+	if err := newTestSnapshot(t, path, "snapshot-key", "node-1").Save(testNodeConfig(t), nil); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, secret, nodeID string
+	}{
+		{"other key", "another-key", "node-1"},
+		{"other node", "snapshot-key", "node-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, _, err := newTestSnapshot(t, path, tt.secret, tt.nodeID).Load()
+			if !errors.Is(err, ErrSnapshotCorrupt) || cfg != nil {
+				t.Fatalf("Load = %v, %v, want ErrSnapshotCorrupt", cfg, err)
+			}
+		})
+	}
+}
+
+func TestSnapshotCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "node-config.snapshot")
+	s := newTestSnapshot(t, path, "snapshot-key", "node-1")
+	if err := s.Save(testNodeConfig(t), map[string]string{"a": "b"}); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flip := func(i int) []byte {
+		b := append([]byte(nil), sealed...)
+		b[i] ^= 0x01
+		return b
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"shorter than a nonce", sealed[:5]},
+		{"nonce only", sealed[:s.aead.NonceSize()]},
+		{"truncated", sealed[:len(sealed)-1]},
+		{"nonce flipped", flip(0)},
+		{"ciphertext flipped", flip(len(sealed) / 2)},
+		{"tag flipped", flip(len(sealed) - 1)},
+		{"garbage", []byte(strings.Repeat("not a snapshot ", 10))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, tt.data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			cfg, secrets, err := s.Load()
+			if !errors.Is(err, ErrSnapshotCorrupt) || cfg != nil || secrets != nil {
+				t.Fatalf("Load = %v, %v, %v, want ErrSnapshotCorrupt", cfg, secrets, err)
+			}
+		})
+	}
+
+	// A well-sealed payload that is not a config is corrupt as well.
+	seal := func(plain string) []byte {
+		nonce := make([]byte, s.aead.NonceSize())
+		return s.aead.Seal(nonce, nonce, []byte(plain), []byte("node-1"))
+	}
+	if err := os.WriteFile(path, seal("[1, 2"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Load(); !errors.Is(err, ErrSnapshotCorrupt) {
+		t.Errorf("sealed garbage: err = %v, want ErrSnapshotCorrupt", err)
+	}
+
+	// Snapshots from before secrets were kept hold the bare config.
+	raw, err := json.Marshal(testNodeConfig(t).raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, seal(string(raw)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, secrets, err := s.Load()
+	if err != nil || cfg.Revision != "rev-7" || secrets != nil {
+		t.Errorf("legacy snapshot: Load = %v, %v, %v", cfg, secrets, err)
+	}
+}
@@ -0,0 +1,80 @@
+package citadel
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+const (
+	configWait       = 55 * time.Second
+	minPollInterval  = 5 * time.Second
+	maxWatchBackoff  = time.Minute
+	initWatchBackoff = time.Second
+)
+
+type ApplyFunc func(ctx context.Context, cfg *NodeConfig) error
+
+// WatchConfig long-polls Citadel for configuration revisions newer than the
+// given one, applies each through apply and acknowledges the outcome.
+func (c *Client) WatchConfig(ctx context.Context, revision string, apply ApplyFunc) {
+	backoff := initWatchBackoff
+	for {
+		// The minimum interval keeps a Citadel that answers without holding
+		// the request from being polled in a tight loop.
+		started := time.Now()
+		cfg, err := c.WaitNodeConfig(ctx, c.nodeID, revision, configWait)
+		if ctx.Err() != nil {
+			return
+		}
+		switch {
+		case errors.Is(err, ErrNotModified):
+			backoff = initWatchBackoff
+			if !sleep(ctx, minPollInterval-time.Since(started)) {
+				return
+			}
+			continue
+		case err != nil:
+			slog.WarnContext(ctx, "citadel config poll failed", "node", c.nodeID, "retry_in", backoff, "error", err)
+			if !sleep(ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, maxWatchBackoff)
+			continue
+		}
+		backoff = initWatchBackoff
+		if cfg.Revision == revision {
+			if !sleep(ctx, minPollInterval-time.Since(started)) {
+				return
+			}
+			continue
+		}
+
+		slog.InfoContext(ctx, "applying citadel config", "node", c.nodeID, "from", revision, "to", cfg.Revision)
+		applyErr := apply(ctx, cfg)
+		if applyErr != nil {
+			slog.ErrorContext(ctx, "citadel config applied with errors", "revision", cfg.Revision, "error", applyErr)
+		}
+		// A failed revision is not retried; Citadel learns about it from the ack
+		// and is expected to publish a fix as a new revision.
+		revision = cfg.Revision
+		if err := c.AckConfig(ctx, c.nodeID, revision, applyErr); err != nil {
+			slog.WarnContext(ctx, "citadel config ack failed", "revision", revision, "error", err)
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
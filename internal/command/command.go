@@ -0,0 +1,89 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"time"
+
+	"vinr.eu/vanguard/internal/citadel"
+	"vinr.eu/vanguard/internal/environment"
+	"vinr.eu/vanguard/internal/errs"
+)
+
+var (
+	ErrUnknownCommand = errors.New("command: unknown command type")
+	ErrMissingService = errors.New("command: service is required")
+)
+
+const defaultLogLines = 100
+
+type Handler struct {
+	manager  *environment.Manager
+	statuses func() []citadel.ServiceStatus
+	started  time.Time
+}
+
+func NewHandler(m *environment.Manager, statuses func() []citadel.ServiceStatus) *Handler {
+	return &Handler{
+		manager:  m,
+		statuses: statuses,
+		started:  time.Now(),
+	}
+}
+
+func (h *Handler) Handle(ctx context.Context, cmd citadel.Command) citadel.CommandResult {
+	result := citadel.CommandResult{StartedAt: time.Now()}
+	result.Err = h.run(ctx, cmd, &result)
+	result.FinishedAt = time.Now()
+	return result
+}
+
+func (h *Handler) run(ctx context.Context, cmd citadel.Command, result *citadel.CommandResult) error {
+	switch cmd.Type {
+	case citadel.CommandRestart, citadel.CommandRedeploy, citadel.CommandLogs:
+		if cmd.Service == "" {
+			return errs.WrapMsg(ErrMissingService, cmd.Type)
+		}
+	}
+	switch cmd.Type {
+	case citadel.CommandRestart:
+		return h.manager.Restart(ctx, cmd.Service)
+	case citadel.CommandRedeploy:
+		return h.manager.Redeploy(ctx, cmd.Service, cmd.Commit)
+	case citadel.CommandLogs:
+		lines := cmd.Lines
+		if lines <= 0 {
+			lines = defaultLogLines
+		}
+		logs, err := h.manager.Logs(cmd.Service, lines)
+		if err != nil {
+			return err
+		}
+		result.Logs = make([]citadel.LogLine, len(logs))
+		for i, l := range logs {
+			result.Logs[i] = citadel.LogLine{Time: l.Time, Stream: l.Stream, Text: l.Text}
+		}
+		return nil
+	case citadel.CommandDiagnose:
+		result.Diagnostics = h.diagnose()
+		return nil
+	default:
+		return errs.WrapMsg(ErrUnknownCommand, cmd.Type)
+	}
+}
+
+func (h *Handler) diagnose() *citadel.Diagnostics {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	hostname, _ := os.Hostname()
+	return &citadel.Diagnostics{
+		Hostname:    hostname,
+		Version:     runtime.Version(),
+		Uptime:      time.Since(h.started),
+		Goroutines:  runtime.NumGoroutine(),
+		MemoryBytes: int64(mem.Sys),
+		Services:    h.statuses(),
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"time"
 
 	"vinr.eu/vanguard/internal/errs"
 )
@@ -12,6 +15,8 @@ var (
 	ErrInvalidMode        = errors.New("config: MODE must be 'local' or 'server'")
 	ErrMissingEnvDefs     = errors.New("config: ENV_DEFS configuration incomplete")
 	ErrMissingCitadelDefs = errors.New("config: CITADEL configuration incomplete")
+	ErrInvalidLogFormat   = errors.New("config: ACCESS_LOG_FORMAT must be 'text' or 'json'")
+	ErrInvalidValue       = errors.New("config: invalid value")
 )
 
 type Config struct {
@@ -22,6 +27,53 @@ type Config struct {
 	CitadelURL    string
 	CitadelAPIKey string
 	CitadelNodeID string
+	AdminAddr     string
+	OTLPEndpoint  string
+
+	// CitadelSnapshotKey seals the cached node config; it defaults to the API key.
+	CitadelSnapshotKey     string
+	CitadelEnrollmentToken string
+	HeartbeatInterval      time.Duration
+
+	// CitadelRetry* shape the backoff of idempotent Citadel calls; one attempt
+	// disables retries. The breaker opens after CitadelBreakerThreshold
+	// consecutive failures for CitadelBreakerCooldown; zero disables it.
+	CitadelRetryMaxAttempts int
+	CitadelRetryBaseDelay   time.Duration
+	CitadelRetryMaxDelay    time.Duration
+	CitadelBreakerThreshold int
+	CitadelBreakerCooldown  time.Duration
+
+	LogShipping         bool
+	LogShippingBufferMB int
+
+	// SecretsFileDir is the root that file/ secret refs are resolved against.
+	SecretsFileDir string
+	// SecretsCacheTTL is how long resolved refs are reused; zero disables
+	// the cache. SecretsRefreshInterval is how often refs are fetched again
+	// to detect rotation; zero disables it.
+	SecretsCacheTTL        time.Duration
+	SecretsRefreshInterval time.Duration
+	VaultAddr              string
+	VaultToken             string
+	VaultNamespace         string
+
+	// AgeKey and AgeKeyFile hold the age identities that decrypt SOPS and age
+	// values in the definitions.
+	AgeKey     string
+	AgeKeyFile string
+
+	GitHubAppID             int
+	GitHubAppInstallationID int
+	GitHubAppPrivateKey     string
+	GitHubAppPrivateKeyFile string
+
+	AccessLogFormat     string
+	AccessLogFile       string
+	AccessLogMaxSizeMB  int
+	AccessLogMaxBackups int
+	AccessLogMaxAgeDays int
+	AccessLogCompress   bool
 }
 
 func Load() (*Config, error) {
@@ -33,6 +85,74 @@ func Load() (*Config, error) {
 		CitadelURL:    os.Getenv("CITADEL_URL"),
 		CitadelAPIKey: os.Getenv("CITADEL_API_KEY"),
 		CitadelNodeID: os.Getenv("CITADEL_NODE_ID"),
+		AdminAddr:     getEnv("ADMIN_ADDR", "127.0.0.1:9090"),
+		OTLPEndpoint:  getEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")),
+
+		CitadelSnapshotKey:     getEnv("CITADEL_SNAPSHOT_KEY", os.Getenv("CITADEL_API_KEY")),
+		CitadelEnrollmentToken: os.Getenv("CITADEL_ENROLLMENT_TOKEN"),
+
+		SecretsFileDir: getEnv("SECRETS_FILE_DIR", "/run/secrets"),
+		VaultAddr:      os.Getenv("VAULT_ADDR"),
+		VaultToken:     os.Getenv("VAULT_TOKEN"),
+		VaultNamespace: os.Getenv("VAULT_NAMESPACE"),
+
+		AgeKey: os.Getenv("SOPS_AGE_KEY"),
+
+		GitHubAppPrivateKey:     os.Getenv("GITHUB_APP_PRIVATE_KEY"),
+		GitHubAppPrivateKeyFile: os.Getenv("GITHUB_APP_PRIVATE_KEY_FILE"),
+
+		AccessLogFormat: getEnv("ACCESS_LOG_FORMAT", "text"),
+		AccessLogFile:   os.Getenv("ACCESS_LOG_FILE"),
+	}
+	cfg.AgeKeyFile = getEnv("SOPS_AGE_KEY_FILE", filepath.Join(cfg.WorkspaceDir, "age", "keys.txt"))
+	var err error
+	if cfg.AccessLogMaxSizeMB, err = getEnvInt("ACCESS_LOG_MAX_SIZE_MB", 100); err != nil {
+		return nil, err
+	}
+	if cfg.AccessLogMaxBackups, err = getEnvInt("ACCESS_LOG_MAX_BACKUPS", 5); err != nil {
+		return nil, err
+	}
+	if cfg.AccessLogMaxAgeDays, err = getEnvInt("ACCESS_LOG_MAX_AGE_DAYS", 0); err != nil {
+		return nil, err
+	}
+	if cfg.AccessLogCompress, err = getEnvBool("ACCESS_LOG_COMPRESS", false); err != nil {
+		return nil, err
+	}
+	if cfg.GitHubAppID, err = getEnvInt("GITHUB_APP_ID", 0); err != nil {
+		return nil, err
+	}
+	if cfg.GitHubAppInstallationID, err = getEnvInt("GITHUB_APP_INSTALLATION_ID", 0); err != nil {
+		return nil, err
+	}
+	if cfg.HeartbeatInterval, err = getEnvDuration("HEARTBEAT_INTERVAL", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.CitadelRetryMaxAttempts, err = getEnvInt("CITADEL_RETRY_MAX_ATTEMPTS", 4); err != nil {
+		return nil, err
+	}
+	if cfg.CitadelRetryBaseDelay, err = getEnvDuration("CITADEL_RETRY_BASE_DELAY", 200*time.Millisecond); err != nil {
+		return nil, err
+	}
+	if cfg.CitadelRetryMaxDelay, err = getEnvDuration("CITADEL_RETRY_MAX_DELAY", 5*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.CitadelBreakerThreshold, err = getEnvInt("CITADEL_BREAKER_THRESHOLD", 5); err != nil {
+		return nil, err
+	}
+	if cfg.CitadelBreakerCooldown, err = getEnvDuration("CITADEL_BREAKER_COOLDOWN", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.SecretsCacheTTL, err = getEnvDuration("SECRETS_CACHE_TTL", 5*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.SecretsRefreshInterval, err = getEnvDuration("SECRETS_REFRESH_INTERVAL", 5*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.LogShipping, err = getEnvBool("LOG_SHIPPING", false); err != nil {
+		return nil, err
+	}
+	if cfg.LogShippingBufferMB, err = getEnvInt("LOG_SHIPPING_BUFFER_MB", 100); err != nil {
+		return nil, err
 	}
 	if err := cfg.validate(); err != nil {
 		return nil, err
@@ -47,19 +167,46 @@ func (c *Config) validate() error {
 			return ErrMissingEnvDefs
 		}
 	case "server":
-		if c.CitadelNodeID == "" || c.CitadelAPIKey == "" || c.CitadelURL == "" {
+		// Without an API key the node authenticates with enrolled credentials.
+		if c.CitadelURL == "" || (c.CitadelAPIKey != "" && c.CitadelNodeID == "") {
 			return ErrMissingCitadelDefs
 		}
 	default:
 		return errs.WrapMsg(ErrInvalidMode, "got "+c.Mode)
 	}
+	switch c.AccessLogFormat {
+	case "text", "json":
+	default:
+		return errs.WrapMsg(ErrInvalidLogFormat, "got "+c.AccessLogFormat)
+	}
+	if c.GitHubAppID != 0 && (c.GitHubAppInstallationID == 0 || (c.GitHubAppPrivateKey == "" && c.GitHubAppPrivateKeyFile == "")) {
+		return errs.WrapMsg(ErrInvalidValue, "GITHUB_APP_ID requires GITHUB_APP_INSTALLATION_ID and a private key")
+	}
+	if c.HeartbeatInterval <= 0 {
+		return errs.WrapMsg(ErrInvalidValue, "HEARTBEAT_INTERVAL must be positive")
+	}
+	if c.CitadelRetryMaxAttempts < 1 {
+		return errs.WrapMsg(ErrInvalidValue, "CITADEL_RETRY_MAX_ATTEMPTS must be at least 1")
+	}
+	if c.CitadelRetryBaseDelay < 0 || c.CitadelRetryMaxDelay < c.CitadelRetryBaseDelay {
+		return errs.WrapMsg(ErrInvalidValue, "CITADEL_RETRY_MAX_DELAY must not be below CITADEL_RETRY_BASE_DELAY")
+	}
+	if c.CitadelBreakerThreshold < 0 || c.CitadelBreakerCooldown < 0 {
+		return errs.WrapMsg(ErrInvalidValue, "CITADEL_BREAKER_THRESHOLD and CITADEL_BREAKER_COOLDOWN must not be negative")
+	}
+	if c.SecretsCacheTTL < 0 || c.SecretsRefreshInterval < 0 {
+		return errs.WrapMsg(ErrInvalidValue, "SECRETS_CACHE_TTL and SECRETS_REFRESH_INTERVAL must not be negative")
+	}
+	if c.LogShipping && c.LogShippingBufferMB <= 0 {
+		return errs.WrapMsg(ErrInvalidValue, "LOG_SHIPPING_BUFFER_MB must be positive")
+	}
 	return nil
 }
 
 func (c *Config) String() string {
 	return fmt.Sprintf(
-		"Mode=%s WorkspaceDir=%s EnvDefsGitURL=%s EnvDefsDir=%s",
-		c.Mode, c.WorkspaceDir, c.EnvDefsGitURL, c.EnvDefsDir,
+		"Mode=%s WorkspaceDir=%s EnvDefsGitURL=%s EnvDefsDir=%s AdminAddr=%s OTLPEndpoint=%s AccessLogFormat=%s AccessLogFile=%s",
+		c.Mode, c.WorkspaceDir, c.EnvDefsGitURL, c.EnvDefsDir, c.AdminAddr, c.OTLPEndpoint, c.AccessLogFormat, c.AccessLogFile,
 	)
 }
 
@@ -69,3 +216,39 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) (int, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errs.WrapMsgErr(ErrInvalidValue, key, err)
+	}
+	return n, nil
+}
+
+func getEnvBool(key string, fallback bool) (bool, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errs.WrapMsgErr(ErrInvalidValue, key, err)
+	}
+	return b, nil
+}
+
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errs.WrapMsgErr(ErrInvalidValue, key, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,67 @@
+package defs
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"vinr.eu/vanguard/internal/errs"
+)
+
+// splitRef separates a ref from its "#<path>" field selector.
+func splitRef(ref string) (string, string) {
+	base, field, _ := strings.Cut(ref, "#")
+	return base, field
+}
+
+func parseJSON(secret string) (any, bool) {
+	dec := json.NewDecoder(strings.NewReader(secret))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return nil, false
+	}
+	return v, true
+}
+
+// selectField returns the field of a JSON secret at a dotted path such as
+// "db.password" or "hosts.0".
+func selectField(secret, path string) (string, error) {
+	v, ok := parseJSON(secret)
+	if !ok {
+		return "", errs.WrapMsg(ErrFieldNotFound, path+": secret is not JSON")
+	}
+	for _, part := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			if v, ok = node[part]; !ok {
+				return "", errs.WrapMsg(ErrFieldNotFound, path)
+			}
+		case []any:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", errs.WrapMsg(ErrFieldNotFound, path)
+			}
+			v = node[i]
+		default:
+			return "", errs.WrapMsg(ErrFieldNotFound, path)
+		}
+	}
+	return fieldValue(v), nil
+}
+
+// fieldValue renders strings as they are and any other JSON value as JSON.
+func fieldValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
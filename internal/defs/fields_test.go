@@ -0,0 +1,182 @@
+package defs
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestSelectField(t *testing.T) {
+	const secret = `{"db": {"password": "pw", "port": 5432, "ratio": 0.5, "tls": true, "opts": null,
+		"hosts": ["a", "b"], "replica": {"host": "r"}}, "big": 12345678901234567890}`
+	tests := []struct {
+		path string
+		want string
+		err  error
+	}{
+		{path: "db.password", want: "pw"},
+		{path: "db.port", want: "5432"},
+		{path: "db.ratio", want: "0.5"},
+		{path: "db.tls", want: "true"},
+		{path: "db.opts", want: ""},
+		{path: "db.hosts.1", want: "b"},
+		{path: "db.hosts", want: `["a","b"]`},
+		{path: "db.replica", want: `{"host":"r"}`},
+		{path: "big", want: "12345678901234567890"},
+		{path: "db.user", err: ErrFieldNotFound},
+		{path: "db.hosts.2", err: ErrFieldNotFound},
+		{path: "db.hosts.-1", err: ErrFieldNotFound},
+		{path: "db.hosts.x", err: ErrFieldNotFound},
+		{path: "db.password.length", err: ErrFieldNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := selectField(secret, tt.path)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("selectField = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+	for _, secret := range []string{"plain", `{"a": 1} {"b": 2}`, ""} {
+		if _, err := selectField(secret, "a"); !errors.Is(err, ErrFieldNotFound) {
+			t.Errorf("selectField(%q) err = %v, want ErrFieldNotFound", secret, err)
+		}
+	}
+}
+
+func TestResolveVariable(t *testing.T) {
+	s, _ := newStubStore(map[string]string{
+		"db":    `{"username": "app", "password": "pw", "port": 5432, "tls": {"ca": "pem"}}`,
+		"plain": "hunter2",
+		"list":  `["a", "b"]`,
+	})
+	tests := []struct {
+		name string
+		v    Variable
+		want map[string]string
+		err  error
+		// cause is matched against the message, as wrapping keeps only the
+		// outer sentinel.
+		cause error
+	}{
+		{
+			name: "literal",
+			v:    Variable{Name: "LOG_LEVEL", Value: ptr("debug")},
+			want: map[string]string{"LOG_LEVEL": "debug"},
+		},
+		{
+			name: "plain secret",
+			v:    Variable{Name: "PASSWORD", Ref: ptr("stub/plain")},
+			want: map[string]string{"PASSWORD": "hunter2"},
+		},
+		{
+			name: "field",
+			v:    Variable{Name: "DB_PASSWORD", Ref: ptr("stub/db#password")},
+			want: map[string]string{"DB_PASSWORD": "pw"},
+		},
+		{
+			name: "non-string field",
+			v:    Variable{Name: "DB_PORT", Ref: ptr("stub/db#port")},
+			want: map[string]string{"DB_PORT": "5432"},
+		},
+		{
+			name: "object field expands",
+			v:    Variable{Name: "tls", Ref: ptr("stub/db#tls")},
+			want: map[string]string{"TLS_CA": "pem"},
+		},
+		{
+			name:  "missing field",
+			v:     Variable{Name: "DB_HOST", Ref: ptr("stub/db#host")},
+			err:   ErrResolveVariableFailed,
+			cause: ErrFieldNotFound,
+		},
+		{
+			name:  "field of a plain secret",
+			v:     Variable{Name: "X", Ref: ptr("stub/plain#x")},
+			err:   ErrResolveVariableFailed,
+			cause: ErrFieldNotFound,
+		},
+		{
+			name: "expand",
+			v:    Variable{Name: "db", Ref: ptr("stub/db")},
+			want: map[string]string{"DB_USERNAME": "app", "DB_PASSWORD": "pw", "DB_PORT": "5432", "DB_TLS": `{"ca":"pem"}`},
+		},
+		{
+			name: "explicit expand",
+			v:    Variable{Name: "DB", Ref: ptr("stub/db"), Mode: VariableModeExpand},
+			want: map[string]string{"DB_USERNAME": "app", "DB_PASSWORD": "pw", "DB_PORT": "5432", "DB_TLS": `{"ca":"pem"}`},
+		},
+		{
+			name: "raw",
+			v:    Variable{Name: "DB_JSON", Ref: ptr("stub/db"), Mode: VariableModeRaw},
+			want: map[string]string{"DB_JSON": `{"username": "app", "password": "pw", "port": 5432, "tls": {"ca": "pem"}}`},
+		},
+		{
+			name: "arrays are not expanded",
+			v:    Variable{Name: "LIST", Ref: ptr("stub/list")},
+			want: map[string]string{"LIST": `["a", "b"]`},
+		},
+		{
+			name: "keys",
+			v:    Variable{Name: "DB", Ref: ptr("stub/db"), Keys: map[string]string{"username": "PGUSER", "port": "PGPORT", "tls.ca": "PGSSLROOTCERT"}},
+			want: map[string]string{"PGUSER": "app", "PGPORT": "5432", "PGSSLROOTCERT": "pem"},
+		},
+		{
+			name: "keys win over mode",
+			v:    Variable{Name: "DB", Ref: ptr("stub/db"), Mode: VariableModeRaw, Keys: map[string]string{"password": "PGPASSWORD"}},
+			want: map[string]string{"PGPASSWORD": "pw"},
+		},
+		{
+			name: "keys after a field",
+			v:    Variable{Name: "TLS", Ref: ptr("stub/db#tls"), Keys: map[string]string{"ca": "CA_CERT"}},
+			want: map[string]string{"CA_CERT": "pem"},
+		},
+		{
+			name:  "missing key",
+			v:     Variable{Name: "DB", Ref: ptr("stub/db"), Keys: map[string]string{"host": "PGHOST"}},
+			err:   ErrResolveVariableFailed,
+			cause: ErrFieldNotFound,
+		},
+		{
+			name: "unknown mode",
+			v:    Variable{Name: "DB", Ref: ptr("stub/db"), Mode: "flatten"},
+			err:  ErrResolveVariableFailed,
+		},
+		{
+			name: "unknown onChange",
+			v:    Variable{Name: "DB", Ref: ptr("stub/db"), OnChange: "reload"},
+			err:  ErrResolveVariableFailed,
+		},
+		{
+			name: "missing secret",
+			v:    Variable{Name: "DB", Ref: ptr("stub/nope")},
+			err:  ErrResolveVariableFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars, err := s.resolveVariable(context.Background(), tt.v)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) || tt.cause != nil && !strings.Contains(err.Error(), tt.cause.Error()) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := env(&Service{Variables: vars})
+			if len(got) != len(vars) || !maps.Equal(got, tt.want) {
+				t.Errorf("variables = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"vinr.eu/vanguard/internal/defs/v1"
 )
 
-func mapServiceV1(svc *v1.Service) *Service {
+// MapServiceV1 converts a v1 service definition. Local definitions and
+// Citadel node configs both go through it, so a service maps to the same
+// Service whichever way it arrives.
+func MapServiceV1(svc *v1.Service) *Service {
 	branch := "main"
 	if svc.Branch != nil {
 		branch = *svc.Branch
@@ -29,7 +32,9 @@ func mapServiceV1(svc *v1.Service) *Service {
 		Port:        port,
 		RunScript:   svc.RunScript,
 		IngressHost: svc.IngressHost,
+		Ingress:     mapIngressV1(svc.Ingress),
 		Variables:   mapVariablesV1(svc.Variables),
+		Files:       mapFilesV1(svc.Files),
 	}
 }
 
@@ -40,7 +45,9 @@ func mapEnvironmentV1(env *v1.Environment) *Environment {
 			Branch:      o.Branch,
 			Port:        o.Port,
 			IngressHost: o.IngressHost,
+			Ingress:     mapIngressV1(o.Ingress),
 			Variables:   mapVariablesV1(o.Variables),
+			Files:       mapFilesV1(o.Files),
 		}
 	}
 
@@ -54,11 +61,172 @@ func mapEnvironmentV1(env *v1.Environment) *Environment {
 func mapVariablesV1(vars []v1.Variable) []Variable {
 	out := make([]Variable, len(vars))
 	for i, v := range vars {
+		mode := VariableModeExpand
+		if v.Mode != nil {
+			mode = *v.Mode
+		}
+		onChange := OnChangeRestart
+		if v.OnChange != nil {
+			onChange = *v.OnChange
+		}
 		out[i] = Variable{
-			Name:  v.Name,
-			Value: v.Value,
-			Ref:   v.Ref,
+			Name:     v.Name,
+			Value:    v.Value,
+			Ref:      v.Ref,
+			Mode:     mode,
+			Keys:     v.Keys,
+			OnChange: onChange,
+		}
+	}
+	return out
+}
+
+func mapFilesV1(files []v1.File) []File {
+	if files == nil {
+		return nil
+	}
+	out := make([]File, len(files))
+	for i, f := range files {
+		out[i] = File{
+			Name:     f.Name,
+			Template: f.Template,
+			Content:  Secret{Ref: f.Ref},
+		}
+		if f.Path != nil {
+			out[i].Path = *f.Path
+		}
+		if f.Env != nil {
+			out[i].Env = *f.Env
+		}
+	}
+	return out
+}
+
+func mapIngressV1(ing *v1.Ingress) *Ingress {
+	if ing == nil {
+		return nil
+	}
+	return &Ingress{
+		Auth:        mapIngressAuthV1(ing.Auth),
+		Middlewares: mapMiddlewaresV1(ing.Middlewares),
+	}
+}
+
+func mapIngressAuthV1(auth *v1.IngressAuth) *IngressAuth {
+	if auth == nil {
+		return nil
+	}
+	out := &IngressAuth{
+		IPAllowlist: auth.IPAllowlist,
+	}
+	if auth.Basic != nil {
+		users := make([]BasicUser, len(auth.Basic.Users))
+		for i, u := range auth.Basic.Users {
+			users[i] = BasicUser{
+				Username: u.Username,
+				Password: Secret(u.Password),
+			}
+		}
+		out.Basic = &BasicAuth{
+			Realm: auth.Basic.Realm,
+			Users: users,
+		}
+	}
+	if auth.APIKey != nil {
+		keys := make([]Secret, len(auth.APIKey.Keys))
+		for i, k := range auth.APIKey.Keys {
+			keys[i] = Secret(k)
+		}
+		out.APIKey = &APIKeyAuth{
+			Header: auth.APIKey.Header,
+			Keys:   keys,
+		}
+	}
+	if auth.OIDC != nil {
+		redirectURL := ""
+		if auth.OIDC.RedirectURL != nil {
+			redirectURL = *auth.OIDC.RedirectURL
+		}
+		out.OIDC = &OIDCAuth{
+			IssuerURL:      auth.OIDC.IssuerURL,
+			ClientID:       auth.OIDC.ClientID,
+			ClientSecret:   Secret(auth.OIDC.ClientSecret),
+			RedirectURL:    redirectURL,
+			Scopes:         auth.OIDC.Scopes,
+			CookieSecret:   Secret(auth.OIDC.CookieSecret),
+			AllowedEmails:  auth.OIDC.AllowedEmails,
+			AllowedDomains: auth.OIDC.AllowedDomains,
 		}
 	}
 	return out
 }
+
+func mapMiddlewaresV1(mws []v1.Middleware) []Middleware {
+	out := make([]Middleware, len(mws))
+	for i, mw := range mws {
+		if mw.Headers != nil {
+			out[i].Headers = &HeadersMiddleware{
+				Request:  mapHeaderRulesV1(mw.Headers.Request),
+				Response: mapHeaderRulesV1(mw.Headers.Response),
+			}
+		}
+		if mw.CORS != nil {
+			maxAge := 0
+			if mw.CORS.MaxAge != nil {
+				maxAge = *mw.CORS.MaxAge
+			}
+			out[i].CORS = &CORSMiddleware{
+				AllowOrigins:     mw.CORS.AllowOrigins,
+				AllowMethods:     mw.CORS.AllowMethods,
+				AllowHeaders:     mw.CORS.AllowHeaders,
+				ExposeHeaders:    mw.CORS.ExposeHeaders,
+				AllowCredentials: mw.CORS.AllowCredentials,
+				MaxAge:           maxAge,
+			}
+		}
+		if mw.Compress != nil {
+			minSize := 1024
+			if mw.Compress.MinSize != nil {
+				minSize = *mw.Compress.MinSize
+			}
+			out[i].Compress = &CompressMiddleware{
+				Algorithms:   mw.Compress.Algorithms,
+				MinSize:      minSize,
+				ContentTypes: mw.Compress.ContentTypes,
+			}
+		}
+		if mw.RateLimit != nil {
+			burst := int(mw.RateLimit.RequestsPerSecond)
+			if mw.RateLimit.Burst != nil {
+				burst = *mw.RateLimit.Burst
+			}
+			keyHeader := ""
+			if mw.RateLimit.KeyHeader != nil {
+				keyHeader = *mw.RateLimit.KeyHeader
+			}
+			out[i].RateLimit = &RateLimitMiddleware{
+				RequestsPerSecond: mw.RateLimit.RequestsPerSecond,
+				Burst:             max(burst, 1),
+				KeyHeader:         keyHeader,
+			}
+		}
+		if mw.BodyLimit != nil {
+			out[i].BodyLimit = &BodyLimitMiddleware{MaxBytes: mw.BodyLimit.MaxBytes}
+		}
+		if mw.Timeout != nil {
+			out[i].Timeout = &TimeoutMiddleware{Duration: mw.Timeout.Duration}
+		}
+	}
+	return out
+}
+
+func mapHeaderRulesV1(rules *v1.HeaderRules) *HeaderRules {
+	if rules == nil {
+		return nil
+	}
+	return &HeaderRules{
+		Set:    rules.Set,
+		Add:    rules.Add,
+		Remove: rules.Remove,
+	}
+}
@@ -14,13 +14,46 @@ type Service struct {
 	Port        int
 	RunScript   string
 	IngressHost *string
+	Ingress     *Ingress
 	Variables   []Variable
+	Files       []File
 }
 
+// File is rendered for a service before it starts. Template is a text/template
+// over the service's variables, e.g. "{{ .DB_PASSWORD }}"; without one the
+// file holds Content.
+type File struct {
+	Name     string
+	Path     string
+	Template *string
+	Content  Secret
+	Env      string
+}
+
+const (
+	// VariableModeExpand turns a JSON object secret into one NAME_KEY variable
+	// per key; it is the default.
+	VariableModeExpand = "expand"
+	// VariableModeRaw keeps a JSON secret as the value.
+	VariableModeRaw = "raw"
+
+	// OnChangeRestart restarts a service when a secret it uses changes; it is
+	// the default. OnChangeSignal sends it SIGHUP instead and OnChangeNone
+	// leaves it running, the new value applying on its next start.
+	OnChangeRestart = "restart"
+	OnChangeSignal  = "signal"
+	OnChangeNone    = "none"
+)
+
 type Variable struct {
 	Name  string
 	Value *string
 	Ref   *string
+	Mode  string
+	// Keys maps dotted JSON paths of the secret to variable names; only the
+	// mapped fields are set.
+	Keys     map[string]string
+	OnChange string
 }
 
 type Environment struct {
@@ -33,5 +66,99 @@ type ServiceOverride struct {
 	Branch      *string
 	Port        *int
 	IngressHost *string
+	Ingress     *Ingress
 	Variables   []Variable
+	Files       []File
+}
+
+type Secret struct {
+	Value *string
+	Ref   *string
+}
+
+type Ingress struct {
+	Auth        *IngressAuth
+	Middlewares []Middleware
+}
+
+type IngressAuth struct {
+	Basic       *BasicAuth
+	APIKey      *APIKeyAuth
+	IPAllowlist []string
+	OIDC        *OIDCAuth
+}
+
+type BasicAuth struct {
+	Realm string
+	Users []BasicUser
+}
+
+type BasicUser struct {
+	Username string
+	Password Secret
+}
+
+type APIKeyAuth struct {
+	Header string
+	Keys   []Secret
+}
+
+type OIDCAuth struct {
+	IssuerURL      string
+	ClientID       string
+	ClientSecret   Secret
+	RedirectURL    string
+	Scopes         []string
+	CookieSecret   Secret
+	AllowedEmails  []string
+	AllowedDomains []string
+}
+
+type Middleware struct {
+	Headers   *HeadersMiddleware
+	CORS      *CORSMiddleware
+	Compress  *CompressMiddleware
+	RateLimit *RateLimitMiddleware
+	BodyLimit *BodyLimitMiddleware
+	Timeout   *TimeoutMiddleware
+}
+
+type HeaderRules struct {
+	Set    map[string]string
+	Add    map[string]string
+	Remove []string
+}
+
+type HeadersMiddleware struct {
+	Request  *HeaderRules
+	Response *HeaderRules
+}
+
+type CORSMiddleware struct {
+	AllowOrigins     []string
+	AllowMethods     []string
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           int
+}
+
+type CompressMiddleware struct {
+	Algorithms   []string
+	MinSize      int
+	ContentTypes []string
+}
+
+type RateLimitMiddleware struct {
+	RequestsPerSecond float64
+	Burst             int
+	KeyHeader         string
+}
+
+type BodyLimitMiddleware struct {
+	MaxBytes int64
+}
+
+type TimeoutMiddleware struct {
+	Duration string
 }
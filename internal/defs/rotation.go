@@ -0,0 +1,129 @@
+package defs
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+)
+
+// binding remembers how a variable with a ref was resolved for a service, so
+// the ref can be checked for changes later.
+type binding struct {
+	variable Variable
+	produced []Variable
+}
+
+// SecretChange is a service whose variables changed because a secret behind
+// them did, with the strongest OnChange policy of the changed variables.
+// The change is reported again on every refresh until it is committed.
+type SecretChange struct {
+	Service  *Service
+	OnChange string
+	pending  []pendingBinding
+}
+
+type pendingBinding struct {
+	binding  *binding
+	produced []Variable
+}
+
+func (s *Store) bind(service string, v Variable, produced []Variable) {
+	if v.Ref == nil {
+		if bs, ok := s.bindings[service]; ok {
+			delete(bs, v.Name)
+		}
+		return
+	}
+	if s.bindings[service] == nil {
+		s.bindings[service] = make(map[string]*binding)
+	}
+	s.bindings[service][v.Name] = &binding{variable: v, produced: produced}
+}
+
+// Refresh fetches every ref the services' variables were resolved from,
+// bypassing the secret cache, and returns updated copies of the services whose
+// variables changed. The store's services are left for the caller to replace,
+// and each change to commit once it has been applied. Only variables are
+// watched; ingress credentials are not.
+func (s *Store) Refresh(ctx context.Context) ([]SecretChange, error) {
+	refs := make(map[string]struct{})
+	for name, bs := range s.bindings {
+		if _, ok := s.Services[name]; !ok {
+			delete(s.bindings, name)
+			continue
+		}
+		for _, b := range bs {
+			ref, _ := splitRef(*b.variable.Ref)
+			refs[ref] = struct{}{}
+		}
+	}
+	var refreshErrs []error
+	failed := make(map[string]bool)
+	for _, ref := range slices.Sorted(maps.Keys(refs)) {
+		if _, err := s.secrets.Refresh(ctx, ref); err != nil {
+			refreshErrs = append(refreshErrs, err)
+			failed[ref] = true
+		}
+	}
+	var changes []SecretChange
+	for _, name := range slices.Sorted(maps.Keys(s.bindings)) {
+		svc := s.Services[name]
+		next := *svc
+		next.Variables = slices.Clone(svc.Variables)
+		var change SecretChange
+		for _, varName := range slices.Sorted(maps.Keys(s.bindings[name])) {
+			b := s.bindings[name][varName]
+			if ref, _ := splitRef(*b.variable.Ref); failed[ref] {
+				continue
+			}
+			produced, err := s.resolveVariable(ctx, b.variable)
+			if err != nil {
+				refreshErrs = append(refreshErrs, err)
+				continue
+			}
+			if slices.EqualFunc(produced, b.produced, sameVariable) {
+				continue
+			}
+			next.Variables = slices.DeleteFunc(next.Variables, func(v Variable) bool {
+				return slices.ContainsFunc(b.produced, func(old Variable) bool { return old.Name == v.Name })
+			})
+			for _, v := range produced {
+				updateOrAppendVariable(&next, v)
+			}
+			change.pending = append(change.pending, pendingBinding{binding: b, produced: produced})
+			change.OnChange = strongerPolicy(change.OnChange, b.variable.OnChange)
+		}
+		if change.OnChange != "" {
+			change.Service = &next
+			changes = append(changes, change)
+		}
+	}
+	return changes, errors.Join(refreshErrs...)
+}
+
+// Commit records the variables of an applied change as the current ones, so
+// later refreshes compare against them. Bindings replaced since the refresh,
+// e.g. by a new config, are left alone.
+func (s *Store) Commit(change SecretChange) {
+	for _, p := range change.pending {
+		if s.bindings[change.Service.Name][p.binding.variable.Name] == p.binding {
+			p.binding.produced = p.produced
+		}
+	}
+}
+
+func sameVariable(a, b Variable) bool {
+	return a.Name == b.Name && (a.Value == b.Value || (a.Value != nil && b.Value != nil && *a.Value == *b.Value))
+}
+
+func strongerPolicy(a, b string) string {
+	rank := map[string]int{"": 0, OnChangeNone: 1, OnChangeSignal: 2, OnChangeRestart: 3}
+	if b == "" {
+		b = OnChangeRestart
+	}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,177 @@
+package defs
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+
+	"vinr.eu/vanguard/internal/secrets"
+)
+
+// stubSecrets serves secrets from a map under the "stub" scheme.
+type stubSecrets struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (p *stubSecrets) Resolve(_ context.Context, name string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	value, ok := p.values[name]
+	if !ok {
+		return "", secrets.ErrNotFound
+	}
+	return value, nil
+}
+
+func (p *stubSecrets) set(name, value string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.values[name] = value
+}
+
+func newStubStore(values map[string]string) (*Store, *stubSecrets) {
+	stub := &stubSecrets{values: maps.Clone(values)}
+	return NewStore().WithSecrets(secrets.NewRegistry().Register("stub", stub)), stub
+}
+
+func ptr(s string) *string { return &s }
+
+// env renders a service's variables as a map for comparisons.
+func env(svc *Service) map[string]string {
+	out := make(map[string]string)
+	for _, v := range svc.Variables {
+		if v.Value != nil {
+			out[v.Name] = *v.Value
+		}
+	}
+	return out
+}
+
+// loadService resolves svc into a store as the environment manager does.
+func loadService(t *testing.T, s *Store, svc *Service) {
+	t.Helper()
+	s.Services[svc.Name] = svc
+	if err := s.Resolve(context.Background(), svc); err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+}
+
+func TestRefreshDetectsRotation(t *testing.T) {
+	s, stub := newStubStore(map[string]string{"db": "old", "api": "key-1"})
+	loadService(t, s, &Service{Name: "app", Variables: []Variable{
+		{Name: "DB_PASSWORD", Ref: ptr("stub/db"), OnChange: OnChangeSignal},
+		{Name: "API_KEY", Ref: ptr("stub/api"), OnChange: OnChangeNone},
+		{Name: "LOG_LEVEL", Value: ptr("debug")},
+	}})
+	ctx := context.Background()
+
+	changes, err := s.Refresh(ctx)
+	if err != nil || len(changes) != 0 {
+		t.Fatalf("Refresh without rotation = %v, %v", changes, err)
+	}
+
+	stub.set("db", "new")
+	changes, err = s.Refresh(ctx)
+	if err != nil || len(changes) != 1 {
+		t.Fatalf("Refresh = %v, %v, want one change", changes, err)
+	}
+	change := changes[0]
+	if change.OnChange != OnChangeSignal {
+		t.Errorf("OnChange = %q, want %q", change.OnChange, OnChangeSignal)
+	}
+	want := map[string]string{"DB_PASSWORD": "new", "API_KEY": "key-1", "LOG_LEVEL": "debug"}
+	if got := env(change.Service); !maps.Equal(got, want) {
+		t.Errorf("variables = %v, want %v", got, want)
+	}
+	if got := env(s.Services["app"]); got["DB_PASSWORD"] != "old" {
+		t.Errorf("store service changed before the caller replaced it: %v", got)
+	}
+
+	// Until the change is committed, e.g. because the restart failed, it is
+	// reported again.
+	s.Services["app"] = change.Service
+	changes, err = s.Refresh(ctx)
+	if err != nil || len(changes) != 1 || env(changes[0].Service)["DB_PASSWORD"] != "new" {
+		t.Fatalf("Refresh after failed apply = %v, %v, want the change again", changes, err)
+	}
+	s.Commit(changes[0])
+	if changes, err = s.Refresh(ctx); err != nil || len(changes) != 0 {
+		t.Fatalf("Refresh after commit = %v, %v, want no change", changes, err)
+	}
+
+	// A failed fetch keeps the last value and reports the error.
+	stub.mu.Lock()
+	delete(stub.values, "api")
+	stub.mu.Unlock()
+	changes, err = s.Refresh(ctx)
+	if err == nil || len(changes) != 0 {
+		t.Fatalf("Refresh with missing secret = %v, %v", changes, err)
+	}
+}
+
+func TestRefreshStrongestPolicy(t *testing.T) {
+	s, stub := newStubStore(map[string]string{"a": "1", "b": "1"})
+	loadService(t, s, &Service{Name: "app", Variables: []Variable{
+		{Name: "A", Ref: ptr("stub/a"), OnChange: OnChangeSignal},
+		{Name: "B", Ref: ptr("stub/b")},
+	}})
+	stub.set("a", "2")
+	stub.set("b", "2")
+	changes, err := s.Refresh(context.Background())
+	if err != nil || len(changes) != 1 {
+		t.Fatalf("Refresh = %v, %v", changes, err)
+	}
+	if changes[0].OnChange != OnChangeRestart {
+		t.Errorf("OnChange = %q, want the default restart to win", changes[0].OnChange)
+	}
+}
+
+func TestStrongerPolicy(t *testing.T) {
+	tests := []struct {
+		a, b, want string
+	}{
+		{"", OnChangeNone, OnChangeNone},
+		{"", "", OnChangeRestart},
+		{OnChangeNone, OnChangeSignal, OnChangeSignal},
+		{OnChangeSignal, OnChangeNone, OnChangeSignal},
+		{OnChangeSignal, OnChangeRestart, OnChangeRestart},
+		{OnChangeRestart, OnChangeSignal, OnChangeRestart},
+		{OnChangeNone, "", OnChangeRestart},
+	}
+	for _, tt := range tests {
+		if got := strongerPolicy(tt.a, tt.b); got != tt.want {
+			t.Errorf("strongerPolicy(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestRefreshReplacesKeysMapping(t *testing.T) {
+	s, stub := newStubStore(map[string]string{
+		"db": `{"username": "app", "password": "old", "host": "db-1"}`,
+	})
+	loadService(t, s, &Service{Name: "app", Variables: []Variable{
+		{Name: "DB", Ref: ptr("stub/db"), Keys: map[string]string{"username": "DB_USER", "password": "DB_PASSWORD"}},
+		{Name: "PORT", Value: ptr("3000")},
+	}})
+	stub.set("db", `{"username": "app", "password": "new", "host": "db-2"}`)
+	changes, err := s.Refresh(context.Background())
+	if err != nil || len(changes) != 1 {
+		t.Fatalf("Refresh = %v, %v", changes, err)
+	}
+	svc := changes[0].Service
+	want := map[string]string{"DB_USER": "app", "DB_PASSWORD": "new", "PORT": "3000"}
+	if got := env(svc); !maps.Equal(got, want) {
+		t.Errorf("variables = %v, want %v", got, want)
+	}
+	var names []string
+	for _, v := range svc.Variables {
+		names = append(names, v.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"DB_PASSWORD", "DB_USER", "PORT"}) {
+		t.Errorf("variable names = %v, want each mapped variable once", names)
+	}
+}
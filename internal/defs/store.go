@@ -4,52 +4,78 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io/fs"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/goccy/go-yaml"
-	"vinr.eu/vanguard/internal/aws"
 	"vinr.eu/vanguard/internal/defs/v1"
 	"vinr.eu/vanguard/internal/errs"
+	"vinr.eu/vanguard/internal/redact"
+	"vinr.eu/vanguard/internal/secrets"
+	"vinr.eu/vanguard/internal/sops"
 )
 
 var (
 	ErrLoadFailed            = errors.New("defs: load failed")
 	ErrReadFailed            = errors.New("defs: read failed")
 	ErrDecodeFailed          = errors.New("defs: decode failed")
+	ErrDecryptFailed         = errors.New("defs: decrypt failed")
 	ErrNoEnvironment         = errors.New("defs: missing environment")
 	ErrDupEnvironment        = errors.New("defs: duplicate environment")
 	ErrImportFailed          = errors.New("defs: import failed")
 	ErrResolveVariableFailed = errors.New("defs: resolve variable failed")
+	ErrResolveSecretFailed   = errors.New("defs: resolve secret failed")
+	ErrFieldNotFound         = errors.New("defs: secret field not found")
 )
 
-const AwsSecretPrefix = "aws/secrets/"
-
 type Store struct {
-	Environment          *Environment
-	Services             map[string]*Service
-	fetchSecret          func(ctx context.Context, secretID string) (string, error)
-	secretsManagerClient *aws.SecretsManagerClient
+	Environment *Environment
+	Services    map[string]*Service
+	secrets     *secrets.Registry
+	decrypter   *sops.Decrypter
+	redactor    *redact.Redactor
+	bindings    map[string]map[string]*binding
 }
 
 func NewStore() *Store {
-	s := &Store{
-		Services: make(map[string]*Service),
+	return &Store{
+		Services:  make(map[string]*Service),
+		secrets:   secrets.NewRegistry(),
+		decrypter: sops.NewDecrypter(),
+		redactor:  redact.New(),
+		bindings:  make(map[string]map[string]*binding),
 	}
-	s.fetchSecret = s.fetchAwsSecret
+}
+
+func (s *Store) WithSecrets(registry *secrets.Registry) *Store {
+	s.secrets = registry
+	return s
+}
+
+// WithRedactor makes every secret value the store resolves or decrypts known
+// to r.
+func (s *Store) WithRedactor(r *redact.Redactor) *Store {
+	s.redactor = r
 	return s
 }
 
-func (s *Store) WithSecretsManager(client *aws.SecretsManagerClient) *Store {
-	s.secretsManagerClient = client
+// WithDecrypter sets the age identities that open SOPS documents, inline age
+// values and encrypted sidecar files in the definitions.
+func (s *Store) WithDecrypter(d *sops.Decrypter) *Store {
+	s.decrypter = d
 	return s
 }
 
 func (s *Store) Load(ctx context.Context, rootPath string) error {
+	// Sidecar refs such as "sops/secrets/dev.enc.yaml" are relative to the
+	// definitions root, which is only known here.
+	sidecars := s.decrypter.Files(rootPath)
+	s.secrets.Register("sops", sidecars).Register("age", sidecars)
 	err := filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
@@ -73,13 +99,24 @@ func (s *Store) loadFile(path string) error {
 	if err != nil {
 		return errs.WrapMsgErr(ErrReadFailed, path, err)
 	}
+	if sops.IsDocument(data) && !hasKind(data) {
+		// An encrypted sidecar for sops/ refs rather than a definition.
+		return nil
+	}
+	data, values, err := s.decrypter.Document(data)
+	if err != nil {
+		return errs.WrapMsgErr(ErrDecryptFailed, path, err)
+	}
+	for _, v := range values {
+		s.remember(v)
+	}
 	obj, err := decode(data)
 	if err != nil {
 		return errs.WrapMsgErr(ErrDecodeFailed, path, err)
 	}
 	switch o := obj.(type) {
 	case *v1.Service:
-		s.Services[o.Name] = mapServiceV1(o)
+		s.Services[o.Name] = MapServiceV1(o)
 	case *v1.Environment:
 		if s.Environment != nil {
 			return errs.WrapMsg(ErrDupEnvironment, path)
@@ -114,6 +151,14 @@ func (s *Store) processEnvironment(ctx context.Context, rootPath string) error {
 			return err
 		}
 	}
+	for _, svc := range s.Services {
+		if err := s.resolveIngressSecrets(ctx, svc); err != nil {
+			return err
+		}
+		if err := s.resolveFileSecrets(ctx, svc); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -142,11 +187,18 @@ func (s *Store) applyOverride(ctx context.Context, svc *Service, override Servic
 	if override.IngressHost != nil {
 		svc.IngressHost = override.IngressHost
 	}
+	if override.Ingress != nil {
+		svc.Ingress = override.Ingress
+	}
+	for _, f := range override.Files {
+		updateOrAppendFile(svc, f)
+	}
 	for _, v := range override.Variables {
 		expandedVars, err := s.resolveVariable(ctx, v)
 		if err != nil {
 			return err
 		}
+		s.bind(svc.Name, v, expandedVars)
 		for _, ev := range expandedVars {
 			updateOrAppendVariable(svc, ev)
 		}
@@ -154,45 +206,167 @@ func (s *Store) applyOverride(ctx context.Context, svc *Service, override Servic
 	return nil
 }
 
+// Resolve fills in the secret refs of a service that did not come from the
+// store's own definitions, such as one delivered by Citadel.
+func (s *Store) Resolve(ctx context.Context, svc *Service) error {
+	if err := s.resolveServiceSecrets(ctx, svc); err != nil {
+		return err
+	}
+	if err := s.resolveIngressSecrets(ctx, svc); err != nil {
+		return err
+	}
+	return s.resolveFileSecrets(ctx, svc)
+}
+
 func (s *Store) resolveServiceSecrets(ctx context.Context, svc *Service) error {
+	delete(s.bindings, svc.Name)
 	var finalVars []Variable
 	for _, v := range svc.Variables {
 		expanded, err := s.resolveVariable(ctx, v)
 		if err != nil {
 			return err
 		}
+		s.bind(svc.Name, v, expanded)
 		finalVars = append(finalVars, expanded...)
 	}
 	svc.Variables = finalVars
 	return nil
 }
 
+func (s *Store) resolveIngressSecrets(ctx context.Context, svc *Service) error {
+	if svc.Ingress == nil || svc.Ingress.Auth == nil {
+		return nil
+	}
+	auth := svc.Ingress.Auth
+	var secrets []*Secret
+	if auth.Basic != nil {
+		for i := range auth.Basic.Users {
+			secrets = append(secrets, &auth.Basic.Users[i].Password)
+		}
+	}
+	if auth.APIKey != nil {
+		for i := range auth.APIKey.Keys {
+			secrets = append(secrets, &auth.APIKey.Keys[i])
+		}
+	}
+	if auth.OIDC != nil {
+		secrets = append(secrets, &auth.OIDC.ClientSecret, &auth.OIDC.CookieSecret)
+	}
+	for _, secret := range secrets {
+		if err := s.resolveSecret(ctx, secret); err != nil {
+			return errs.WrapMsgErr(ErrResolveSecretFailed, "ingress auth: "+svc.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) resolveFileSecrets(ctx context.Context, svc *Service) error {
+	for i := range svc.Files {
+		f := &svc.Files[i]
+		if f.Template != nil {
+			continue
+		}
+		if err := s.resolveSecret(ctx, &f.Content); err != nil {
+			return errs.WrapMsgErr(ErrResolveSecretFailed, "file "+f.Name+": "+svc.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) resolveSecret(ctx context.Context, secret *Secret) error {
+	if secret.Value != nil {
+		value, err := s.decrypt(*secret.Value)
+		if err != nil {
+			return err
+		}
+		// Ingress credentials are secret even when written inline.
+		s.remember(value)
+		secret.Value = &value
+		return nil
+	}
+	if secret.Ref == nil {
+		return nil
+	}
+	ref, field := splitRef(*secret.Ref)
+	value, err := s.secrets.Resolve(ctx, ref)
+	if err != nil {
+		return err
+	}
+	s.remember(value)
+	if field != "" {
+		if value, err = selectField(value, field); err != nil {
+			return err
+		}
+		s.remember(value)
+	}
+	secret.Value = &value
+	return nil
+}
+
+// resolveVariable turns a ref into variables. A "#<path>" selector narrows
+// the secret to one of its fields first; Keys or Mode then decide how a JSON
+// object becomes variables.
 func (s *Store) resolveVariable(ctx context.Context, v Variable) ([]Variable, error) {
-	if v.Value != nil || v.Ref == nil {
-		return []Variable{v}, nil
+	if v.Value != nil {
+		value, err := s.decrypt(*v.Value)
+		if err != nil {
+			return nil, errs.WrapMsgErr(ErrResolveVariableFailed, v.Name, err)
+		}
+		return []Variable{{Name: v.Name, Value: &value}}, nil
 	}
-	if !strings.HasPrefix(*v.Ref, AwsSecretPrefix) {
+	if v.Ref == nil {
 		return []Variable{v}, nil
 	}
-	secretID := strings.TrimPrefix(*v.Ref, AwsSecretPrefix)
-	secretValue, err := s.fetchSecret(ctx, secretID)
+	switch v.OnChange {
+	case "", OnChangeRestart, OnChangeSignal, OnChangeNone:
+	default:
+		return nil, errs.WrapMsg(ErrResolveVariableFailed, v.Name+": unknown onChange "+v.OnChange)
+	}
+	ref, field := splitRef(*v.Ref)
+	secretValue, err := s.secrets.Resolve(ctx, ref)
 	if err != nil {
 		return nil, errs.WrapMsgErr(ErrResolveVariableFailed, v.Name, err)
 	}
-	var entries map[string]any
-	if err := json.Unmarshal([]byte(secretValue), &entries); err == nil {
-		var expanded []Variable
-		prefix := strings.ToUpper(v.Name)
-		for key, value := range entries {
-			suffix := strings.ToUpper(key)
-			newName := fmt.Sprintf("%s_%s", prefix, suffix)
-			val := fmt.Sprintf("%v", value)
-			expanded = append(expanded, Variable{
-				Name:  newName,
-				Value: &val,
-			})
+	s.remember(secretValue)
+	if field != "" {
+		if secretValue, err = selectField(secretValue, field); err != nil {
+			return nil, errs.WrapMsgErr(ErrResolveVariableFailed, v.Name, err)
+		}
+		s.remember(secretValue)
+	}
+	if len(v.Keys) > 0 {
+		var mapped []Variable
+		for _, path := range slices.Sorted(maps.Keys(v.Keys)) {
+			value, err := selectField(secretValue, path)
+			if err != nil {
+				return nil, errs.WrapMsgErr(ErrResolveVariableFailed, v.Name, err)
+			}
+			s.remember(value)
+			mapped = append(mapped, Variable{Name: v.Keys[path], Value: &value})
+		}
+		return mapped, nil
+	}
+	switch v.Mode {
+	case VariableModeExpand, "":
+	case VariableModeRaw:
+		return []Variable{{Name: v.Name, Value: &secretValue}}, nil
+	default:
+		return nil, errs.WrapMsg(ErrResolveVariableFailed, v.Name+": unknown mode "+v.Mode)
+	}
+	if entries, ok := parseJSON(secretValue); ok {
+		if obj, ok := entries.(map[string]any); ok {
+			var expanded []Variable
+			prefix := strings.ToUpper(v.Name)
+			for _, key := range slices.Sorted(maps.Keys(obj)) {
+				val := fieldValue(obj[key])
+				s.remember(val)
+				expanded = append(expanded, Variable{
+					Name:  prefix + "_" + strings.ToUpper(key),
+					Value: &val,
+				})
+			}
+			return expanded, nil
 		}
-		return expanded, nil
 	}
 	return []Variable{{
 		Name:  v.Name,
@@ -200,11 +374,21 @@ func (s *Store) resolveVariable(ctx context.Context, v Variable) ([]Variable, er
 	}}, nil
 }
 
-func (s *Store) fetchAwsSecret(ctx context.Context, secretID string) (string, error) {
-	if s.secretsManagerClient == nil {
-		return "", fmt.Errorf("aws secrets manager client not initialized")
+// decrypt opens an inline age value; plain values are returned as is.
+func (s *Store) decrypt(value string) (string, error) {
+	if !sops.IsEncrypted(value) {
+		return value, nil
+	}
+	plain, err := s.decrypter.Value(value)
+	if err != nil {
+		return "", errs.Wrap(ErrDecryptFailed, err)
 	}
-	return s.secretsManagerClient.GetSecret(ctx, secretID)
+	s.remember(plain)
+	return plain, nil
+}
+
+func (s *Store) remember(value string) {
+	s.redactor.Add(value)
 }
 
 func decode(data []byte) (any, error) {
@@ -227,6 +411,13 @@ func decode(data []byte) (any, error) {
 	}
 }
 
+func hasKind(data []byte) bool {
+	var meta struct {
+		Kind string `yaml:"kind"`
+	}
+	return yaml.Unmarshal(data, &meta) == nil && meta.Kind != ""
+}
+
 func decodeV1(kind string, data []byte) (any, error) {
 	switch kind {
 	case "Service":
@@ -255,3 +446,13 @@ func updateOrAppendVariable(svc *Service, newVal Variable) {
 	}
 	svc.Variables = append(svc.Variables, newVal)
 }
+
+func updateOrAppendFile(svc *Service, f File) {
+	for i, existing := range svc.Files {
+		if existing.Name == f.Name {
+			svc.Files[i] = f
+			return
+		}
+	}
+	svc.Files = append(svc.Files, f)
+}
@@ -25,13 +25,30 @@ type Service struct {
 	Port        *int        `json:"port,omitempty"`
 	RunScript   string      `json:"runScript"`
 	IngressHost *string     `json:"ingressHost,omitempty"`
+	Ingress     *Ingress    `json:"ingress,omitempty"`
 	Variables   []Variable  `json:"variables,omitempty"`
+	Files       []File      `json:"files,omitempty"`
+}
+
+// File is rendered for a service before it starts, from either a template or
+// a secret ref. Path places it in the checkout; without one it goes to a
+// private directory. Env names the variable that receives its location.
+type File struct {
+	Name     string  `json:"name"`
+	Path     *string `json:"path,omitempty"`
+	Template *string `json:"template,omitempty"`
+	Ref      *string `json:"ref,omitempty"`
+	Env      *string `json:"env,omitempty"`
 }
 
 type Variable struct {
 	Name  string  `json:"name"`
 	Value *string `json:"value,omitempty"`
-	Ref   *string `json:"ref,omitempty"`
+	// Ref may end in "#<path>" to select a field of a JSON secret.
+	Ref      *string           `json:"ref,omitempty"`
+	Mode     *string           `json:"mode,omitempty"`
+	Keys     map[string]string `json:"keys,omitempty"`
+	OnChange *string           `json:"onChange,omitempty"`
 }
 
 type Environment struct {
@@ -46,5 +63,99 @@ type ServiceOverride struct {
 	Branch      *string    `json:"branch,omitempty"`
 	Port        *int       `json:"port,omitempty"`
 	IngressHost *string    `json:"ingressHost,omitempty"`
+	Ingress     *Ingress   `json:"ingress,omitempty"`
 	Variables   []Variable `json:"variables,omitempty"`
+	Files       []File     `json:"files,omitempty"`
+}
+
+type SecretValue struct {
+	Value *string `json:"value,omitempty"`
+	Ref   *string `json:"ref,omitempty"`
+}
+
+type Ingress struct {
+	Auth        *IngressAuth `json:"auth,omitempty"`
+	Middlewares []Middleware `json:"middlewares,omitempty"`
+}
+
+type IngressAuth struct {
+	Basic       *BasicAuth  `json:"basic,omitempty"`
+	APIKey      *APIKeyAuth `json:"apiKey,omitempty"`
+	IPAllowlist []string    `json:"ipAllowlist,omitempty"`
+	OIDC        *OIDCAuth   `json:"oidc,omitempty"`
+}
+
+type BasicAuth struct {
+	Realm string      `json:"realm,omitempty"`
+	Users []BasicUser `json:"users"`
+}
+
+type BasicUser struct {
+	Username string      `json:"username"`
+	Password SecretValue `json:"password"`
+}
+
+type APIKeyAuth struct {
+	Header string        `json:"header,omitempty"`
+	Keys   []SecretValue `json:"keys"`
+}
+
+type OIDCAuth struct {
+	IssuerURL      string      `json:"issuerURL"`
+	ClientID       string      `json:"clientID"`
+	ClientSecret   SecretValue `json:"clientSecret"`
+	RedirectURL    *string     `json:"redirectURL,omitempty"`
+	Scopes         []string    `json:"scopes,omitempty"`
+	CookieSecret   SecretValue `json:"cookieSecret"`
+	AllowedEmails  []string    `json:"allowedEmails,omitempty"`
+	AllowedDomains []string    `json:"allowedDomains,omitempty"`
+}
+
+type Middleware struct {
+	Headers   *HeadersMiddleware   `json:"headers,omitempty"`
+	CORS      *CORSMiddleware      `json:"cors,omitempty"`
+	Compress  *CompressMiddleware  `json:"compress,omitempty"`
+	RateLimit *RateLimitMiddleware `json:"rateLimit,omitempty"`
+	BodyLimit *BodyLimitMiddleware `json:"bodyLimit,omitempty"`
+	Timeout   *TimeoutMiddleware   `json:"timeout,omitempty"`
+}
+
+type HeaderRules struct {
+	Set    map[string]string `json:"set,omitempty"`
+	Add    map[string]string `json:"add,omitempty"`
+	Remove []string          `json:"remove,omitempty"`
+}
+
+type HeadersMiddleware struct {
+	Request  *HeaderRules `json:"request,omitempty"`
+	Response *HeaderRules `json:"response,omitempty"`
+}
+
+type CORSMiddleware struct {
+	AllowOrigins     []string `json:"allowOrigins"`
+	AllowMethods     []string `json:"allowMethods,omitempty"`
+	AllowHeaders     []string `json:"allowHeaders,omitempty"`
+	ExposeHeaders    []string `json:"exposeHeaders,omitempty"`
+	AllowCredentials bool     `json:"allowCredentials,omitempty"`
+	MaxAge           *int     `json:"maxAge,omitempty"`
+}
+
+type CompressMiddleware struct {
+	Algorithms   []string `json:"algorithms,omitempty"`
+	MinSize      *int     `json:"minSize,omitempty"`
+	ContentTypes []string `json:"contentTypes,omitempty"`
+}
+
+type RateLimitMiddleware struct {
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	Burst             *int    `json:"burst,omitempty"`
+	KeyHeader         *string `json:"keyHeader,omitempty"`
+}
+
+type BodyLimitMiddleware struct {
+	MaxBytes int64 `json:"maxBytes"`
+}
+
+type TimeoutMiddleware struct {
+	Duration string `json:"duration"`
 }
@@ -3,10 +3,12 @@ package deployment
 import (
 	"context"
 	"errors"
+	"os"
 	"strings"
 
 	"vinr.eu/vanguard/internal/defs"
 	"vinr.eu/vanguard/internal/errs"
+	"vinr.eu/vanguard/internal/redact"
 )
 
 var (
@@ -18,21 +20,35 @@ type Deployment interface {
 	Install(ctx context.Context) error
 	Start(ctx context.Context) error
 	Stop() error
+	// Update replaces the definition used by later starts and re-renders the
+	// files of a running service.
+	Update(svc *defs.Service) error
+	Signal(sig os.Signal) error
+	Status() Status
+	Logs(n int) []LogLine
 }
 
-func New(svc *defs.Service, repoPath, binDir string) (Deployment, error) {
+func New(svc *defs.Service, repoPath, binDir string, sink LogSink, redactor *redact.Redactor) (Deployment, error) {
 	if svc == nil {
 		return nil, errs.WrapMsg(ErrInvalidConfig, "service definition is nil")
 	}
+	for _, v := range svc.Variables {
+		if v.Value == nil {
+			return nil, errs.WrapMsg(ErrInvalidConfig, "variable "+v.Name+" has no value")
+		}
+	}
+	if err := validateFiles(svc); err != nil {
+		return nil, err
+	}
 	engine := strings.ToLower(svc.Runtime.Engine)
 	if engine == "" {
 		engine = "node"
 	}
 	switch engine {
 	case "node":
-		return NewNodeDeployment(svc, repoPath, binDir), nil
+		return NewNodeDeployment(svc, repoPath, binDir, sink, redactor), nil
 	case "openjdk":
-		return NewOpenJDKDeployment(svc, repoPath, binDir), nil
+		return NewOpenJDKDeployment(svc, repoPath, binDir, sink, redactor), nil
 	default:
 		return nil, errs.WrapMsg(ErrUnsupportedEngine, engine)
 	}
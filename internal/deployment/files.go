@@ -0,0 +1,182 @@
+package deployment
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"vinr.eu/vanguard/internal/defs"
+	"vinr.eu/vanguard/internal/errs"
+)
+
+var ErrRenderFailed = errors.New("deployment: rendering files failed")
+
+// files renders the files section of a service: into the checkout for files
+// with a path, otherwise into a private directory created on first use.
+// Every file is written with mode 0600 and replaced atomically, so a service
+// re-reading it after SIGHUP never sees a partial file.
+type files struct {
+	dir     string
+	written []string
+}
+
+// validateFiles checks what can be checked before resolution: names, paths
+// and that every file has something to render.
+func validateFiles(svc *defs.Service) error {
+	seen := make(map[string]bool, len(svc.Files))
+	for _, f := range svc.Files {
+		if f.Name == "" || f.Name != filepath.Base(f.Name) || f.Name == "." || f.Name == ".." {
+			return errs.WrapMsg(ErrInvalidConfig, "file name must be a plain file name: "+f.Name)
+		}
+		if seen[f.Name] {
+			return errs.WrapMsg(ErrInvalidConfig, "duplicate file "+f.Name)
+		}
+		seen[f.Name] = true
+		if f.Path != "" && !filepath.IsLocal(f.Path) {
+			return errs.WrapMsg(ErrInvalidConfig, "file "+f.Name+" path must stay inside the checkout: "+f.Path)
+		}
+		if f.Template == nil && f.Content.Value == nil {
+			return errs.WrapMsg(ErrInvalidConfig, "file "+f.Name+" has no content")
+		}
+	}
+	return nil
+}
+
+// render writes the files of svc and returns the variables that point to
+// them. Files no longer defined are removed.
+func (f *files) render(svc *defs.Service, execPath string) ([]string, error) {
+	vars := make(map[string]string, len(svc.Variables))
+	for _, v := range svc.Variables {
+		vars[v.Name] = *v.Value
+	}
+	var env, written []string
+	for _, file := range svc.Files {
+		content, err := renderContent(file, vars)
+		if err != nil {
+			return nil, errs.WrapMsgErr(ErrRenderFailed, file.Name, err)
+		}
+		var path string
+		if file.Path != "" {
+			path, err = writeInRoot(execPath, file.Path, content)
+		} else {
+			path, err = f.writePrivate(svc.Name, file.Name, content)
+		}
+		if err != nil {
+			return nil, errs.WrapMsgErr(ErrRenderFailed, file.Name, err)
+		}
+		written = append(written, path)
+		env = append(env, fileEnvName(file)+"="+path)
+	}
+	for _, old := range f.written {
+		if !slices.Contains(written, old) {
+			os.Remove(old)
+		}
+	}
+	f.written = written
+	return env, nil
+}
+
+func renderContent(file defs.File, vars map[string]string) ([]byte, error) {
+	if file.Template == nil {
+		return []byte(*file.Content.Value), nil
+	}
+	tmpl, err := template.New(file.Name).Option("missingkey=error").Parse(*file.Template)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeInRoot writes below execPath without following paths or symlinks out
+// of it.
+func writeInRoot(execPath, rel string, content []byte) (string, error) {
+	root, err := os.OpenRoot(execPath)
+	if err != nil {
+		return "", err
+	}
+	defer root.Close()
+	rel = filepath.Clean(rel)
+	if dir := filepath.Dir(rel); dir != "." {
+		if err := root.MkdirAll(dir, 0o700); err != nil {
+			return "", err
+		}
+	}
+	tmp := rel + ".tmp"
+	// WriteFile keeps the mode of a file it truncates, so a leftover from an
+	// interrupted write must not be reused.
+	if err := root.Remove(tmp); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	if err := root.WriteFile(tmp, content, 0o600); err != nil {
+		return "", err
+	}
+	if err := root.Rename(tmp, rel); err != nil {
+		root.Remove(tmp)
+		return "", err
+	}
+	return filepath.Join(execPath, rel), nil
+}
+
+func (f *files) writePrivate(service, name string, content []byte) (string, error) {
+	if f.dir == "" {
+		dir, err := os.MkdirTemp("", "vanguard-"+service+"-")
+		if err != nil {
+			return "", err
+		}
+		f.dir = dir
+	}
+	path := filepath.Join(f.dir, name)
+	tmp, err := os.CreateTemp(f.dir, "."+name+"-")
+	if err != nil {
+		return "", err
+	}
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return path, nil
+}
+
+// cleanup removes every rendered file and the private directory.
+func (f *files) cleanup() {
+	for _, path := range f.written {
+		os.Remove(path)
+	}
+	f.written = nil
+	if f.dir != "" {
+		os.RemoveAll(f.dir)
+		f.dir = ""
+	}
+}
+
+// fileEnvName is the file's Env or one derived from its name, e.g.
+// "gcp-credentials.json" becomes VANGUARD_FILE_GCP_CREDENTIALS_JSON.
+func fileEnvName(file defs.File) string {
+	if file.Env != "" {
+		return file.Env
+	}
+	name := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, file.Name)
+	return "VANGUARD_FILE_" + name
+}
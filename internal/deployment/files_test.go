@@ -0,0 +1,188 @@
+package deployment
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"vinr.eu/vanguard/internal/defs"
+)
+
+func ptr(s string) *string { return &s }
+
+func fileService(files ...defs.File) *defs.Service {
+	return &defs.Service{
+		Name:      "api",
+		Runtime:   defs.RuntimeSpec{Engine: "node"},
+		RunScript: "sleep 30",
+		Variables: []defs.Variable{{Name: "DB_PASSWORD", Value: ptr("hunter2")}},
+		Files:     files,
+	}
+}
+
+func envPath(t *testing.T, env []string, name string) string {
+	t.Helper()
+	for _, kv := range env {
+		if v, ok := strings.CutPrefix(kv, name+"="); ok {
+			return v
+		}
+	}
+	t.Fatalf("%s not in %q", name, env)
+	return ""
+}
+
+func assertFile(t *testing.T, path, content string) {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("%s mode = %v, want 0600", path, mode)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Errorf("%s = %q, want %q", path, data, content)
+	}
+}
+
+func TestFilesWrittenPrivately(t *testing.T) {
+	checkout := t.TempDir()
+	svc := fileService(
+		defs.File{Name: "db.conf", Template: ptr("password={{.DB_PASSWORD}}")},
+		defs.File{Name: "app.json", Path: "config/app.json", Content: defs.Secret{Value: ptr(`{"debug":true}`)}, Env: "APP_CONFIG"},
+	)
+	// Leftovers a service or an interrupted write made world-readable.
+	if err := os.MkdirAll(filepath.Join(checkout, "config"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"app.json", "app.json.tmp"} {
+		if err := os.WriteFile(filepath.Join(checkout, "config", name), []byte("old"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var f files
+	defer f.cleanup()
+	env, err := f.render(svc, checkout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	private := envPath(t, env, "VANGUARD_FILE_DB_CONF")
+	assertFile(t, private, "password=hunter2")
+	if info, err := os.Stat(filepath.Dir(private)); err != nil || info.Mode().Perm() != 0o700 {
+		t.Errorf("private directory: %v, %v, want mode 0700", info, err)
+	}
+	inCheckout := envPath(t, env, "APP_CONFIG")
+	if inCheckout != filepath.Join(checkout, "config", "app.json") {
+		t.Errorf("APP_CONFIG = %q", inCheckout)
+	}
+	assertFile(t, inCheckout, `{"debug":true}`)
+
+	// A re-render replaces the files in place and removes dropped ones.
+	svc.Variables[0].Value = ptr("rotated")
+	svc.Files = svc.Files[:1]
+	if _, err := f.render(svc, checkout); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, private, "password=rotated")
+	if _, err := os.Stat(inCheckout); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("dropped file kept: %v", err)
+	}
+}
+
+func TestFilesStayInCheckout(t *testing.T) {
+	content := defs.Secret{Value: ptr("x")}
+	tests := []struct {
+		name string
+		file defs.File
+	}{
+		{"parent path", defs.File{Name: "a", Path: "../a", Content: content}},
+		{"nested parent path", defs.File{Name: "a", Path: "config/../../a", Content: content}},
+		{"absolute path", defs.File{Name: "a", Path: "/etc/a", Content: content}},
+		{"name with a directory", defs.File{Name: "config/a", Content: content}},
+		{"dot dot name", defs.File{Name: "..", Content: content}},
+		{"no content", defs.File{Name: "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(fileService(tt.file), t.TempDir(), "", nil, nil); !errors.Is(err, ErrInvalidConfig) {
+				t.Fatalf("err = %v, want ErrInvalidConfig", err)
+			}
+		})
+	}
+	if _, err := New(fileService(defs.File{Name: "a", Content: content}, defs.File{Name: "a", Path: "b", Content: content}), t.TempDir(), "", nil, nil); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("duplicate names: err = %v, want ErrInvalidConfig", err)
+	}
+
+	// A symlink in the checkout cannot lead a file out of it.
+	checkout, outside := t.TempDir(), t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(checkout, "config")); err != nil {
+		t.Fatal(err)
+	}
+	var f files
+	defer f.cleanup()
+	_, err := f.render(fileService(defs.File{Name: "a", Path: "config/a", Content: content}), checkout)
+	if !errors.Is(err, ErrRenderFailed) {
+		t.Fatalf("err = %v, want ErrRenderFailed", err)
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+		t.Errorf("wrote %v outside the checkout", entries)
+	}
+}
+
+func TestFilesRemovedOnStop(t *testing.T) {
+	for _, engine := range []string{"node", "openjdk"} {
+		t.Run(engine, func(t *testing.T) {
+			checkout := t.TempDir()
+			svc := fileService(
+				defs.File{Name: "db.conf", Template: ptr("password={{.DB_PASSWORD}}")},
+				defs.File{Name: "app.json", Path: "config/app.json", Content: defs.Secret{Value: ptr("{}")}},
+			)
+			svc.Runtime.Engine = engine
+			d, err := New(svc, checkout, "", nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := d.Start(t.Context()); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = d.Stop() })
+			f := renderedFiles(d)
+			rendered := append(slices.Clone(f.written), f.dir)
+			if len(rendered) != 3 {
+				t.Fatalf("rendered %q, want two files and the private directory", rendered)
+			}
+			if err := d.Stop(); err != nil {
+				t.Fatal(err)
+			}
+			for _, path := range rendered {
+				if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("%s left after Stop: %v", path, err)
+				}
+			}
+			if st := d.Status(); st.State != StateStopped {
+				t.Errorf("state = %q, want stopped", st.State)
+			}
+			if _, err := os.Stat(filepath.Join(checkout, "config")); err != nil {
+				t.Errorf("checkout directory removed: %v", err)
+			}
+		})
+	}
+}
+
+func renderedFiles(d Deployment) *files {
+	switch d := d.(type) {
+	case *NodeDeployment:
+		return &d.files
+	case *OpenJDKDeployment:
+		return &d.files
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package deployment
+
+import (
+	"sync"
+	"time"
+
+	"vinr.eu/vanguard/internal/redact"
+)
+
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+
+	logBufferLines = 1000
+)
+
+type LogLine struct {
+	Time   time.Time
+	Stream string
+	Text   string
+}
+
+// LogSink receives every output line of every service; it must not block.
+type LogSink func(service string, line LogLine)
+
+// logBuffer keeps the most recent output lines of a service and forwards
+// each of them to the sink, if any. Secrets are masked before a line is kept
+// or forwarded.
+type logBuffer struct {
+	service  string
+	sink     LogSink
+	redactor *redact.Redactor
+	mu       sync.Mutex
+	lines    []LogLine
+	next     int
+}
+
+// add returns the line as kept, i.e. with secrets masked.
+func (b *logBuffer) add(stream, text string) string {
+	line := LogLine{Time: time.Now(), Stream: stream, Text: b.redactor.String(text)}
+	if b.sink != nil {
+		b.sink(b.service, line)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.lines) < logBufferLines {
+		b.lines = append(b.lines, line)
+		return line.Text
+	}
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % logBufferLines
+	return line.Text
+}
+
+func (b *logBuffer) last(n int) []LogLine {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if n <= 0 || n > len(b.lines) {
+		n = len(b.lines)
+	}
+	out := make([]LogLine, 0, n)
+	for i := len(b.lines) - n; i < len(b.lines); i++ {
+		out = append(out, b.lines[(b.next+i)%len(b.lines)])
+	}
+	return out
+}
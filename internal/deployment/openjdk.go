@@ -11,9 +11,11 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"vinr.eu/vanguard/internal/defs"
 	"vinr.eu/vanguard/internal/errs"
+	"vinr.eu/vanguard/internal/redact"
 )
 
 var (
@@ -26,11 +28,13 @@ type OpenJDKDeployment struct {
 	svc      *defs.Service
 	execPath string
 	binDir   string
-	cmd      *exec.Cmd
+	proc     process
+	files    files
+	logs     logBuffer
 	logger   *slog.Logger
 }
 
-func NewOpenJDKDeployment(svc *defs.Service, repoPath, binDir string) *OpenJDKDeployment {
+func NewOpenJDKDeployment(svc *defs.Service, repoPath, binDir string, sink LogSink, redactor *redact.Redactor) *OpenJDKDeployment {
 	execPath := repoPath
 	if svc.Path != "" {
 		execPath = filepath.Join(repoPath, svc.Path)
@@ -39,6 +43,7 @@ func NewOpenJDKDeployment(svc *defs.Service, repoPath, binDir string) *OpenJDKDe
 		svc:      svc,
 		execPath: execPath,
 		binDir:   binDir,
+		logs:     logBuffer{service: svc.Name, sink: sink, redactor: redactor},
 		logger:   slog.Default().With("svc", svc.Name, "engine", svc.Runtime.Engine, "version", svc.Runtime.Version),
 	}
 }
@@ -54,10 +59,11 @@ func (d *OpenJDKDeployment) Install(ctx context.Context) error {
 	cmd := exec.CommandContext(ctx, manager, args...)
 	cmd.Dir = d.execPath
 	cmd.Env = d.buildEnv()
-	if err := d.setupPipes(ctx, cmd); err != nil {
+	drained, err := d.setupPipes(ctx, cmd)
+	if err != nil {
 		return errs.Wrap(ErrJavaPipeFailed, err)
 	}
-	if err := cmd.Run(); err != nil {
+	if err := run(cmd, drained); err != nil {
 		return errs.WrapMsgErr(ErrJavaBuildFailed, manager, err)
 	}
 	return nil
@@ -74,24 +80,48 @@ func (d *OpenJDKDeployment) Start(ctx context.Context) error {
 		commandName = filepath.Join(d.binDir, commandName)
 	}
 	cmd := exec.CommandContext(ctx, commandName, args[1:]...)
+	fileEnv, err := d.files.render(d.svc, d.execPath)
+	if err != nil {
+		return err
+	}
 	cmd.Dir = d.execPath
-	cmd.Env = d.buildEnv()
-	if err := d.setupPipes(ctx, cmd); err != nil {
+	cmd.Env = append(d.buildEnv(), fileEnv...)
+	drained, err := d.setupPipes(ctx, cmd)
+	if err != nil {
 		return errs.Wrap(ErrJavaPipeFailed, err)
 	}
-	if err := cmd.Start(); err != nil {
+	if err := d.proc.start(cmd, drained); err != nil {
 		return errs.Wrap(ErrJavaStartFailed, err)
 	}
-	d.cmd = cmd
 	d.logger.Info("process started", "pid", cmd.Process.Pid)
 	return nil
 }
 
 func (d *OpenJDKDeployment) Stop() error {
-	if d.cmd != nil && d.cmd.Process != nil {
-		return d.cmd.Process.Signal(os.Interrupt)
+	err := d.proc.stop(stopTimeout)
+	d.files.cleanup()
+	return err
+}
+
+func (d *OpenJDKDeployment) Update(svc *defs.Service) error {
+	d.svc = svc
+	if d.proc.status().State != StateRunning {
+		return nil
 	}
-	return nil
+	_, err := d.files.render(svc, d.execPath)
+	return err
+}
+
+func (d *OpenJDKDeployment) Signal(sig os.Signal) error {
+	return d.proc.signal(sig)
+}
+
+func (d *OpenJDKDeployment) Status() Status {
+	return d.proc.status()
+}
+
+func (d *OpenJDKDeployment) Logs(n int) []LogLine {
+	return d.logs.last(n)
 }
 
 func (d *OpenJDKDeployment) buildEnv() []string {
@@ -121,24 +151,25 @@ func (d *OpenJDKDeployment) detectManager() (string, []string) {
 	return "./gradlew", []string{"build", "-x", "test"}
 }
 
-func (d *OpenJDKDeployment) setupPipes(ctx context.Context, cmd *exec.Cmd) error {
+func (d *OpenJDKDeployment) setupPipes(ctx context.Context, cmd *exec.Cmd) (*sync.WaitGroup, error) {
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	go d.logPipe(ctx, stdout, slog.LevelInfo)
-	go d.logPipe(ctx, stderr, slog.LevelError)
-	return nil
+	drained := new(sync.WaitGroup)
+	drained.Go(func() { d.logPipe(ctx, stdout, StreamStdout, slog.LevelInfo) })
+	drained.Go(func() { d.logPipe(ctx, stderr, StreamStderr, slog.LevelError) })
+	return drained, nil
 }
 
-func (d *OpenJDKDeployment) logPipe(ctx context.Context, rc io.ReadCloser, level slog.Level) {
+func (d *OpenJDKDeployment) logPipe(ctx context.Context, rc io.ReadCloser, stream string, level slog.Level) {
 	defer rc.Close()
 	scanner := bufio.NewScanner(rc)
 	for scanner.Scan() {
-		d.logger.Log(ctx, level, scanner.Text())
+		d.logger.Log(ctx, level, d.logs.add(stream, scanner.Text()))
 	}
 }
@@ -0,0 +1,142 @@
+package deployment
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	StatePending = "pending"
+	StateRunning = "running"
+	StateExited  = "exited"
+	StateStopped = "stopped"
+
+	stopTimeout = 10 * time.Second
+)
+
+type Status struct {
+	State     string
+	PID       int
+	ExitCode  int
+	Restarts  int
+	StartedAt time.Time
+}
+
+type process struct {
+	mu        sync.Mutex
+	cmd       *exec.Cmd
+	state     string
+	exitCode  int
+	starts    int
+	startedAt time.Time
+	stopping  bool
+	exited    chan struct{}
+}
+
+// run runs cmd to completion, waiting for the readers of its output pipes
+// to be drained first.
+func run(cmd *exec.Cmd, drained *sync.WaitGroup) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	drained.Wait()
+	return cmd.Wait()
+}
+
+// start runs cmd in the background. drained is done once the readers of its
+// output pipes have hit EOF.
+func (p *process) start(cmd *exec.Cmd, drained *sync.WaitGroup) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.cmd = cmd
+	p.state = StateRunning
+	p.exitCode = 0
+	p.starts++
+	p.startedAt = time.Now()
+	p.stopping = false
+	p.exited = make(chan struct{})
+	go p.wait(cmd, drained, p.exited)
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *process) wait(cmd *exec.Cmd, drained *sync.WaitGroup, exited chan struct{}) {
+	// Cmd.Wait closes the output pipes, so it only runs once their readers
+	// are done. It then releases the pipes and the context watcher.
+	drained.Wait()
+	_ = cmd.Wait()
+	defer close(exited)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cmd != cmd {
+		return
+	}
+	p.exitCode = -1
+	if cmd.ProcessState != nil {
+		p.exitCode = cmd.ProcessState.ExitCode()
+	}
+	p.state = StateExited
+	if p.stopping {
+		p.state = StateStopped
+	}
+}
+
+func (p *process) signal(sig os.Signal) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cmd == nil || p.cmd.Process == nil || p.state != StateRunning {
+		return nil
+	}
+	if sig == os.Interrupt || sig == os.Kill {
+		p.stopping = true
+	}
+	return p.cmd.Process.Signal(sig)
+}
+
+// stop interrupts the process and waits for it to exit, killing it if it
+// does not exit within the timeout.
+func (p *process) stop(timeout time.Duration) error {
+	p.mu.Lock()
+	exited := p.exited
+	p.mu.Unlock()
+	if err := p.signal(os.Interrupt); err != nil {
+		return err
+	}
+	if exited == nil {
+		return nil
+	}
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(timeout):
+	}
+	if err := p.signal(os.Kill); err != nil {
+		return err
+	}
+	<-exited
+	return nil
+}
+
+func (p *process) status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := Status{
+		State:     p.state,
+		ExitCode:  p.exitCode,
+		StartedAt: p.startedAt,
+	}
+	if st.State == "" {
+		st.State = StatePending
+	}
+	if p.starts > 1 {
+		st.Restarts = p.starts - 1
+	}
+	if p.cmd != nil && p.cmd.Process != nil && p.state == StateRunning {
+		st.PID = p.cmd.Process.Pid
+	}
+	return st
+}
@@ -0,0 +1,62 @@
+package deployment
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+)
+
+func openFDs(t *testing.T) int {
+	t.Helper()
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("no /proc/self/fd")
+	}
+	return len(fds)
+}
+
+func TestProcessDrainsAndReleasesPipes(t *testing.T) {
+	before := openFDs(t)
+	var p process
+	for range 3 {
+		cmd := exec.Command("sh", "-c", "for i in $(seq 1000); do echo line $i; done; echo done >&2; exit 3")
+		var mu sync.Mutex
+		var lines []string
+		drained := new(sync.WaitGroup)
+		for _, pipe := range []func() (io.ReadCloser, error){cmd.StdoutPipe, cmd.StderrPipe} {
+			rc, err := pipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			drained.Go(func() {
+				scanner := bufio.NewScanner(rc)
+				for scanner.Scan() {
+					mu.Lock()
+					lines = append(lines, scanner.Text())
+					mu.Unlock()
+				}
+			})
+		}
+		if err := p.start(cmd, drained); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-p.exited:
+		case <-time.After(10 * time.Second):
+			t.Fatal("process did not exit")
+		}
+		if st := p.status(); st.State != StateExited || st.ExitCode != 3 {
+			t.Fatalf("status = %+v, want exited with code 3", st)
+		}
+		if len(lines) != 1001 {
+			t.Fatalf("read %d lines, want all 1001", len(lines))
+		}
+	}
+	if after := openFDs(t); after > before {
+		t.Errorf("open fds = %d, was %d before the runs", after, before)
+	}
+}
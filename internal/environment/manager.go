@@ -5,13 +5,28 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"net"
+	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 
-	"vinr.eu/vanguard/internal/aws"
 	"vinr.eu/vanguard/internal/defs"
 	"vinr.eu/vanguard/internal/deployment"
 	"vinr.eu/vanguard/internal/errs"
+	"vinr.eu/vanguard/internal/metrics"
+	"vinr.eu/vanguard/internal/redact"
+	"vinr.eu/vanguard/internal/secrets"
+	"vinr.eu/vanguard/internal/sops"
 	"vinr.eu/vanguard/internal/source"
+	"vinr.eu/vanguard/internal/telemetry"
 	"vinr.eu/vanguard/internal/toolchain"
 )
 
@@ -20,27 +35,81 @@ var (
 	ErrNoSource        = errors.New("environment: no source for definitions")
 	ErrProvisionFailed = errors.New("environment: provisioning failed")
 	ErrDeployFailed    = errors.New("environment: service deployment failed")
+	ErrUnknownService  = errors.New("environment: unknown service")
+	ErrNotRunning      = errors.New("environment: service not deployed")
+	ErrResolveFailed   = errors.New("environment: resolving secrets failed")
+)
+
+const (
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+	HealthUnknown   = "unknown"
+
+	StateFailed = "failed"
 )
 
+type ServiceStatus struct {
+	Name   string
+	Port   int
+	Commit string
+	Health string
+	Error  string
+	deployment.Status
+}
+
 type Manager struct {
-	workspaceDir         string
-	defsStore            *defs.Store
-	activeDeployments    map[string]deployment.Deployment
-	tokenProvider        source.TokenProvider
-	secretsManagerClient *aws.SecretsManagerClient
+	workspaceDir      string
+	defsStore         *defs.Store
+	applyMu           sync.Mutex
+	mu                sync.RWMutex
+	activeDeployments map[string]deployment.Deployment
+	commits           map[string]string
+	failures          map[string]error
+	health            map[string]string
+	tokenProvider     source.TokenProvider
+	logSink           deployment.LogSink
+	redactor          *redact.Redactor
 }
 
-func NewManager(workspaceDir string, tp source.TokenProvider, smc *aws.SecretsManagerClient) *Manager {
+func NewManager(workspaceDir string, tp source.TokenProvider, registry *secrets.Registry) *Manager {
+	redactor := redact.New()
 	return &Manager{
-		workspaceDir:         workspaceDir,
-		defsStore:            defs.NewStore().WithSecretsManager(smc),
-		activeDeployments:    make(map[string]deployment.Deployment),
-		tokenProvider:        tp,
-		secretsManagerClient: smc,
+		workspaceDir:      workspaceDir,
+		defsStore:         defs.NewStore().WithSecrets(registry).WithRedactor(redactor),
+		activeDeployments: make(map[string]deployment.Deployment),
+		commits:           make(map[string]string),
+		failures:          make(map[string]error),
+		health:            make(map[string]string),
+		tokenProvider:     tp,
+		redactor:          redactor,
 	}
 }
 
-func (m *Manager) Boot(ctx context.Context, envDefsGitURL string, envDefsDir string) error {
+// WithLogSink forwards the output of every deployed service to sink. It must
+// be set before the first deployment.
+func (m *Manager) WithLogSink(sink deployment.LogSink) *Manager {
+	m.logSink = sink
+	return m
+}
+
+// WithRedactor masks the secrets the manager resolves in the output of its
+// services. It must be set before the first deployment.
+func (m *Manager) WithRedactor(r *redact.Redactor) *Manager {
+	m.redactor = r
+	m.defsStore.WithRedactor(r)
+	return m
+}
+
+// WithDecrypter sets the age identities used to open encrypted values in the
+// definitions.
+func (m *Manager) WithDecrypter(d *sops.Decrypter) *Manager {
+	m.defsStore.WithDecrypter(d)
+	return m
+}
+
+func (m *Manager) Boot(ctx context.Context, envDefsGitURL string, envDefsDir string) (err error) {
+	ctx, span := telemetry.Start(ctx, "environment.Boot")
+	defer func() { telemetry.End(span, err) }()
 	var envPath string
 	if envDefsGitURL != "" && envDefsDir != "" {
 		definitionsDir := filepath.Join(m.workspaceDir, "definitions")
@@ -49,7 +118,11 @@ func (m *Manager) Boot(ctx context.Context, envDefsGitURL string, envDefsDir str
 		if err != nil {
 			return errs.WrapMsgErr(ErrBootFailed, "source init", err)
 		}
-		if err := envSrc.Fetch(ctx, definitionsDir); err != nil {
+		err = traced(ctx, "source.Fetch", func(ctx context.Context) error {
+			_, err := envSrc.Fetch(ctx, definitionsDir)
+			return err
+		})
+		if err != nil {
 			return errs.WrapMsgErr(ErrBootFailed, "fetch specs", err)
 		}
 	} else if envDefsDir != "" {
@@ -64,22 +137,57 @@ func (m *Manager) Boot(ctx context.Context, envDefsGitURL string, envDefsDir str
 	return m.Start(ctx)
 }
 
-func (m *Manager) BootWithConfig(ctx context.Context, services []*defs.Service) error {
+func (m *Manager) BootWithConfig(ctx context.Context, services []*defs.Service) (err error) {
+	ctx, span := telemetry.Start(ctx, "environment.BootWithConfig")
+	defer func() { telemetry.End(span, err) }()
+	if err := m.resolve(ctx, services); err != nil {
+		return errs.Wrap(ErrBootFailed, err)
+	}
 	for _, svc := range services {
 		m.defsStore.Services[svc.Name] = svc
 	}
 	return m.Start(ctx)
 }
 
+// BootOffline boots from a config snapshot while Citadel is unreachable. A
+// service whose refs cannot be resolved is marked failed instead of failing
+// the boot, and one whose source cannot be fetched starts from its existing
+// checkout.
+func (m *Manager) BootOffline(ctx context.Context, services []*defs.Service) (err error) {
+	ctx, span := telemetry.Start(ctx, "environment.BootOffline")
+	defer func() { telemetry.End(span, err) }()
+	for _, svc := range services {
+		m.defsStore.Services[svc.Name] = svc
+		if err := m.defsStore.Resolve(ctx, svc); err != nil {
+			err = errs.WrapMsgErr(ErrResolveFailed, svc.Name, err)
+			slog.ErrorContext(ctx, "service not started", "service", svc.Name, "error", err)
+			m.mu.Lock()
+			m.failures[svc.Name] = err
+			m.mu.Unlock()
+		}
+	}
+	return m.start(ctx, true)
+}
+
 func (m *Manager) Start(ctx context.Context) error {
+	return m.start(ctx, false)
+}
+
+func (m *Manager) start(ctx context.Context, offline bool) (err error) {
+	ctx, span := telemetry.Start(ctx, "environment.Start", attribute.Int("services", len(m.defsStore.Services)))
+	defer func() { telemetry.End(span, err) }()
 	runtimePaths, err := m.ProvisionAll(ctx)
 	if err != nil {
 		return errs.Wrap(ErrProvisionFailed, err)
 	}
 	for _, svc := range m.defsStore.Services {
-		key := fmt.Sprintf("%s:%s", svc.Runtime.Engine, svc.Runtime.Version)
-		binDir := runtimePaths[key]
-		if err := m.deployService(ctx, svc, binDir); err != nil {
+		m.mu.RLock()
+		_, failed := m.failures[svc.Name]
+		m.mu.RUnlock()
+		if failed {
+			continue
+		}
+		if err := m.deployService(ctx, svc, runtimePaths[runtimeKey(svc.Runtime)], "", offline); err != nil {
 			slog.ErrorContext(ctx, "deployment failed", "service", svc.Name, "error", err)
 			continue
 		}
@@ -87,11 +195,177 @@ func (m *Manager) Start(ctx context.Context) error {
 	return nil
 }
 
+// Apply brings the running services in line with a new set of definitions.
+// Unchanged services keep running, removed ones are stopped and changed,
+// added or failed ones are (re)deployed.
+func (m *Manager) Apply(ctx context.Context, services []*defs.Service) (err error) {
+	ctx, span := telemetry.Start(ctx, "environment.Apply", attribute.Int("services", len(services)))
+	defer func() { telemetry.End(span, err) }()
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+	// Deployed processes must outlive the poll that delivered their config.
+	ctx = context.WithoutCancel(ctx)
+	// Resolve before diffing, so services compare by their effective values
+	// and a bad ref leaves every running service untouched.
+	if err := m.resolve(ctx, services); err != nil {
+		return err
+	}
+
+	desired := make(map[string]*defs.Service, len(services))
+	for _, svc := range services {
+		desired[svc.Name] = svc
+	}
+	m.mu.RLock()
+	current := maps.Clone(m.defsStore.Services)
+	failed := maps.Clone(m.failures)
+	m.mu.RUnlock()
+
+	var changed []*defs.Service
+	var removed []string
+	for name, svc := range current {
+		next, ok := desired[name]
+		_, retry := failed[name]
+		switch {
+		case !ok:
+			removed = append(removed, name)
+		case retry || !reflect.DeepEqual(svc, next):
+			changed = append(changed, next)
+		}
+	}
+	for name, svc := range desired {
+		if _, ok := current[name]; !ok {
+			changed = append(changed, svc)
+		}
+	}
+	slog.InfoContext(ctx, "applying configuration", "changed", len(changed), "removed", len(removed))
+
+	// Provision first so a missing runtime leaves the running services untouched.
+	runtimePaths, err := m.provisionRuntimes(ctx, changed)
+	if err != nil {
+		return errs.Wrap(ErrProvisionFailed, err)
+	}
+	for _, name := range removed {
+		slog.InfoContext(ctx, "removing service", "service", name)
+		m.stopService(name)
+	}
+	m.mu.Lock()
+	m.defsStore.Services = desired
+	m.mu.Unlock()
+
+	var deployErrs []error
+	for _, svc := range changed {
+		slog.InfoContext(ctx, "redeploying service", "service", svc.Name)
+		m.stopService(svc.Name)
+		if err := m.deployService(ctx, svc, runtimePaths[runtimeKey(svc.Runtime)], "", false); err != nil {
+			slog.ErrorContext(ctx, "deployment failed", "service", svc.Name, "error", err)
+			deployErrs = append(deployErrs, err)
+		}
+	}
+	return errors.Join(deployErrs...)
+}
+
+// resolve fills in the secret refs of services delivered by Citadel through
+// the same pipeline as local definitions.
+func (m *Manager) resolve(ctx context.Context, services []*defs.Service) error {
+	var resolveErrs []error
+	for _, svc := range services {
+		if err := m.defsStore.Resolve(ctx, svc); err != nil {
+			resolveErrs = append(resolveErrs, errs.WrapMsgErr(ErrResolveFailed, svc.Name, err))
+		}
+	}
+	return errors.Join(resolveErrs...)
+}
+
+func (m *Manager) Restart(ctx context.Context, name string) (err error) {
+	ctx, span := telemetry.Start(ctx, "environment.Restart", attribute.String("service.name", name))
+	defer func() { telemetry.End(span, err) }()
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+	m.mu.RLock()
+	dep, ok := m.activeDeployments[name]
+	m.mu.RUnlock()
+	if !ok {
+		return errs.WrapMsg(ErrNotRunning, name)
+	}
+	slog.InfoContext(ctx, "restarting service", "service", name)
+	if err := dep.Stop(); err != nil {
+		return errs.WrapMsgErr(ErrDeployFailed, "stop: "+name, err)
+	}
+	if err := dep.Start(context.WithoutCancel(ctx)); err != nil {
+		return errs.WrapMsgErr(ErrDeployFailed, "start: "+name, err)
+	}
+	return nil
+}
+
+// Redeploy fetches a service again, pinned to commit when one is given.
+func (m *Manager) Redeploy(ctx context.Context, name, commit string) (err error) {
+	ctx, span := telemetry.Start(ctx, "environment.Redeploy",
+		attribute.String("service.name", name),
+		attribute.String("vcs.ref", commit),
+	)
+	defer func() { telemetry.End(span, err) }()
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+	ctx = context.WithoutCancel(ctx)
+	m.mu.RLock()
+	svc, ok := m.defsStore.Services[name]
+	m.mu.RUnlock()
+	if !ok {
+		return errs.WrapMsg(ErrUnknownService, name)
+	}
+	runtimePaths, err := m.provisionRuntimes(ctx, []*defs.Service{svc})
+	if err != nil {
+		return errs.Wrap(ErrProvisionFailed, err)
+	}
+	slog.InfoContext(ctx, "redeploying service", "service", name, "commit", commit)
+	m.stopService(name)
+	return m.deployService(ctx, svc, runtimePaths[runtimeKey(svc.Runtime)], commit, false)
+}
+
+func (m *Manager) Logs(name string, n int) ([]deployment.LogLine, error) {
+	m.mu.RLock()
+	dep, ok := m.activeDeployments[name]
+	_, known := m.defsStore.Services[name]
+	m.mu.RUnlock()
+	switch {
+	case ok:
+		return dep.Logs(n), nil
+	case known:
+		return nil, errs.WrapMsg(ErrNotRunning, name)
+	default:
+		return nil, errs.WrapMsg(ErrUnknownService, name)
+	}
+}
+
+func (m *Manager) stopService(name string) {
+	m.mu.Lock()
+	dep, ok := m.activeDeployments[name]
+	delete(m.activeDeployments, name)
+	delete(m.commits, name)
+	delete(m.failures, name)
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+	if err := dep.Stop(); err != nil {
+		slog.Error("stop error", "service", name, "error", err)
+	}
+}
+
 func (m *Manager) ProvisionAll(ctx context.Context) (map[string]string, error) {
-	required := make(map[string]defs.RuntimeSpec)
+	services := make([]*defs.Service, 0, len(m.defsStore.Services))
 	for _, svc := range m.defsStore.Services {
-		key := fmt.Sprintf("%s:%s", svc.Runtime.Engine, svc.Runtime.Version)
-		required[key] = svc.Runtime
+		services = append(services, svc)
+	}
+	return m.provisionRuntimes(ctx, services)
+}
+
+func (m *Manager) provisionRuntimes(ctx context.Context, services []*defs.Service) (_ map[string]string, err error) {
+	ctx, span := telemetry.Start(ctx, "environment.ProvisionAll")
+	defer func() { telemetry.End(span, err) }()
+	required := make(map[string]defs.RuntimeSpec)
+	for _, svc := range services {
+		required[runtimeKey(svc.Runtime)] = svc.Runtime
 	}
 	slog.InfoContext(ctx, "resolving runtimes", "count", len(required))
 	results := make(map[string]string)
@@ -101,7 +375,7 @@ func (m *Manager) ProvisionAll(ctx context.Context) (map[string]string, error) {
 			return nil, errs.WrapMsgErr(ErrProvisionFailed, key, err)
 		}
 		slog.InfoContext(ctx, "provisioning toolchain", "spec", key)
-		binDir, err := tc.Provision(ctx, spec.Version)
+		binDir, err := m.provision(ctx, tc, spec)
 		if err != nil {
 			return nil, errs.WrapMsgErr(ErrProvisionFailed, key, err)
 		}
@@ -110,41 +384,197 @@ func (m *Manager) ProvisionAll(ctx context.Context) (map[string]string, error) {
 	return results, nil
 }
 
+func (m *Manager) provision(ctx context.Context, tc toolchain.Toolchain, spec defs.RuntimeSpec) (_ string, err error) {
+	ctx, span := telemetry.Start(ctx, "toolchain.Provision",
+		attribute.String("toolchain.engine", spec.Engine),
+		attribute.String("toolchain.version", spec.Version),
+	)
+	defer func() { telemetry.End(span, err) }()
+	started := time.Now()
+	binDir, err := tc.Provision(ctx, spec.Version)
+	metrics.ObserveToolchainProvision(spec.Engine, spec.Version, time.Since(started), err)
+	return binDir, err
+}
+
 func (m *Manager) GetServices() map[string]*defs.Service {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return m.defsStore.Services
 }
 
-func (m *Manager) Shutdown() {
+// Statuses reports every service with the health found by the last
+// ProbeHealth; it does not probe, so it is cheap enough for metric scrapes.
+func (m *Manager) Statuses() []ServiceStatus {
+	m.mu.RLock()
+	statuses := make([]ServiceStatus, 0, len(m.defsStore.Services))
+	for name, svc := range m.defsStore.Services {
+		st := ServiceStatus{
+			Name:   name,
+			Port:   svc.Port,
+			Commit: m.commits[name],
+			Health: HealthUnknown,
+		}
+		if dep, ok := m.activeDeployments[name]; ok {
+			st.Status = dep.Status()
+			if health, ok := m.health[name]; ok && st.State == deployment.StateRunning {
+				st.Health = health
+			}
+		} else if err, ok := m.failures[name]; ok {
+			st.State = StateFailed
+			st.Error = m.redactor.String(err.Error())
+		} else {
+			st.State = deployment.StatePending
+		}
+		statuses = append(statuses, st)
+	}
+	m.mu.RUnlock()
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// ProbeHealth dials the port of every running service, all at once, and keeps
+// the results for Statuses.
+func (m *Manager) ProbeHealth() {
+	ports := make(map[string]int)
+	m.mu.RLock()
 	for name, dep := range m.activeDeployments {
-		slog.Info("stopping service", "service", name)
-		if err := dep.Stop(); err != nil {
-			slog.Error("shutdown error", "service", name, "error", err)
+		if svc, ok := m.defsStore.Services[name]; ok && dep.Status().State == deployment.StateRunning {
+			ports[name] = svc.Port
 		}
 	}
+	m.mu.RUnlock()
+	health := make(map[string]string, len(ports))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, port := range ports {
+		wg.Go(func() {
+			h := probe(port)
+			mu.Lock()
+			health[name] = h
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+	m.mu.Lock()
+	m.health = health
+	m.mu.Unlock()
 }
 
-func (m *Manager) deployService(ctx context.Context, svc *defs.Service, binDir string) error {
+func (m *Manager) Shutdown() {
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+	m.mu.RLock()
+	active := maps.Clone(m.activeDeployments)
+	m.mu.RUnlock()
+	var wg sync.WaitGroup
+	for name, dep := range active {
+		slog.Info("stopping service", "service", name)
+		wg.Go(func() {
+			if err := dep.Stop(); err != nil {
+				slog.Error("shutdown error", "service", name, "error", err)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// deployService fetches svc at ref, or at the head of its branch when ref is
+// empty, and starts it. Offline, a failed fetch falls back to the checkout
+// left by the last deployment, which is started as it is.
+func (m *Manager) deployService(ctx context.Context, svc *defs.Service, binDir, ref string, offline bool) (err error) {
+	ctx, span := telemetry.Start(ctx, "environment.deployService", attribute.String("service.name", svc.Name))
+	defer func() {
+		if err != nil {
+			m.mu.Lock()
+			m.failures[svc.Name] = err
+			m.mu.Unlock()
+		}
+		telemetry.End(span, err)
+	}()
 	if svc.GitURL == "" {
 		return errs.WrapMsg(ErrDeployFailed, "no git url: "+svc.Name)
 	}
 	repoPath := filepath.Join(m.workspaceDir, "services", svc.Name)
-	src, err := source.New(svc.GitURL, svc.Branch, m.tokenProvider)
+	if ref == "" {
+		ref = svc.Branch
+	}
+	src, err := source.New(svc.GitURL, ref, m.tokenProvider)
 	if err != nil {
 		return errs.WrapMsgErr(ErrDeployFailed, "source init: "+svc.Name, err)
 	}
-	if err := src.Fetch(ctx, repoPath); err != nil {
-		return errs.WrapMsgErr(ErrDeployFailed, "fetch: "+svc.Name, err)
+	var commit string
+	err = traced(ctx, "source.Fetch", func(ctx context.Context) (err error) {
+		commit, err = src.Fetch(ctx, repoPath)
+		return err
+	})
+	reused := false
+	if err != nil {
+		last, ok := readCommit(repoPath)
+		if !offline || !ok {
+			return errs.WrapMsgErr(ErrDeployFailed, "fetch: "+svc.Name, err)
+		}
+		slog.WarnContext(ctx, "fetch failed, starting the existing checkout", "service", svc.Name, "commit", last, "error", err)
+		commit, reused = last, true
 	}
-	dep, err := deployment.New(svc, repoPath, binDir)
+	dep, err := deployment.New(svc, repoPath, binDir, m.logSink, m.redactor)
 	if err != nil {
 		return errs.WrapMsgErr(ErrDeployFailed, "dep init: "+svc.Name, err)
 	}
-	if err := dep.Install(ctx); err != nil {
-		return errs.WrapMsgErr(ErrDeployFailed, "install: "+svc.Name, err)
+	if !reused {
+		if err := traced(ctx, "deployment.Install", dep.Install); err != nil {
+			return errs.WrapMsgErr(ErrDeployFailed, "install: "+svc.Name, err)
+		}
+		if err := writeCommit(repoPath, commit); err != nil {
+			slog.WarnContext(ctx, "failed to record checkout commit", "service", svc.Name, "error", err)
+		}
 	}
-	if err := dep.Start(ctx); err != nil {
+	if err := traced(ctx, "deployment.Start", dep.Start); err != nil {
 		return errs.WrapMsgErr(ErrDeployFailed, "start: "+svc.Name, err)
 	}
+	m.mu.Lock()
 	m.activeDeployments[svc.Name] = dep
+	m.commits[svc.Name] = commit
+	delete(m.failures, svc.Name)
+	m.mu.Unlock()
 	return nil
 }
+
+// readCommit and writeCommit keep the commit of an installed checkout next to
+// it, so an offline boot knows what it is starting.
+func readCommit(repoPath string) (string, bool) {
+	data, err := os.ReadFile(repoPath + ".commit")
+	if err != nil {
+		return "", false
+	}
+	if _, err := os.Stat(repoPath); err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(data)), true
+}
+
+func writeCommit(repoPath, commit string) error {
+	return os.WriteFile(repoPath+".commit", []byte(commit+"\n"), 0o644)
+}
+
+func runtimeKey(spec defs.RuntimeSpec) string {
+	return fmt.Sprintf("%s:%s", spec.Engine, spec.Version)
+}
+
+func probe(port int) string {
+	if port == 0 {
+		return HealthUnknown
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("localhost", strconv.Itoa(port)), 500*time.Millisecond)
+	if err != nil {
+		return HealthUnhealthy
+	}
+	conn.Close()
+	return HealthHealthy
+}
+
+func traced(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	ctx, span := telemetry.Start(ctx, name)
+	err := fn(ctx)
+	telemetry.End(span, err)
+	return err
+}
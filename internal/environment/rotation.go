@@ -0,0 +1,83 @@
+package environment
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"vinr.eu/vanguard/internal/defs"
+	"vinr.eu/vanguard/internal/errs"
+	"vinr.eu/vanguard/internal/telemetry"
+)
+
+// WatchSecrets refreshes the secrets behind the services' variables every
+// interval until ctx is done.
+func (m *Manager) WatchSecrets(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := m.RefreshSecrets(ctx); err != nil && ctx.Err() == nil {
+			slog.WarnContext(ctx, "secret refresh failed", "error", err)
+		}
+	}
+}
+
+// RefreshSecrets fetches the secrets behind the services' variables again and
+// applies the OnChange policy of the variables that changed to their running
+// services.
+func (m *Manager) RefreshSecrets(ctx context.Context) (err error) {
+	ctx, span := telemetry.Start(ctx, "environment.RefreshSecrets")
+	defer func() { telemetry.End(span, err) }()
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+	changes, err := m.defsStore.Refresh(ctx)
+	if err != nil {
+		err = errs.Wrap(ErrResolveFailed, err)
+	}
+	span.SetAttributes(attribute.Int("services", len(changes)))
+	var applyErrs []error
+	for _, change := range changes {
+		svc := change.Service
+		m.mu.Lock()
+		m.defsStore.Services[svc.Name] = svc
+		dep, ok := m.activeDeployments[svc.Name]
+		m.mu.Unlock()
+		slog.InfoContext(ctx, "secrets changed", "service", svc.Name, "onChange", change.OnChange, "running", ok)
+		if !ok {
+			m.defsStore.Commit(change)
+			continue
+		}
+		if err := dep.Update(svc); err != nil {
+			applyErrs = append(applyErrs, errs.WrapMsgErr(ErrDeployFailed, "update: "+svc.Name, err))
+			continue
+		}
+		switch change.OnChange {
+		case defs.OnChangeRestart:
+			if err := dep.Stop(); err != nil {
+				applyErrs = append(applyErrs, errs.WrapMsgErr(ErrDeployFailed, "stop: "+svc.Name, err))
+				continue
+			}
+			if err := dep.Start(context.WithoutCancel(ctx)); err != nil {
+				applyErrs = append(applyErrs, errs.WrapMsgErr(ErrDeployFailed, "start: "+svc.Name, err))
+				continue
+			}
+		case defs.OnChangeSignal:
+			if err := dep.Signal(syscall.SIGHUP); err != nil {
+				applyErrs = append(applyErrs, errs.WrapMsgErr(ErrDeployFailed, "signal: "+svc.Name, err))
+				continue
+			}
+		}
+		// A change that failed to apply is reported again on the next refresh.
+		m.defsStore.Commit(change)
+	}
+	return errors.Join(append(applyErrs, err)...)
+}
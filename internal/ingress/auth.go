@@ -0,0 +1,238 @@
+package ingress
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"vinr.eu/vanguard/internal/defs"
+	"vinr.eu/vanguard/internal/errs"
+)
+
+var (
+	ErrInvalidAuth    = errors.New("ingress: invalid auth configuration")
+	ErrUnresolvedAuth = errors.New("ingress: auth secret not resolved")
+)
+
+const (
+	defaultAPIKeyHeader = "X-API-Key"
+	defaultRealm        = "vanguard"
+
+	HeaderForwardedUser  = "X-Forwarded-User"
+	HeaderForwardedEmail = "X-Forwarded-Email"
+)
+
+type auth struct {
+	allowlist    []*net.IPNet
+	realm        string
+	users        map[string][32]byte
+	apiKeyHeader string
+	apiKeys      [][32]byte
+	oidc         *oidcAuth
+}
+
+func newAuth(ctx context.Context, host string, spec *defs.IngressAuth) (Middleware, error) {
+	a := &auth{}
+	for _, entry := range spec.IPAllowlist {
+		ipNet, err := parseAllowlistEntry(entry)
+		if err != nil {
+			return nil, errs.WrapMsgErr(ErrInvalidAuth, "ipAllowlist", err)
+		}
+		a.allowlist = append(a.allowlist, ipNet)
+	}
+	if spec.Basic != nil {
+		if len(spec.Basic.Users) == 0 {
+			return nil, errs.WrapMsg(ErrInvalidAuth, "basic needs at least one user")
+		}
+		a.realm = spec.Basic.Realm
+		if a.realm == "" {
+			a.realm = defaultRealm
+		}
+		a.users = make(map[string][32]byte, len(spec.Basic.Users))
+		for _, u := range spec.Basic.Users {
+			if u.Password.Value == nil {
+				return nil, errs.WrapMsg(ErrUnresolvedAuth, "basic user "+u.Username)
+			}
+			a.users[u.Username] = sha256.Sum256([]byte(*u.Password.Value))
+		}
+	}
+	if spec.APIKey != nil {
+		if len(spec.APIKey.Keys) == 0 {
+			return nil, errs.WrapMsg(ErrInvalidAuth, "apiKey needs at least one key")
+		}
+		a.apiKeyHeader = spec.APIKey.Header
+		if a.apiKeyHeader == "" {
+			a.apiKeyHeader = defaultAPIKeyHeader
+		}
+		for i, k := range spec.APIKey.Keys {
+			if k.Value == nil {
+				return nil, errs.WrapMsg(ErrUnresolvedAuth, fmt.Sprintf("api key #%d", i))
+			}
+			a.apiKeys = append(a.apiKeys, sha256.Sum256([]byte(*k.Value)))
+		}
+	}
+	if spec.OIDC != nil {
+		o, err := newOIDCAuth(ctx, host, spec.OIDC)
+		if err != nil {
+			return nil, err
+		}
+		a.oidc = o
+	}
+	return a.wrap, nil
+}
+
+func (a *auth) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.allowedIP(r) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		r.Header.Del(HeaderForwardedUser)
+		r.Header.Del(HeaderForwardedEmail)
+		if a.oidc != nil && a.oidc.handles(r) {
+			a.oidc.ServeHTTP(w, r)
+			return
+		}
+		if a.users == nil && a.apiKeys == nil && a.oidc == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if a.checkAPIKey(r) {
+			a.strip(r)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if user, ok := a.checkBasic(r); ok {
+			a.strip(r)
+			r.Header.Set(HeaderForwardedUser, user)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if a.oidc != nil {
+			if sess, ok := a.oidc.session(r); ok {
+				a.strip(r)
+				r.Header.Set(HeaderForwardedUser, sess.Subject)
+				if sess.Email != "" {
+					r.Header.Set(HeaderForwardedEmail, sess.Email)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if wantsHTML(r) {
+				a.oidc.login(w, r)
+				return
+			}
+		}
+		if a.users != nil {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", a.realm))
+		}
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+// strip removes the credentials the ingress checks, so they never reach the
+// upstream.
+func (a *auth) strip(r *http.Request) {
+	if a.apiKeys != nil {
+		r.Header.Del(a.apiKeyHeader)
+	}
+	if a.users != nil {
+		r.Header.Del("Authorization")
+	}
+	if a.oidc != nil {
+		stripCookies(r, sessionCookieName, stateCookieName)
+	}
+}
+
+func stripCookies(r *http.Request, names ...string) {
+	cookies := r.Cookies()
+	kept := make([]string, 0, len(cookies))
+	for _, c := range cookies {
+		if !slices.Contains(names, c.Name) {
+			kept = append(kept, (&http.Cookie{Name: c.Name, Value: c.Value}).String())
+		}
+	}
+	r.Header.Del("Cookie")
+	if len(kept) > 0 {
+		r.Header.Set("Cookie", strings.Join(kept, "; "))
+	}
+}
+
+func (a *auth) allowedIP(r *http.Request) bool {
+	if len(a.allowlist) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range a.allowlist {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *auth) checkAPIKey(r *http.Request) bool {
+	if a.apiKeys == nil {
+		return false
+	}
+	key := r.Header.Get(a.apiKeyHeader)
+	if key == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(key))
+	match := 0
+	for _, k := range a.apiKeys {
+		match |= subtle.ConstantTimeCompare(sum[:], k[:])
+	}
+	return match == 1
+}
+
+func (a *auth) checkBasic(r *http.Request) (string, bool) {
+	if a.users == nil {
+		return "", false
+	}
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return "", false
+	}
+	expected, known := a.users[user]
+	sum := sha256.Sum256([]byte(pass))
+	if subtle.ConstantTimeCompare(sum[:], expected[:]) != 1 || !known {
+		return "", false
+	}
+	return user, true
+}
+
+func parseAllowlistEntry(entry string) (*net.IPNet, error) {
+	if strings.Contains(entry, "/") {
+		_, ipNet, err := net.ParseCIDR(entry)
+		return ipNet, err
+	}
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid ip: %s", entry)
+	}
+	bits := 128
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+func wantsHTML(r *http.Request) bool {
+	return r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html")
+}
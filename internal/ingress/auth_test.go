@@ -0,0 +1,185 @@
+package ingress
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"vinr.eu/vanguard/internal/defs"
+	"vinr.eu/vanguard/internal/oidcstub"
+)
+
+func ptr(s string) *string { return &s }
+
+// forwarded records the request the auth middleware forwards.
+func forwarded(got **http.Request) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*got = r
+	})
+}
+
+func TestAuthStripsCredentials(t *testing.T) {
+	spec := &defs.IngressAuth{
+		Basic:  &defs.BasicAuth{Users: []defs.BasicUser{{Username: "alice", Password: defs.Secret{Value: ptr("wonderland")}}}},
+		APIKey: &defs.APIKeyAuth{Keys: []defs.Secret{{Value: ptr("key-1234")}}},
+	}
+	mw, err := newAuth(context.Background(), "app.local", spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		set  func(r *http.Request)
+		user string
+	}{
+		{"basic", func(r *http.Request) { r.SetBasicAuth("alice", "wonderland") }, "alice"},
+		{"api key", func(r *http.Request) { r.Header.Set(defaultAPIKeyHeader, "key-1234") }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			tt.set(r)
+			r.Header.Set(HeaderForwardedUser, "mallory")
+			w := httptest.NewRecorder()
+			mw(forwarded(&got)).ServeHTTP(w, r)
+			if got == nil {
+				t.Fatalf("request not forwarded: %d", w.Code)
+			}
+			if v := got.Header.Get("Authorization"); v != "" {
+				t.Errorf("Authorization forwarded: %q", v)
+			}
+			if v := got.Header.Get(defaultAPIKeyHeader); v != "" {
+				t.Errorf("%s forwarded: %q", defaultAPIKeyHeader, v)
+			}
+			if v := got.Header.Get(HeaderForwardedUser); v != tt.user {
+				t.Errorf("%s = %q, want %q", HeaderForwardedUser, v, tt.user)
+			}
+		})
+	}
+	t.Run("rejected", func(t *testing.T) {
+		var got *http.Request
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.SetBasicAuth("alice", "wrong")
+		w := httptest.NewRecorder()
+		mw(forwarded(&got)).ServeHTTP(w, r)
+		if got != nil || w.Code != http.StatusUnauthorized {
+			t.Fatalf("got status %d, forwarded %v", w.Code, got != nil)
+		}
+		if !strings.HasPrefix(w.Header().Get("WWW-Authenticate"), "Basic ") {
+			t.Errorf("missing Basic challenge")
+		}
+	})
+}
+
+// An auth block without credentials must not leave the route open.
+func TestAuthRejectsEmptyCredentials(t *testing.T) {
+	tests := []struct {
+		name string
+		spec *defs.IngressAuth
+	}{
+		{"no api keys", &defs.IngressAuth{APIKey: &defs.APIKeyAuth{}}},
+		{"no basic users", &defs.IngressAuth{Basic: &defs.BasicAuth{Realm: "staff"}}},
+		{"no api keys beside an allowlist", &defs.IngressAuth{IPAllowlist: []string{"0.0.0.0/0"}, APIKey: &defs.APIKeyAuth{Keys: []defs.Secret{}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw, err := newAuth(context.Background(), "app.local", tt.spec)
+			if !errors.Is(err, ErrInvalidAuth) {
+				t.Fatalf("err = %v, want ErrInvalidAuth", err)
+			}
+			if mw != nil {
+				t.Fatal("middleware returned for invalid auth")
+			}
+		})
+	}
+}
+
+func TestAuthOIDCLogin(t *testing.T) {
+	provider, err := oidcstub.New("user-1", "dev@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := httptest.NewServer(provider)
+	defer idp.Close()
+
+	spec := &defs.IngressAuth{OIDC: &defs.OIDCAuth{
+		IssuerURL:      idp.URL,
+		ClientID:       "vanguard",
+		ClientSecret:   defs.Secret{Value: ptr("client-secret")},
+		CookieSecret:   defs.Secret{Value: ptr("cookie-secret")},
+		AllowedDomains: []string{"example.com"},
+	}}
+	mw, err := newAuth(context.Background(), "app.local", spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got *http.Request
+	handler := mw(forwarded(&got))
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	// An unauthenticated browser is sent to the provider.
+	r := httptest.NewRequest(http.MethodGet, "http://app.local/dashboard?tab=1", nil)
+	r.Header.Set("Accept", "text/html")
+	w := serve(r)
+	if w.Code != http.StatusFound || !strings.HasPrefix(w.Header().Get("Location"), idp.URL+"/authorize") {
+		t.Fatalf("login: status %d, location %q", w.Code, w.Header().Get("Location"))
+	}
+	stateCookies := w.Result().Cookies()
+
+	// The provider approves and redirects back with a code.
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := noRedirect.Get(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || callback.Path != oidcCallbackPath {
+		t.Fatalf("provider redirect: %q", resp.Header.Get("Location"))
+	}
+
+	r = httptest.NewRequest(http.MethodGet, callback.String(), nil)
+	for _, c := range stateCookies {
+		r.AddCookie(c)
+	}
+	w = serve(r)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/dashboard?tab=1" {
+		t.Fatalf("callback: status %d, location %q, body %q", w.Code, w.Header().Get("Location"), w.Body)
+	}
+	var session *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == sessionCookieName {
+			session = c
+		}
+	}
+	if session == nil {
+		t.Fatal("no session cookie")
+	}
+
+	// The session is accepted and stays with vanguard.
+	r = httptest.NewRequest(http.MethodGet, "http://app.local/dashboard", nil)
+	r.AddCookie(session)
+	r.AddCookie(&http.Cookie{Name: "app", Value: "kept"})
+	w = serve(r)
+	if got == nil {
+		t.Fatalf("session rejected: %d", w.Code)
+	}
+	if v := got.Header.Get(HeaderForwardedUser); v != "user-1" {
+		t.Errorf("%s = %q", HeaderForwardedUser, v)
+	}
+	if v := got.Header.Get(HeaderForwardedEmail); v != "dev@example.com" {
+		t.Errorf("%s = %q", HeaderForwardedEmail, v)
+	}
+	if v := got.Header.Get("Cookie"); v != "app=kept" {
+		t.Errorf("Cookie forwarded as %q", v)
+	}
+}
@@ -0,0 +1,188 @@
+package ingress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"vinr.eu/vanguard/internal/defs"
+	"vinr.eu/vanguard/internal/errs"
+)
+
+var (
+	ErrInvalidMiddleware = errors.New("ingress: invalid middleware")
+)
+
+func newMiddleware(spec defs.Middleware) (Middleware, error) {
+	var built []Middleware
+	if spec.Headers != nil {
+		built = append(built, headersMiddleware(spec.Headers))
+	}
+	if spec.CORS != nil {
+		built = append(built, corsMiddleware(spec.CORS))
+	}
+	if spec.Compress != nil {
+		mw, err := compressMiddleware(spec.Compress)
+		if err != nil {
+			return nil, err
+		}
+		built = append(built, mw)
+	}
+	if spec.RateLimit != nil {
+		if spec.RateLimit.RequestsPerSecond <= 0 {
+			return nil, errs.WrapMsg(ErrInvalidMiddleware, "rateLimit.requestsPerSecond must be positive")
+		}
+		built = append(built, newRateLimiter(spec.RateLimit).wrap)
+	}
+	if spec.BodyLimit != nil {
+		if spec.BodyLimit.MaxBytes <= 0 {
+			return nil, errs.WrapMsg(ErrInvalidMiddleware, "bodyLimit.maxBytes must be positive")
+		}
+		built = append(built, bodyLimitMiddleware(spec.BodyLimit.MaxBytes))
+	}
+	if spec.Timeout != nil {
+		d, err := time.ParseDuration(spec.Timeout.Duration)
+		if err != nil || d <= 0 {
+			return nil, errs.WrapMsg(ErrInvalidMiddleware, "timeout.duration: "+spec.Timeout.Duration)
+		}
+		built = append(built, timeoutMiddleware(d))
+	}
+	if len(built) != 1 {
+		return nil, errs.WrapMsg(ErrInvalidMiddleware, fmt.Sprintf("each entry must configure exactly one middleware, got %d", len(built)))
+	}
+	return built[0], nil
+}
+
+func headersMiddleware(spec *defs.HeadersMiddleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			applyHeaderRules(r.Header, spec.Request)
+			if spec.Response != nil {
+				w = &headerWriter{ResponseWriter: w, rules: spec.Response}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func applyHeaderRules(h http.Header, rules *defs.HeaderRules) {
+	if rules == nil {
+		return
+	}
+	for _, name := range rules.Remove {
+		h.Del(name)
+	}
+	for name, value := range rules.Set {
+		h.Set(name, value)
+	}
+	for name, value := range rules.Add {
+		h.Add(name, value)
+	}
+}
+
+type headerWriter struct {
+	http.ResponseWriter
+	rules   *defs.HeaderRules
+	applied bool
+}
+
+func (w *headerWriter) WriteHeader(status int) {
+	if !w.applied {
+		w.applied = true
+		applyHeaderRules(w.Header(), w.rules)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *headerWriter) Write(b []byte) (int, error) {
+	if !w.applied {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *headerWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func corsMiddleware(spec *defs.CORSMiddleware) Middleware {
+	methods := spec.AllowMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(spec.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(spec.ExposeHeaders, ", ")
+	allowAll := slices.Contains(spec.AllowOrigins, "*")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			if !allowAll && !slices.Contains(spec.AllowOrigins, origin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if allowAll && !spec.AllowCredentials {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if spec.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if !preflight {
+				if exposeHeaders != "" {
+					h.Set("Access-Control-Expose-Headers", exposeHeaders)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", allowMethods)
+			if allowHeaders != "" {
+				h.Set("Access-Control-Allow-Headers", allowHeaders)
+			} else if reqHeaders := r.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+				h.Set("Access-Control-Allow-Headers", reqHeaders)
+			}
+			if spec.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(spec.MaxAge))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+func bodyLimitMiddleware(maxBytes int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func timeoutMiddleware(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
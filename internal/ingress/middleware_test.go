@@ -0,0 +1,280 @@
+package ingress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"vinr.eu/vanguard/internal/defs"
+)
+
+var page = strings.Repeat("<p>hello vanguard</p>\n", 100)
+
+// upstreamApp serves page as HTML, a pre-compressed copy of it on /encoded
+// and a short body on /small, counting the requests that reach it.
+func upstreamApp(hits *atomic.Int32) http.Handler {
+	var encoded bytes.Buffer
+	zw := gzip.NewWriter(&encoded)
+	_, _ = zw.Write([]byte(page))
+	_ = zw.Close()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/encoded":
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("Content-Encoding", "gzip")
+			_, _ = w.Write(encoded.Bytes())
+		case "/small":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = io.WriteString(w, "ok")
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = io.WriteString(w, page)
+		}
+	})
+}
+
+// routeTo builds the app.local route of a router in front of upstream.
+func routeTo(t *testing.T, upstream http.Handler, middlewares ...defs.Middleware) http.Handler {
+	t.Helper()
+	srv := httptest.NewServer(upstream)
+	t.Cleanup(srv.Close)
+	svc := &defs.Service{
+		Name:        "app",
+		Port:        srv.Listener.Addr().(*net.TCPAddr).Port,
+		IngressHost: ptr("app.local"),
+		Ingress:     &defs.Ingress{Middlewares: middlewares},
+	}
+	router := NewRouter()
+	if err := router.Update(context.Background(), map[string]*defs.Service{"app": svc}); err != nil {
+		t.Fatal(err)
+	}
+	route, ok := router.Match("app.local")
+	if !ok {
+		t.Fatal("no route for app.local")
+	}
+	return route
+}
+
+func serve(h http.Handler, r *http.Request) *http.Response {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Result()
+}
+
+func gunzip(t *testing.T, body io.Reader) string {
+	t.Helper()
+	zr, err := gzip.NewReader(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(plain)
+}
+
+func TestCompressMiddleware(t *testing.T) {
+	var hits atomic.Int32
+	route := routeTo(t, upstreamApp(&hits), defs.Middleware{Compress: &defs.CompressMiddleware{MinSize: 64}})
+	tests := []struct {
+		name     string
+		path     string
+		accept   string
+		encoding string
+	}{
+		{name: "gzip", path: "/", accept: "gzip", encoding: "gzip"},
+		{name: "brotli preferred", path: "/", accept: "gzip, br", encoding: "br"},
+		{name: "brotli refused", path: "/", accept: "br;q=0, gzip;q=0.5", encoding: "gzip"},
+		{name: "identity", path: "/", accept: "identity"},
+		{name: "no accept-encoding", path: "/"},
+		{name: "below min size", path: "/small", accept: "gzip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://app.local"+tt.path, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept-Encoding", tt.accept)
+			}
+			resp := serve(route, r)
+			if got := resp.Header.Get("Content-Encoding"); got != tt.encoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.encoding)
+			}
+			switch tt.encoding {
+			case "gzip":
+				if got := gunzip(t, resp.Body); got != page {
+					t.Errorf("decompressed body differs from the upstream page")
+				}
+				if resp.Header.Get("ETag") != `W/"v1"` || resp.Header.Get("Content-Length") != "" {
+					t.Errorf("ETag %q, Content-Length %q: want a weak ETag and no length",
+						resp.Header.Get("ETag"), resp.Header.Get("Content-Length"))
+				}
+				if !strings.Contains(strings.Join(resp.Header.Values("Vary"), ","), "Accept-Encoding") {
+					t.Errorf("Vary = %q, want Accept-Encoding", resp.Header.Values("Vary"))
+				}
+			case "":
+				body, _ := io.ReadAll(resp.Body)
+				if want := map[string]string{"/": page, "/small": "ok"}[tt.path]; string(body) != want {
+					t.Errorf("body = %.40q, want it uncompressed", body)
+				}
+			}
+		})
+	}
+
+	t.Run("already encoded", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://app.local/encoded", nil)
+		r.Header.Set("Accept-Encoding", "gzip, br")
+		resp := serve(route, r)
+		if got := resp.Header.Values("Content-Encoding"); len(got) != 1 || got[0] != "gzip" {
+			t.Fatalf("Content-Encoding = %q, want the upstream gzip only", got)
+		}
+		if got := gunzip(t, resp.Body); got != page {
+			t.Errorf("body was encoded twice")
+		}
+	})
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        defs.CORSMiddleware
+		method      string
+		origin      string
+		status      int
+		allowOrigin string
+		credentials string
+		forwarded   bool
+	}{
+		{
+			name:        "preflight",
+			spec:        defs.CORSMiddleware{AllowOrigins: []string{"https://app.example"}, AllowHeaders: []string{"X-Token"}, MaxAge: 600},
+			method:      http.MethodOptions,
+			origin:      "https://app.example",
+			status:      http.StatusNoContent,
+			allowOrigin: "https://app.example",
+		},
+		{
+			name:        "simple request",
+			spec:        defs.CORSMiddleware{AllowOrigins: []string{"https://app.example"}, ExposeHeaders: []string{"X-Request-Id"}},
+			method:      http.MethodGet,
+			origin:      "https://app.example",
+			status:      http.StatusOK,
+			allowOrigin: "https://app.example",
+			forwarded:   true,
+		},
+		{
+			name:      "origin not allowed",
+			spec:      defs.CORSMiddleware{AllowOrigins: []string{"https://app.example"}},
+			method:    http.MethodGet,
+			origin:    "https://evil.example",
+			status:    http.StatusOK,
+			forwarded: true,
+		},
+		{
+			name:        "wildcard",
+			spec:        defs.CORSMiddleware{AllowOrigins: []string{"*"}},
+			method:      http.MethodGet,
+			origin:      "https://any.example",
+			status:      http.StatusOK,
+			allowOrigin: "*",
+			forwarded:   true,
+		},
+		{
+			// Browsers reject "*" on credentialed requests, so the origin is
+			// echoed instead.
+			name:        "wildcard with credentials",
+			spec:        defs.CORSMiddleware{AllowOrigins: []string{"*"}, AllowCredentials: true},
+			method:      http.MethodOptions,
+			origin:      "https://any.example",
+			status:      http.StatusNoContent,
+			allowOrigin: "https://any.example",
+			credentials: "true",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			route := routeTo(t, upstreamApp(&hits), defs.Middleware{CORS: &tt.spec})
+			r := httptest.NewRequest(tt.method, "http://app.local/", nil)
+			r.Header.Set("Origin", tt.origin)
+			if tt.method == http.MethodOptions {
+				r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+				r.Header.Set("Access-Control-Request-Headers", "X-Token")
+			}
+			resp := serve(route, r)
+			h := resp.Header
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if got := h.Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.allowOrigin)
+			}
+			if got := h.Get("Access-Control-Allow-Credentials"); got != tt.credentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, tt.credentials)
+			}
+			if forwarded := hits.Load() > 0; forwarded != tt.forwarded {
+				t.Errorf("forwarded upstream = %v, want %v", forwarded, tt.forwarded)
+			}
+			if !strings.Contains(strings.Join(h.Values("Vary"), ","), "Origin") {
+				t.Errorf("Vary = %q, want Origin", h.Values("Vary"))
+			}
+			if tt.status == http.StatusNoContent {
+				if h.Get("Access-Control-Allow-Methods") == "" || h.Get("Access-Control-Allow-Headers") != "X-Token" {
+					t.Errorf("preflight headers = %v", h)
+				}
+				if tt.spec.MaxAge > 0 && h.Get("Access-Control-Max-Age") != "600" {
+					t.Errorf("Access-Control-Max-Age = %q", h.Get("Access-Control-Max-Age"))
+				}
+			}
+			if len(tt.spec.ExposeHeaders) > 0 && h.Get("Access-Control-Expose-Headers") != "X-Request-Id" {
+				t.Errorf("Access-Control-Expose-Headers = %q", h.Get("Access-Control-Expose-Headers"))
+			}
+		})
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	var hits atomic.Int32
+	route := routeTo(t, upstreamApp(&hits), defs.Middleware{RateLimit: &defs.RateLimitMiddleware{
+		RequestsPerSecond: 0.5,
+		Burst:             2,
+		KeyHeader:         "X-Client",
+	}})
+	request := func(client string) *http.Response {
+		r := httptest.NewRequest(http.MethodGet, "http://app.local/small", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		if client != "" {
+			r.Header.Set("X-Client", client)
+		}
+		return serve(route, r)
+	}
+	for i := range 2 {
+		if resp := request(""); resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d within the burst: status %d", i+1, resp.StatusCode)
+		}
+	}
+	resp := request("")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429 once the bucket is empty", resp.StatusCode)
+	}
+	// One token refills in two seconds at half a request per second.
+	if got := resp.Header.Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("upstream hits = %d, want the limited request kept from it", hits.Load())
+	}
+	// The key header gives a client its own bucket.
+	if resp := request("tenant-a"); resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d for a separate key, want 200", resp.StatusCode)
+	}
+}
@@ -0,0 +1,324 @@
+package ingress
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"vinr.eu/vanguard/internal/defs"
+	"vinr.eu/vanguard/internal/errs"
+)
+
+var (
+	ErrOIDCDiscovery = errors.New("ingress: oidc discovery failed")
+	errInvalidCookie = errors.New("invalid cookie")
+)
+
+const (
+	oidcCallbackPath = "/_vanguard/oidc/callback"
+	oidcLogoutPath   = "/_vanguard/oidc/logout"
+
+	sessionCookieName = "_vanguard_session"
+	stateCookieName   = "_vanguard_oidc_state"
+
+	sessionTTL = 8 * time.Hour
+	stateTTL   = 10 * time.Minute
+)
+
+type oidcAuth struct {
+	host           string
+	spec           *defs.OIDCAuth
+	key            []byte
+	mu             sync.Mutex
+	provider       *oidc.Provider
+	verifier       *oidc.IDTokenVerifier
+	allowedEmails  []string
+	allowedDomains []string
+}
+
+type oidcSession struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email,omitempty"`
+	Expiry  int64  `json:"exp"`
+}
+
+type oidcState struct {
+	State  string `json:"state"`
+	Nonce  string `json:"nonce"`
+	Return string `json:"ret"`
+	Expiry int64  `json:"exp"`
+}
+
+func newOIDCAuth(ctx context.Context, host string, spec *defs.OIDCAuth) (*oidcAuth, error) {
+	if spec.IssuerURL == "" || spec.ClientID == "" {
+		return nil, errs.WrapMsg(ErrInvalidAuth, "oidc issuerURL and clientID are required")
+	}
+	if spec.ClientSecret.Value == nil {
+		return nil, errs.WrapMsg(ErrUnresolvedAuth, "oidc clientSecret")
+	}
+	o := &oidcAuth{
+		host:           host,
+		spec:           spec,
+		allowedEmails:  lower(spec.AllowedEmails),
+		allowedDomains: lower(spec.AllowedDomains),
+	}
+	if spec.CookieSecret.Value != nil {
+		sum := sha256.Sum256([]byte(*spec.CookieSecret.Value))
+		o.key = sum[:]
+	} else {
+		slog.Warn("oidc cookieSecret not set, sessions will not survive a restart", "host", host)
+		o.key = make([]byte, 32)
+		if _, err := rand.Read(o.key); err != nil {
+			return nil, err
+		}
+	}
+	// Discovery is retried on first use when the issuer is not reachable yet.
+	if _, _, err := o.client(ctx); err != nil {
+		slog.Warn("oidc discovery deferred", "host", host, "issuer", spec.IssuerURL, "error", err)
+	}
+	return o, nil
+}
+
+func (o *oidcAuth) client(ctx context.Context) (*oidc.Provider, *oidc.IDTokenVerifier, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.provider != nil {
+		return o.provider, o.verifier, nil
+	}
+	provider, err := oidc.NewProvider(ctx, o.spec.IssuerURL)
+	if err != nil {
+		return nil, nil, errs.WrapMsgErr(ErrOIDCDiscovery, o.spec.IssuerURL, err)
+	}
+	o.provider = provider
+	o.verifier = provider.Verifier(&oidc.Config{ClientID: o.spec.ClientID})
+	return o.provider, o.verifier, nil
+}
+
+func (o *oidcAuth) oauth2Config(r *http.Request, provider *oidc.Provider) *oauth2.Config {
+	redirectURL := o.spec.RedirectURL
+	if redirectURL == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		redirectURL = scheme + "://" + r.Host + oidcCallbackPath
+	}
+	scopes := o.spec.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+	return &oauth2.Config{
+		ClientID:     o.spec.ClientID,
+		ClientSecret: *o.spec.ClientSecret.Value,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  redirectURL,
+		Scopes:       scopes,
+	}
+}
+
+func (o *oidcAuth) handles(r *http.Request) bool {
+	return r.URL.Path == oidcCallbackPath || r.URL.Path == oidcLogoutPath
+}
+
+func (o *oidcAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case oidcCallbackPath:
+		o.callback(w, r)
+	case oidcLogoutPath:
+		o.clearCookie(w, r, sessionCookieName)
+		http.Redirect(w, r, "/", http.StatusFound)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (o *oidcAuth) login(w http.ResponseWriter, r *http.Request) {
+	provider, _, err := o.client(r.Context())
+	if err != nil {
+		slog.Error("oidc login failed", "host", o.host, "error", err)
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		return
+	}
+	st := oidcState{
+		State:  randomToken(),
+		Nonce:  randomToken(),
+		Return: r.URL.RequestURI(),
+		Expiry: time.Now().Add(stateTTL).Unix(),
+	}
+	if err := o.setCookie(w, r, stateCookieName, st, stateTTL); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	url := o.oauth2Config(r, provider).AuthCodeURL(st.State, oidc.Nonce(st.Nonce))
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+func (o *oidcAuth) callback(w http.ResponseWriter, r *http.Request) {
+	var st oidcState
+	if err := o.readCookie(r, stateCookieName, &st); err != nil || st.Expiry < time.Now().Unix() {
+		http.Error(w, "invalid login state", http.StatusBadRequest)
+		return
+	}
+	o.clearCookie(w, r, stateCookieName)
+	if state := r.URL.Query().Get("state"); state == "" || !hmac.Equal([]byte(state), []byte(st.State)) {
+		http.Error(w, "invalid login state", http.StatusBadRequest)
+		return
+	}
+	if e := r.URL.Query().Get("error"); e != "" {
+		http.Error(w, "login failed: "+e, http.StatusUnauthorized)
+		return
+	}
+	provider, verifier, err := o.client(r.Context())
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		return
+	}
+	token, err := o.oauth2Config(r, provider).Exchange(r.Context(), r.URL.Query().Get("code"))
+	if err != nil {
+		slog.Warn("oidc code exchange failed", "host", o.host, "error", err)
+		http.Error(w, "login failed", http.StatusUnauthorized)
+		return
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		http.Error(w, "login failed: no id_token", http.StatusUnauthorized)
+		return
+	}
+	idToken, err := verifier.Verify(r.Context(), rawIDToken)
+	if err != nil || idToken.Nonce != st.Nonce {
+		slog.Warn("oidc id token rejected", "host", o.host, "error", err)
+		http.Error(w, "login failed", http.StatusUnauthorized)
+		return
+	}
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified *bool  `json:"email_verified"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		http.Error(w, "login failed", http.StatusUnauthorized)
+		return
+	}
+	if !o.permitted(claims.Email, claims.EmailVerified) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	sess := oidcSession{
+		Subject: idToken.Subject,
+		Email:   claims.Email,
+		Expiry:  time.Now().Add(sessionTTL).Unix(),
+	}
+	if err := o.setCookie(w, r, sessionCookieName, sess, sessionTTL); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	ret := st.Return
+	if !strings.HasPrefix(ret, "/") || strings.HasPrefix(ret, "//") {
+		ret = "/"
+	}
+	http.Redirect(w, r, ret, http.StatusFound)
+}
+
+func (o *oidcAuth) permitted(email string, verified *bool) bool {
+	if len(o.allowedEmails) == 0 && len(o.allowedDomains) == 0 {
+		return true
+	}
+	if email == "" || (verified != nil && !*verified) {
+		return false
+	}
+	email = strings.ToLower(email)
+	if slices.Contains(o.allowedEmails, email) {
+		return true
+	}
+	if at := strings.LastIndexByte(email, '@'); at >= 0 {
+		return slices.Contains(o.allowedDomains, email[at+1:])
+	}
+	return false
+}
+
+func (o *oidcAuth) session(r *http.Request) (oidcSession, bool) {
+	var sess oidcSession
+	if err := o.readCookie(r, sessionCookieName, &sess); err != nil {
+		return sess, false
+	}
+	return sess, sess.Expiry >= time.Now().Unix()
+}
+
+func (o *oidcAuth) setCookie(w http.ResponseWriter, r *http.Request, name string, v any, ttl time.Duration) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    encoded + "." + o.sign(encoded),
+		Path:     "/",
+		MaxAge:   int(ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+func (o *oidcAuth) readCookie(r *http.Request, name string, v any) error {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return err
+	}
+	encoded, sig, ok := strings.Cut(c.Value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(o.sign(encoded))) {
+		return errInvalidCookie
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return errInvalidCookie
+	}
+	return json.Unmarshal(payload, v)
+}
+
+func (o *oidcAuth) clearCookie(w http.ResponseWriter, r *http.Request, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (o *oidcAuth) sign(data string) string {
+	mac := hmac.New(sha256.New, o.key)
+	mac.Write([]byte(o.host))
+	mac.Write([]byte{0})
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func randomToken() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func lower(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = strings.ToLower(v)
+	}
+	return out
+}
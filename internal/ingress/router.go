@@ -0,0 +1,162 @@
+package ingress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"vinr.eu/vanguard/internal/defs"
+	"vinr.eu/vanguard/internal/errs"
+)
+
+var (
+	ErrInvalidRoute = errors.New("ingress: invalid route")
+)
+
+type Middleware func(http.Handler) http.Handler
+
+type Route struct {
+	Host    string
+	Service string
+	Port    int
+	handler http.Handler
+}
+
+func (rt *Route) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.handler.ServeHTTP(w, r)
+}
+
+type Router struct {
+	routes atomic.Pointer[map[string]*Route]
+}
+
+func NewRouter() *Router {
+	r := &Router{}
+	r.routes.Store(&map[string]*Route{})
+	return r
+}
+
+func (r *Router) Update(ctx context.Context, services map[string]*defs.Service) error {
+	routes := make(map[string]*Route)
+	var routeErrs []error
+	for _, svc := range services {
+		if svc.IngressHost == nil {
+			continue
+		}
+		// A route that fails to build is left out rather than exposed without its auth.
+		route, err := newRoute(ctx, svc)
+		if err != nil {
+			routeErrs = append(routeErrs, errs.WrapMsgErr(ErrInvalidRoute, svc.Name, err))
+			continue
+		}
+		slog.Info("Setting up reverse proxy", "service", svc.Name, "host", route.Host, "port", route.Port)
+		routes[route.Host] = route
+	}
+	r.routes.Store(&routes)
+	return errors.Join(routeErrs...)
+}
+
+func (r *Router) Match(host string) (*Route, bool) {
+	route, ok := (*r.routes.Load())[host]
+	return route, ok
+}
+
+func (r *Router) Hosts() []string {
+	routes := *r.routes.Load()
+	hosts := make([]string, 0, len(routes))
+	for host := range routes {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+func newRoute(ctx context.Context, svc *defs.Service) (*Route, error) {
+	target, err := url.Parse(fmt.Sprintf("http://localhost:%d", svc.Port))
+	if err != nil {
+		return nil, err
+	}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = otelhttp.NewTransport(http.DefaultTransport)
+	proxy.ErrorHandler = proxyError(svc.Name)
+
+	// Declared middlewares run in order ahead of auth so CORS preflights and
+	// rate limits apply to unauthenticated requests as well.
+	var middlewares []Middleware
+	if svc.Ingress != nil {
+		for i, spec := range svc.Ingress.Middlewares {
+			mw, err := newMiddleware(spec)
+			if err != nil {
+				return nil, fmt.Errorf("middlewares[%d]: %w", i, err)
+			}
+			middlewares = append(middlewares, mw)
+		}
+	}
+	if svc.Ingress != nil && svc.Ingress.Auth != nil {
+		auth, err := newAuth(ctx, *svc.IngressHost, svc.Ingress.Auth)
+		if err != nil {
+			return nil, err
+		}
+		middlewares = append(middlewares, auth)
+	}
+
+	return &Route{
+		Host:    *svc.IngressHost,
+		Service: svc.Name,
+		Port:    svc.Port,
+		handler: chain(upstream{proxy: proxy}, middlewares...),
+	}, nil
+}
+
+func proxyError(service string) func(http.ResponseWriter, *http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		status := http.StatusBadGateway
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			status = http.StatusRequestEntityTooLarge
+		case errors.Is(err, context.DeadlineExceeded):
+			status = http.StatusGatewayTimeout
+		}
+		slog.Warn("proxy error", "service", service, "host", r.Host, "status", status, "error", err)
+		w.WriteHeader(status)
+	}
+}
+
+func chain(h http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+type upstream struct {
+	proxy *httputil.ReverseProxy
+}
+
+func (u upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	u.proxy.ServeHTTP(w, r)
+	if stats, ok := r.Context().Value(statsKey{}).(*Stats); ok {
+		stats.UpstreamLatency = time.Since(start)
+	}
+}
+
+type Stats struct {
+	UpstreamLatency time.Duration
+}
+
+type statsKey struct{}
+
+func WithStats(ctx context.Context) (context.Context, *Stats) {
+	stats := &Stats{}
+	return context.WithValue(ctx, statsKey{}, stats), stats
+}
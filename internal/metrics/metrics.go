@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "vanguard"
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Proxied requests by ingress host, service and status class.",
+	}, []string{"ingress", "service", "method", "status_class"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of proxied requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"ingress", "service"})
+
+	toolchainProvision = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "toolchain",
+		Name:      "provision_duration_seconds",
+		Help:      "Time spent provisioning toolchains.",
+		Buckets:   []float64{0.01, 0.1, 1, 5, 15, 30, 60, 120, 300, 600},
+	}, []string{"engine", "version", "result"})
+
+	sourceFetch = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "source",
+		Name:      "fetch_duration_seconds",
+		Help:      "Time spent fetching source archives.",
+		Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"provider", "result"})
+
+	sourceFetchBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "source",
+		Name:      "fetch_bytes_total",
+		Help:      "Bytes downloaded while fetching source archives.",
+	}, []string{"provider"})
+
+	citadelCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "citadel",
+		Name:      "calls_total",
+		Help:      "Citadel API calls by operation and result.",
+	}, []string{"operation", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		toolchainProvision,
+		sourceFetch,
+		sourceFetchBytes,
+		citadelCalls,
+	)
+}
+
+// Handler serves the registry uncompressed, so the admin server can redact
+// the exposition before it is written.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry, DisableCompression: true})
+}
+
+func ObserveRequest(ingress, service, method string, status int, d time.Duration) {
+	httpRequests.WithLabelValues(ingress, service, method, statusClass(status)).Inc()
+	httpDuration.WithLabelValues(ingress, service).Observe(d.Seconds())
+}
+
+func ObserveToolchainProvision(engine, version string, d time.Duration, err error) {
+	toolchainProvision.WithLabelValues(engine, version, result(err)).Observe(d.Seconds())
+}
+
+func ObserveSourceFetch(provider string, d time.Duration, bytes int64, err error) {
+	sourceFetch.WithLabelValues(provider, result(err)).Observe(d.Seconds())
+	sourceFetchBytes.WithLabelValues(provider).Add(float64(bytes))
+}
+
+func ObserveCitadelCall(operation, result string) {
+	citadelCalls.WithLabelValues(operation, result).Inc()
+}
+
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
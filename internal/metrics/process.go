@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// userHZ is the kernel clock tick used in /proc/<pid>/stat; 100 on every
+// platform Go supports.
+const userHZ = 100
+
+type ServiceProcess struct {
+	Service  string
+	PID      int
+	Running  bool
+	Restarts int
+	ExitCode int
+}
+
+var (
+	serviceUpDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "service", "up"),
+		"Whether the service process is running.",
+		[]string{"service"}, nil,
+	)
+	serviceRestartsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "service", "restarts_total"),
+		"Number of times the service process was restarted.",
+		[]string{"service"}, nil,
+	)
+	serviceExitCodeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "service", "last_exit_code"),
+		"Exit code of the last service process exit, -1 if killed by a signal.",
+		[]string{"service"}, nil,
+	)
+	serviceCPUDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "service", "cpu_seconds_total"),
+		"User and system CPU time of the service process.",
+		[]string{"service"}, nil,
+	)
+	serviceRSSDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "service", "resident_memory_bytes"),
+		"Resident memory size of the service process.",
+		[]string{"service"}, nil,
+	)
+)
+
+type processCollector struct {
+	list func() []ServiceProcess
+}
+
+func RegisterServiceProcesses(list func() []ServiceProcess) {
+	Registry.MustRegister(&processCollector{list: list})
+}
+
+func (c *processCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- serviceUpDesc
+	ch <- serviceRestartsDesc
+	ch <- serviceExitCodeDesc
+	ch <- serviceCPUDesc
+	ch <- serviceRSSDesc
+}
+
+func (c *processCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, p := range c.list() {
+		up := 0.0
+		if p.Running {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(serviceUpDesc, prometheus.GaugeValue, up, p.Service)
+		ch <- prometheus.MustNewConstMetric(serviceRestartsDesc, prometheus.CounterValue, float64(p.Restarts), p.Service)
+		ch <- prometheus.MustNewConstMetric(serviceExitCodeDesc, prometheus.GaugeValue, float64(p.ExitCode), p.Service)
+		if !p.Running || p.PID == 0 {
+			continue
+		}
+		if cpu, err := readCPUSeconds(p.PID); err == nil {
+			ch <- prometheus.MustNewConstMetric(serviceCPUDesc, prometheus.CounterValue, cpu, p.Service)
+		}
+		if rss, err := readRSSBytes(p.PID); err == nil {
+			ch <- prometheus.MustNewConstMetric(serviceRSSDesc, prometheus.GaugeValue, rss, p.Service)
+		}
+	}
+}
+
+func readCPUSeconds(pid int) (float64, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+	// The command name may contain spaces, so fields are counted from the
+	// closing parenthesis: state is the first, utime the 12th, stime the 13th.
+	idx := strings.LastIndexByte(string(data), ')')
+	if idx < 0 {
+		return 0, fmt.Errorf("malformed stat for pid %d", pid)
+	}
+	fields := strings.Fields(string(data[idx+1:]))
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("short stat for pid %d", pid)
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return float64(utime+stime) / userHZ, nil
+}
+
+func readRSSBytes(pid int) (float64, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("short statm for pid %d", pid)
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return float64(pages * uint64(os.Getpagesize())), nil
+}
@@ -0,0 +1,107 @@
+package redact
+
+import (
+	"cmp"
+	"encoding/json"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	Mask = "[REDACTED]"
+	// minLength keeps short values such as "1" or "true" from blanking
+	// unrelated output.
+	minLength = 4
+)
+
+// Redactor masks known secret values. Values are also masked in their URL
+// and JSON escaped forms, as they appear in query strings and JSON logs.
+// A nil Redactor masks nothing.
+type Redactor struct {
+	mu       sync.RWMutex
+	values   map[string]struct{}
+	replacer *strings.Replacer
+}
+
+func New() *Redactor {
+	return &Redactor{values: make(map[string]struct{}), replacer: strings.NewReplacer()}
+}
+
+func (r *Redactor) Add(values ...string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	added := false
+	for _, v := range values {
+		if len(v) < minLength {
+			continue
+		}
+		for _, form := range []string{v, url.QueryEscape(v), url.PathEscape(v), jsonEscape(v)} {
+			if _, ok := r.values[form]; !ok {
+				r.values[form] = struct{}{}
+				added = true
+			}
+		}
+	}
+	if added {
+		r.rebuild()
+	}
+}
+
+// rebuild orders values longest first so a secret that contains another is
+// masked as a whole.
+func (r *Redactor) rebuild() {
+	values := make([]string, 0, len(r.values))
+	for v := range r.values {
+		values = append(values, v)
+	}
+	slices.SortFunc(values, func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), strings.Compare(a, b))
+	})
+	pairs := make([]string, 0, 2*len(values))
+	for _, v := range values {
+		pairs = append(pairs, v, Mask)
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	r.mu.RLock()
+	replacer := r.replacer
+	r.mu.RUnlock()
+	return replacer.Replace(s)
+}
+
+func jsonEscape(v string) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	return string(b[1 : len(b)-1])
+}
+
+type writer struct {
+	w io.Writer
+	r *Redactor
+}
+
+// Writer masks secrets in every Write to w. Secrets split across writes are
+// not caught, so it suits writers that receive whole lines, such as logs.
+func Writer(w io.Writer, r *Redactor) io.Writer {
+	return &writer{w: w, r: r}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.r.String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,99 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"vinr.eu/vanguard/internal/aws"
+	"vinr.eu/vanguard/internal/errs"
+)
+
+// AWSSecretsManager resolves Secrets Manager secrets. A leading
+// "region@alias/" segment reads the secret in another region or with the role
+// aliased in AWS_ROLES; either part may be left out, as in
+// "aws/secrets/eu-west-1@prod-ro/db" or "aws/secrets/@shared/db".
+func AWSSecretsManager(pool *aws.Pool[*aws.SecretsManagerClient]) Provider {
+	return ProviderFunc(func(ctx context.Context, path string) (string, error) {
+		region, alias, name := awsTarget(path, pool.HasRole)
+		client, err := pool.Client(ctx, region, alias)
+		if err != nil {
+			return "", errs.WrapMsgErr(ErrNotConfigured, "aws/secrets/"+path, err)
+		}
+		return client.GetSecret(ctx, name)
+	})
+}
+
+// AWSParameterStore resolves SSM parameters. Hierarchical names may be
+// written without their leading slash: "aws/ssm/prod/db" reads "/prod/db".
+// Regions and role aliases work as for AWSSecretsManager.
+func AWSParameterStore(pool *aws.Pool[*aws.SSMClient]) Provider {
+	return ProviderFunc(func(ctx context.Context, path string) (string, error) {
+		region, alias, name := awsTarget(path, pool.HasRole)
+		client, err := pool.Client(ctx, region, alias)
+		if err != nil {
+			return "", errs.WrapMsgErr(ErrNotConfigured, "aws/ssm/"+path, err)
+		}
+		if strings.Contains(name, "/") && !strings.HasPrefix(name, "/") {
+			name = "/" + name
+		}
+		return client.GetParameter(ctx, name)
+	})
+}
+
+var awsRegion = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+
+// awsTarget splits a leading "region@alias" segment off path. As "@" is legal
+// in secret names, the segment is only taken as a target when its region
+// looks like one and its alias is a known role; otherwise it is part of the
+// name.
+func awsTarget(path string, hasRole func(alias string) bool) (region, alias, name string) {
+	first, rest, ok := strings.Cut(path, "/")
+	if !ok {
+		return "", "", path
+	}
+	region, alias, ok = strings.Cut(first, "@")
+	if !ok || region == "" && alias == "" ||
+		region != "" && !awsRegion.MatchString(region) ||
+		alias != "" && !hasRole(alias) {
+		return "", "", path
+	}
+	return region, alias, rest
+}
+
+// Env resolves variables of the vanguard process itself.
+func Env() Provider {
+	return ProviderFunc(func(_ context.Context, name string) (string, error) {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", errs.WrapMsg(ErrNotFound, "env "+name)
+		}
+		return value, nil
+	})
+}
+
+// Files resolves paths relative to root, e.g. mounted Docker or Kubernetes
+// secrets. A single trailing newline is dropped.
+func Files(root string) Provider {
+	return ProviderFunc(func(_ context.Context, name string) (string, error) {
+		// os.Root rejects paths that escape the directory, symlinks included.
+		dir, err := os.OpenRoot(root)
+		if err != nil {
+			return "", errs.WrapMsgErr(ErrFetchFailed, "file "+name, err)
+		}
+		defer dir.Close()
+		data, err := dir.ReadFile(filepath.Clean(name))
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", errs.WrapMsg(ErrNotFound, "file "+name)
+		}
+		if err != nil {
+			return "", errs.WrapMsgErr(ErrFetchFailed, "file "+name, err)
+		}
+		value := strings.TrimSuffix(string(data), "\n")
+		return strings.TrimSuffix(value, "\r"), nil
+	})
+}
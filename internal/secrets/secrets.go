@@ -0,0 +1,159 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"vinr.eu/vanguard/internal/errs"
+)
+
+var (
+	ErrUnknownScheme = errors.New("secrets: unknown scheme")
+	ErrInvalidRef    = errors.New("secrets: invalid ref")
+	ErrNotConfigured = errors.New("secrets: provider not configured")
+	ErrNotFound      = errors.New("secrets: secret not found")
+	ErrFetchFailed   = errors.New("secrets: fetch failed")
+)
+
+// Provider resolves the path part of a ref, i.e. everything after the
+// scheme, to a secret value.
+type Provider interface {
+	Resolve(ctx context.Context, path string) (string, error)
+}
+
+type ProviderFunc func(ctx context.Context, path string) (string, error)
+
+func (f ProviderFunc) Resolve(ctx context.Context, path string) (string, error) {
+	return f(ctx, path)
+}
+
+// Unconfigured stands in for a known backend that lacks its settings, so refs
+// to it fail with a hint rather than as an unknown scheme.
+func Unconfigured(hint string) Provider {
+	return ProviderFunc(func(context.Context, string) (string, error) {
+		return "", errs.WrapMsg(ErrNotConfigured, hint)
+	})
+}
+
+// Registry dispatches refs of the form "<scheme>/<path>" to the provider
+// registered for the scheme. Schemes may contain slashes, as in
+// "aws/secrets"; the longest registered scheme wins.
+type Registry struct {
+	providers map[string]Provider
+	schemes   []string
+	ttl       time.Duration
+	mu        sync.Mutex
+	cache     map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	value   string
+	err     error
+	expires time.Time
+	ready   chan struct{}
+}
+
+func NewRegistry() *Registry {
+	return &Registry{providers: make(map[string]Provider)}
+}
+
+func (r *Registry) Register(scheme string, p Provider) *Registry {
+	scheme = strings.Trim(scheme, "/")
+	if _, ok := r.providers[scheme]; !ok {
+		r.schemes = append(r.schemes, scheme)
+		slices.SortFunc(r.schemes, func(a, b string) int { return len(b) - len(a) })
+	}
+	r.providers[scheme] = p
+	return r
+}
+
+// WithCache keeps resolved values for ttl. Concurrent lookups of the same ref
+// share a single fetch; failures are not cached.
+func (r *Registry) WithCache(ttl time.Duration) *Registry {
+	r.ttl = ttl
+	r.cache = make(map[string]*cacheEntry)
+	return r
+}
+
+func (r *Registry) Schemes() []string {
+	return slices.Sorted(slices.Values(r.schemes))
+}
+
+func (r *Registry) Resolve(ctx context.Context, ref string) (string, error) {
+	if r.ttl <= 0 {
+		return r.fetch(ctx, ref)
+	}
+	r.mu.Lock()
+	e, ok := r.cache[ref]
+	if ok && e.settled() && (e.err != nil || time.Now().After(e.expires)) {
+		ok = false
+	}
+	if !ok {
+		e = &cacheEntry{ready: make(chan struct{})}
+		r.cache[ref] = e
+		r.mu.Unlock()
+		r.settle(ctx, e, ref)
+		return e.value, e.err
+	}
+	r.mu.Unlock()
+	select {
+	case <-e.ready:
+		return e.value, e.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Refresh fetches ref bypassing the cache and caches the result.
+func (r *Registry) Refresh(ctx context.Context, ref string) (string, error) {
+	if r.ttl <= 0 {
+		return r.fetch(ctx, ref)
+	}
+	e := &cacheEntry{ready: make(chan struct{})}
+	r.settle(ctx, e, ref)
+	if e.err == nil {
+		r.mu.Lock()
+		r.cache[ref] = e
+		r.mu.Unlock()
+	}
+	return e.value, e.err
+}
+
+func (r *Registry) settle(ctx context.Context, e *cacheEntry, ref string) {
+	e.value, e.err = r.fetch(ctx, ref)
+	e.expires = time.Now().Add(r.ttl)
+	close(e.ready)
+}
+
+func (e *cacheEntry) settled() bool {
+	select {
+	case <-e.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *Registry) fetch(ctx context.Context, ref string) (string, error) {
+	scheme, path, err := r.split(ref)
+	if err != nil {
+		return "", err
+	}
+	return r.providers[scheme].Resolve(ctx, path)
+}
+
+func (r *Registry) split(ref string) (scheme, path string, err error) {
+	for _, s := range r.schemes {
+		if rest, ok := strings.CutPrefix(ref, s+"/"); ok {
+			if rest == "" {
+				return "", "", errs.WrapMsg(ErrInvalidRef, ref+": empty path")
+			}
+			return s, rest, nil
+		}
+	}
+	return "", "", errs.WrapMsg(ErrUnknownScheme, ref+" (known: "+strings.Join(r.Schemes(), ", ")+")")
+}
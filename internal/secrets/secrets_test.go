@@ -0,0 +1,127 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegistrySplit(t *testing.T) {
+	none := Unconfigured("test")
+	r := NewRegistry().
+		Register("aws", none).
+		Register("aws/secrets", none).
+		Register("/aws/ssm/", none).
+		Register("vault", none)
+	tests := []struct {
+		ref    string
+		scheme string
+		path   string
+		err    error
+	}{
+		{ref: "aws/secrets/db", scheme: "aws/secrets", path: "db"},
+		{ref: "aws/secrets/eu-west-1@prod/db", scheme: "aws/secrets", path: "eu-west-1@prod/db"},
+		{ref: "aws/ssm/prod/db", scheme: "aws/ssm", path: "prod/db"},
+		{ref: "aws/secretsdb", scheme: "aws", path: "secretsdb"},
+		{ref: "aws/other/db", scheme: "aws", path: "other/db"},
+		{ref: "vault/secret/app#password", scheme: "vault", path: "secret/app#password"},
+		{ref: "aws/secrets/", err: ErrInvalidRef},
+		{ref: "vault/", err: ErrInvalidRef},
+		{ref: "vault", err: ErrUnknownScheme},
+		{ref: "vaults/secret/app", err: ErrUnknownScheme},
+		{ref: "gcp/db", err: ErrUnknownScheme},
+		{ref: "", err: ErrUnknownScheme},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			scheme, path, err := r.split(tt.ref)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if scheme != tt.scheme || path != tt.path {
+				t.Errorf("split = %q, %q, want %q, %q", scheme, path, tt.scheme, tt.path)
+			}
+		})
+	}
+	t.Run("lists known schemes", func(t *testing.T) {
+		_, _, err := r.split("gcp/db")
+		if want := "(known: aws, aws/secrets, aws/ssm, vault)"; err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want it to contain %q", err, want)
+		}
+	})
+}
+
+func TestFilesStaysInRoot(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "secrets")
+	if err := os.MkdirAll(filepath.Join(root, "db"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(root, "db", "password"), "hunter2\r\n")
+	write(filepath.Join(dir, "outside"), "leaked")
+	if err := os.Symlink(filepath.Join(dir, "outside"), filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	files := Files(root)
+	ctx := context.Background()
+	value, err := files.Resolve(ctx, "db/password")
+	if err != nil || value != "hunter2" {
+		t.Fatalf("Resolve(db/password) = %q, %v", value, err)
+	}
+	if _, err := files.Resolve(ctx, "db/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Resolve(db/missing) err = %v, want ErrNotFound", err)
+	}
+	for _, name := range []string{"../outside", "db/../../outside", "/etc/hostname", "link"} {
+		t.Run(name, func(t *testing.T) {
+			value, err := files.Resolve(ctx, name)
+			if err == nil || value != "" {
+				t.Fatalf("Resolve(%q) = %q, %v, want it rejected", name, value, err)
+			}
+		})
+	}
+}
+
+func TestAWSTarget(t *testing.T) {
+	hasRole := func(alias string) bool { return alias == "prod-ro" || alias == "shared" }
+	tests := []struct {
+		path                string
+		region, alias, name string
+	}{
+		{path: "db", name: "db"},
+		{path: "prod/db", name: "prod/db"},
+		{path: "eu-west-1@prod-ro/db", region: "eu-west-1", alias: "prod-ro", name: "db"},
+		{path: "us-gov-west-1@/db", region: "us-gov-west-1", name: "db"},
+		{path: "@shared/team/db", alias: "shared", name: "team/db"},
+		// "@" is legal in secret names.
+		{path: "alice@example.com/token", name: "alice@example.com/token"},
+		{path: "@unknown/db", name: "@unknown/db"},
+		{path: "eu-west-1@unknown/db", name: "eu-west-1@unknown/db"},
+		{path: "team@/db", name: "team@/db"},
+		{path: "@/db", name: "@/db"},
+		{path: "eu-west-1@prod-ro", name: "eu-west-1@prod-ro"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			region, alias, name := awsTarget(tt.path, hasRole)
+			if region != tt.region || alias != tt.alias || name != tt.name {
+				t.Errorf("awsTarget = %q, %q, %q, want %q, %q, %q", region, alias, name, tt.region, tt.alias, tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"vinr.eu/vanguard/internal/errs"
+)
+
+type VaultOptions struct {
+	Addr      string
+	Token     string
+	Namespace string
+	Timeout   time.Duration
+}
+
+type vault struct {
+	opts   VaultOptions
+	client *http.Client
+}
+
+// Vault resolves "<mount>/<path>" from a KV version 2 engine, e.g.
+// "vault/secret/myapp/db" reads secret/data/myapp/db. A secret with a single
+// "value" key resolves to that value; any other secret to its data as a JSON
+// object.
+func Vault(opts VaultOptions) Provider {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	v := &vault{opts: opts, client: &http.Client{Timeout: opts.Timeout}}
+	return ProviderFunc(v.resolve)
+}
+
+type vaultKVResponse struct {
+	Data struct {
+		Data map[string]any `json:"data"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+func (v *vault) resolve(ctx context.Context, ref string) (string, error) {
+	mount, path, ok := strings.Cut(ref, "/")
+	if !ok || path == "" {
+		return "", errs.WrapMsg(ErrInvalidRef, "vault ref must be <mount>/<path>: "+ref)
+	}
+	endpoint, err := url.JoinPath(v.opts.Addr, "v1", mount, "data", path)
+	if err != nil {
+		return "", errs.WrapMsgErr(ErrInvalidRef, ref, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", errs.WrapMsgErr(ErrFetchFailed, "vault "+ref, err)
+	}
+	req.Header.Set("X-Vault-Token", v.opts.Token)
+	if v.opts.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.opts.Namespace)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", errs.WrapMsgErr(ErrFetchFailed, "vault "+ref, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", errs.WrapMsgErr(ErrFetchFailed, "vault "+ref, err)
+	}
+	var out vaultKVResponse
+	_ = json.Unmarshal(body, &out)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", errs.WrapMsg(ErrNotFound, "vault "+ref)
+	case resp.StatusCode != http.StatusOK:
+		return "", errs.WrapMsg(ErrFetchFailed, fmt.Sprintf("vault %s: status %d: %s", ref, resp.StatusCode, strings.Join(out.Errors, "; ")))
+	case out.Data.Data == nil:
+		// Deleted or destroyed versions come back with null data.
+		return "", errs.WrapMsg(ErrNotFound, "vault "+ref)
+	}
+	if value, ok := out.Data.Data["value"]; ok && len(out.Data.Data) == 1 {
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	}
+	data, err := json.Marshal(out.Data.Data)
+	if err != nil {
+		return "", errs.WrapMsgErr(ErrFetchFailed, "vault "+ref, err)
+	}
+	return string(data), nil
+}
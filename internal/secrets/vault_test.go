@@ -0,0 +1,105 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestVaultKV runs against a real Vault with a KV version 2 engine mounted at
+// "secret", as in `vault server -dev`. It is skipped unless VAULT_ADDR and
+// VAULT_TOKEN are set.
+func TestVaultKV(t *testing.T) {
+	opts := VaultOptions{
+		Addr:      os.Getenv("VAULT_ADDR"),
+		Token:     os.Getenv("VAULT_TOKEN"),
+		Namespace: os.Getenv("VAULT_NAMESPACE"),
+	}
+	if opts.Addr == "" || opts.Token == "" {
+		t.Skip("VAULT_ADDR and VAULT_TOKEN are not set")
+	}
+	base := fmt.Sprintf("vanguard-test/%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		for _, name := range []string{"single", "object", "deleted"} {
+			vaultRequest(t, opts, http.MethodDelete, "secret/metadata/"+base+"/"+name, nil)
+		}
+	})
+	vaultRequest(t, opts, http.MethodPost, "secret/data/"+base+"/single", map[string]any{"value": "s3cr3t"})
+	vaultRequest(t, opts, http.MethodPost, "secret/data/"+base+"/object", map[string]any{"username": "app", "password": "pw"})
+	vaultRequest(t, opts, http.MethodPost, "secret/data/"+base+"/deleted", map[string]any{"value": "gone"})
+	vaultRequest(t, opts, http.MethodDelete, "secret/data/"+base+"/deleted", nil)
+
+	v := Vault(opts)
+	ctx := context.Background()
+	t.Run("single value", func(t *testing.T) {
+		got, err := v.Resolve(ctx, "secret/"+base+"/single")
+		if err != nil || got != "s3cr3t" {
+			t.Fatalf("Resolve = %q, %v", got, err)
+		}
+	})
+	t.Run("object", func(t *testing.T) {
+		got, err := v.Resolve(ctx, "secret/"+base+"/object")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var data map[string]string
+		if err := json.Unmarshal([]byte(got), &data); err != nil || data["username"] != "app" || data["password"] != "pw" {
+			t.Fatalf("Resolve = %q, want the secret as a JSON object", got)
+		}
+	})
+	t.Run("missing", func(t *testing.T) {
+		if _, err := v.Resolve(ctx, "secret/"+base+"/missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("err = %v, want ErrNotFound", err)
+		}
+	})
+	t.Run("deleted version", func(t *testing.T) {
+		if _, err := v.Resolve(ctx, "secret/"+base+"/deleted"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("err = %v, want ErrNotFound", err)
+		}
+	})
+	t.Run("bad token", func(t *testing.T) {
+		bad := opts
+		bad.Token = "not-a-token"
+		if _, err := Vault(bad).Resolve(ctx, "secret/"+base+"/single"); !errors.Is(err, ErrFetchFailed) {
+			t.Fatalf("err = %v, want ErrFetchFailed", err)
+		}
+	})
+}
+
+// vaultRequest calls the Vault HTTP API directly, wrapping data for KV writes.
+func vaultRequest(t *testing.T, opts VaultOptions, method, path string, data map[string]any) {
+	t.Helper()
+	var body bytes.Buffer
+	if data != nil {
+		if err := json.NewEncoder(&body).Encode(map[string]any{"data": data}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	endpoint, err := url.JoinPath(opts.Addr, "v1", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(method, endpoint, &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Vault-Token", opts.Token)
+	if opts.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", opts.Namespace)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		t.Fatalf("vault %s %s: %s", method, path, resp.Status)
+	}
+}
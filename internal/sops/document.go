@@ -0,0 +1,242 @@
+package sops
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age/armor"
+	"github.com/goccy/go-yaml"
+	"vinr.eu/vanguard/internal/errs"
+)
+
+// macOnlyEncryptedInit seeds the MAC of documents written with
+// mac_only_encrypted, as sops does.
+var macOnlyEncryptedInit = []byte{0x8a, 0x3f, 0xd2, 0xad, 0x54, 0xce, 0x66, 0x52, 0x7b, 0x10, 0x34, 0xf3, 0xd1, 0x47, 0xbe, 0xb, 0xb, 0x97, 0x5b, 0x3b, 0xf4, 0x4f, 0x72, 0xc6, 0xfd, 0xad, 0xec, 0x81, 0x76, 0xf2, 0x7d, 0x69}
+
+var encValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.+),iv:(.+),tag:(.+),type:(.+)\]`)
+
+type metadata struct {
+	Age []struct {
+		Recipient string `yaml:"recipient"`
+		Enc       string `yaml:"enc"`
+	} `yaml:"age"`
+	LastModified     string `yaml:"lastmodified"`
+	MAC              string `yaml:"mac"`
+	MACOnlyEncrypted bool   `yaml:"mac_only_encrypted"`
+}
+
+func parseMetadata(data []byte) *metadata {
+	var doc struct {
+		Sops *metadata `yaml:"sops"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil || doc.Sops == nil || doc.Sops.MAC == "" {
+		return nil
+	}
+	return doc.Sops
+}
+
+// IsDocument reports whether data is a YAML or JSON document encrypted by
+// sops.
+func IsDocument(data []byte) bool {
+	return parseMetadata(data) != nil
+}
+
+// Document decrypts a SOPS document and returns its data, without the sops
+// metadata, as JSON along with the decrypted values. Only age recipients are
+// supported. Any other data is returned unchanged.
+func (d *Decrypter) Document(data []byte) ([]byte, []string, error) {
+	meta := parseMetadata(data)
+	if meta == nil {
+		return data, nil, nil
+	}
+	key, err := d.dataKey(meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	var tree yaml.MapSlice
+	if err := yaml.UnmarshalWithOptions(data, &tree, yaml.UseOrderedMap()); err != nil {
+		return nil, nil, errs.Wrap(ErrDecryptFailed, err)
+	}
+	w := &walker{key: key, macOnlyEncrypted: meta.MACOnlyEncrypted, hash: sha512.New()}
+	if meta.MACOnlyEncrypted {
+		w.hash.Write(macOnlyEncryptedInit)
+	}
+	out := make(map[string]any, len(tree))
+	for _, item := range tree {
+		name := fmt.Sprint(item.Key)
+		if name == "sops" {
+			continue
+		}
+		if out[name], err = w.walk(item.Value, []string{name}); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := w.verify(meta); err != nil {
+		return nil, nil, err
+	}
+	plain, err := json.Marshal(out)
+	if err != nil {
+		return nil, nil, errs.Wrap(ErrDecryptFailed, err)
+	}
+	return plain, w.values, nil
+}
+
+func (d *Decrypter) dataKey(meta *metadata) ([]byte, error) {
+	if len(meta.Age) == 0 {
+		return nil, errs.WrapMsg(ErrDecryptFailed, "document has no age recipients")
+	}
+	var lastErr error
+	for _, r := range meta.Age {
+		key, err := d.open(armor.NewReader(strings.NewReader(strings.TrimSpace(r.Enc))))
+		if err == nil {
+			return key, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+type walker struct {
+	key              []byte
+	macOnlyEncrypted bool
+	hash             hash.Hash
+	values           []string
+}
+
+// walk decrypts the leaves of a tree in document order. The additional data
+// of each value is its path of map keys; list indices are not part of it.
+func (w *walker) walk(in any, path []string) (any, error) {
+	switch v := in.(type) {
+	case yaml.MapSlice:
+		out := make(map[string]any, len(v))
+		for _, item := range v {
+			name := fmt.Sprint(item.Key)
+			value, err := w.walk(item.Value, append(path[:len(path):len(path)], name))
+			if err != nil {
+				return nil, err
+			}
+			out[name] = value
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			value, err := w.walk(item, path)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = value
+		}
+		return out, nil
+	case nil:
+		return nil, nil
+	case string:
+		if encValue.MatchString(v) {
+			return w.decrypt(v, strings.Join(path, ":")+":")
+		}
+	}
+	if !w.macOnlyEncrypted {
+		b, err := macBytes(in)
+		if err != nil {
+			return nil, errs.WrapMsgErr(ErrDecryptFailed, strings.Join(path, "."), err)
+		}
+		w.hash.Write(b)
+	}
+	return in, nil
+}
+
+func (w *walker) decrypt(value, aad string) (any, error) {
+	plain, typ, err := open(value, w.key, aad)
+	if err != nil {
+		return nil, err
+	}
+	w.hash.Write(plain)
+	w.values = append(w.values, string(plain))
+	switch typ {
+	case "str", "bytes", "time":
+		return string(plain), nil
+	case "int":
+		return strconv.Atoi(string(plain))
+	case "float":
+		return strconv.ParseFloat(string(plain), 64)
+	case "bool":
+		return strconv.ParseBool(string(plain))
+	default:
+		return nil, errs.WrapMsg(ErrDecryptFailed, "unknown type "+typ)
+	}
+}
+
+// verify checks the MAC over all values, which is itself encrypted with the
+// last modification time as additional data.
+func (w *walker) verify(meta *metadata) error {
+	lastModified, err := time.Parse(time.RFC3339, meta.LastModified)
+	if err != nil {
+		return errs.WrapMsgErr(ErrDecryptFailed, "lastmodified", err)
+	}
+	mac, _, err := open(meta.MAC, w.key, lastModified.Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(string(mac), fmt.Sprintf("%X", w.hash.Sum(nil))) {
+		return ErrMACMismatch
+	}
+	return nil
+}
+
+func open(value string, key []byte, aad string) ([]byte, string, error) {
+	m := encValue.FindStringSubmatch(value)
+	if m == nil {
+		return nil, "", errs.WrapMsg(ErrDecryptFailed, "malformed value")
+	}
+	var parts [3][]byte
+	for i, s := range m[1:4] {
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, "", errs.Wrap(ErrDecryptFailed, err)
+		}
+		parts[i] = b
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, "", errs.Wrap(ErrDecryptFailed, err)
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(parts[1]))
+	if err != nil {
+		return nil, "", errs.Wrap(ErrDecryptFailed, err)
+	}
+	plain, err := gcm.Open(nil, parts[1], append(parts[0], parts[2]...), []byte(aad))
+	if err != nil {
+		return nil, "", errs.Wrap(ErrDecryptFailed, err)
+	}
+	return plain, m[4], nil
+}
+
+// macBytes renders a plaintext value the way sops feeds it into the MAC.
+func macBytes(v any) ([]byte, error) {
+	switch v := v.(type) {
+	case string:
+		return []byte(v), nil
+	case bool:
+		if v {
+			return []byte("True"), nil
+		}
+		return []byte("False"), nil
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64)), nil
+	case int, int64, uint64:
+		return []byte(fmt.Sprint(v)), nil
+	case time.Time:
+		return v.MarshalText()
+	default:
+		return nil, fmt.Errorf("unsupported type %T", v)
+	}
+}
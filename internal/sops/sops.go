@@ -0,0 +1,132 @@
+package sops
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"vinr.eu/vanguard/internal/errs"
+	"vinr.eu/vanguard/internal/secrets"
+)
+
+var (
+	ErrNoIdentity    = errors.New("sops: no age identity configured")
+	ErrInvalidKey    = errors.New("sops: invalid age key")
+	ErrDecryptFailed = errors.New("sops: decrypt failed")
+	ErrMACMismatch   = errors.New("sops: MAC mismatch")
+)
+
+const ageHeader = "-----BEGIN AGE ENCRYPTED FILE-----"
+
+// Decrypter opens age-encrypted values and SOPS documents with a set of age
+// identities.
+type Decrypter struct {
+	identities []age.Identity
+}
+
+func NewDecrypter(identities ...age.Identity) *Decrypter {
+	return &Decrypter{identities: identities}
+}
+
+// LoadDecrypter reads identities from key, the contents of a key file as
+// written by age-keygen, and from keyFile. A missing key file is not an error,
+// so nodes without encrypted definitions need no key at all.
+func LoadDecrypter(key, keyFile string) (*Decrypter, error) {
+	d := NewDecrypter()
+	if key != "" {
+		ids, err := age.ParseIdentities(strings.NewReader(key))
+		if err != nil {
+			return nil, errs.WrapMsgErr(ErrInvalidKey, "inline key", err)
+		}
+		d.identities = append(d.identities, ids...)
+	}
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return nil, errs.WrapMsgErr(ErrInvalidKey, keyFile, err)
+		default:
+			ids, err := age.ParseIdentities(bytes.NewReader(data))
+			if err != nil {
+				return nil, errs.WrapMsgErr(ErrInvalidKey, keyFile, err)
+			}
+			d.identities = append(d.identities, ids...)
+		}
+	}
+	return d, nil
+}
+
+// IsEncrypted reports whether value is an ASCII-armored age ciphertext.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(strings.TrimSpace(value), ageHeader)
+}
+
+// Value decrypts an ASCII-armored age ciphertext; anything else is returned
+// as is.
+func (d *Decrypter) Value(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	plain, err := d.open(armor.NewReader(strings.NewReader(strings.TrimSpace(value))))
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func (d *Decrypter) open(r io.Reader) ([]byte, error) {
+	if len(d.identities) == 0 {
+		return nil, errs.WrapMsg(ErrNoIdentity, "set SOPS_AGE_KEY or SOPS_AGE_KEY_FILE")
+	}
+	dec, err := age.Decrypt(r, d.identities...)
+	if err != nil {
+		return nil, errs.Wrap(ErrDecryptFailed, err)
+	}
+	plain, err := io.ReadAll(dec)
+	if err != nil {
+		return nil, errs.Wrap(ErrDecryptFailed, err)
+	}
+	return plain, nil
+}
+
+// Files resolves paths relative to root to the contents of encrypted sidecar
+// files: a SOPS document resolves to its data as a JSON object, an armored or
+// binary age file to its plaintext with a single trailing newline dropped.
+func (d *Decrypter) Files(root string) secrets.Provider {
+	return secrets.ProviderFunc(func(_ context.Context, name string) (string, error) {
+		dir, err := os.OpenRoot(root)
+		if err != nil {
+			return "", errs.WrapMsgErr(secrets.ErrFetchFailed, "sidecar "+name, err)
+		}
+		defer dir.Close()
+		data, err := dir.ReadFile(filepath.Clean(name))
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", errs.WrapMsg(secrets.ErrNotFound, "sidecar "+name)
+		}
+		if err != nil {
+			return "", errs.WrapMsgErr(secrets.ErrFetchFailed, "sidecar "+name, err)
+		}
+		var plain []byte
+		switch {
+		case IsDocument(data):
+			plain, _, err = d.Document(data)
+		case IsEncrypted(string(data)):
+			plain, err = d.open(armor.NewReader(bytes.NewReader(bytes.TrimSpace(data))))
+		default:
+			plain, err = d.open(bytes.NewReader(data))
+		}
+		if err != nil {
+			return "", errs.WrapMsgErr(secrets.ErrFetchFailed, "sidecar "+name, err)
+		}
+		value := strings.TrimSuffix(string(plain), "\n")
+		return strings.TrimSuffix(value, "\r"), nil
+	})
+}
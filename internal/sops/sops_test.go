@@ -0,0 +1,239 @@
+package sops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// The files in testdata were written by the sops and age CLIs for the
+// identity in testdata/key.txt:
+//
+//	printf 's3cr3t-inline' | age -r $R -a > inline.age
+//	printf 'binary-secret\n' | age -r $R -o token.age
+//	sops encrypt --age $R secrets.yaml > secrets.enc.yaml
+//	sops encrypt --age $R secrets.json > secrets.enc.json
+//	sops encrypt --age $R service.yaml > service.enc.yaml
+//	sops encrypt --age $R --encrypted-regex '^value$' partial.yaml > partial.enc.yaml
+//
+// maconly.enc.yaml is partial.yaml encrypted with mac_only_encrypted set in
+// .sops.yaml.
+
+func testDecrypter(t *testing.T) *Decrypter {
+	t.Helper()
+	d, err := LoadDecrypter("", filepath.Join("testdata", "key.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// decodeDocument decrypts a fixture and decodes it keeping number literals.
+func decodeDocument(t *testing.T, d *Decrypter, data []byte) (map[string]any, []string) {
+	t.Helper()
+	plain, values, err := d.Document(data)
+	if err != nil {
+		t.Fatalf("Document: %v", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(plain))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc, values
+}
+
+func TestValue(t *testing.T) {
+	d := testDecrypter(t)
+	inline := string(readTestdata(t, "inline.age"))
+	if !IsEncrypted(inline) {
+		t.Fatal("inline.age not detected as encrypted")
+	}
+	got, err := d.Value(inline)
+	if err != nil || got != "s3cr3t-inline" {
+		t.Fatalf("Value = %q, %v", got, err)
+	}
+	if got, err := d.Value("plain"); err != nil || got != "plain" {
+		t.Errorf("Value(plain) = %q, %v", got, err)
+	}
+	if _, err := NewDecrypter().Value(inline); !errors.Is(err, ErrNoIdentity) {
+		t.Errorf("err = %v, want ErrNoIdentity", err)
+	}
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewDecrypter(other).Value(inline); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("err = %v, want ErrDecryptFailed for another identity", err)
+	}
+}
+
+func TestFilesSidecars(t *testing.T) {
+	files := testDecrypter(t).Files("testdata")
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"secrets.enc.yaml", `{"api_token":"tok-123","db":{"password":"hunter2","username":"app"}}`},
+		{"secrets.enc.json", `{"nested":{"key":"v"},"token":"json-token"}`},
+		{"inline.age", "s3cr3t-inline"},
+		{"token.age", "binary-secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := files.Resolve(ctx, tt.name)
+			if err != nil || got != tt.want {
+				t.Fatalf("Resolve = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentFullyEncrypted(t *testing.T) {
+	doc, values := decodeDocument(t, testDecrypter(t), readTestdata(t, "service.enc.yaml"))
+	if doc["name"] != "billing" || doc["port"] != json.Number("3005") {
+		t.Errorf("name, port = %v, %v", doc["name"], doc["port"])
+	}
+	if _, ok := doc["sops"]; ok {
+		t.Error("sops metadata left in the document")
+	}
+	vars := doc["variables"].([]any)
+	if v := vars[0].(map[string]any)["value"]; v != "hunter2" {
+		t.Errorf("variables[0].value = %v", v)
+	}
+	typed := doc["typed"].(map[string]any)
+	want := map[string]any{
+		"count":   json.Number("42"),
+		"enabled": true,
+		"ratio":   json.Number("0.75"),
+		"label":   "plain",
+	}
+	for k, v := range want {
+		if typed[k] != v {
+			t.Errorf("typed.%s = %#v, want %#v", k, typed[k], v)
+		}
+	}
+	if !slices.Contains(values, "hunter2") {
+		t.Errorf("values = %q, want the decrypted secrets", values)
+	}
+}
+
+func TestDocumentPartiallyEncrypted(t *testing.T) {
+	d := testDecrypter(t)
+	for _, name := range []string{"partial.enc.yaml", "maconly.enc.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			doc, values := decodeDocument(t, d, readTestdata(t, name))
+			vars := doc["variables"].([]any)
+			if v := vars[1].(map[string]any)["value"]; v != "debug" {
+				t.Errorf("variables[1].value = %v", v)
+			}
+			if doc["port"] != json.Number("3006") {
+				t.Errorf("port = %v", doc["port"])
+			}
+			if !slices.Equal(values, []string{"hunter2", "debug"}) {
+				t.Errorf("values = %q", values)
+			}
+		})
+	}
+}
+
+func TestDocumentTampered(t *testing.T) {
+	d := testDecrypter(t)
+	firstData := regexp.MustCompile(`data:([A-Za-z0-9+/])`)
+	tests := []struct {
+		name   string
+		file   string
+		tamper func(string) string
+		err    error
+	}{
+		{
+			name: "ciphertext",
+			file: "service.enc.yaml",
+			tamper: func(s string) string {
+				loc := firstData.FindStringSubmatchIndex(s)
+				c := "A"
+				if s[loc[2]] == 'A' {
+					c = "B"
+				}
+				return s[:loc[2]] + c + s[loc[3]:]
+			},
+			err: ErrDecryptFailed,
+		},
+		{
+			name:   "unencrypted value",
+			file:   "partial.enc.yaml",
+			tamper: func(s string) string { return strings.Replace(s, "port: 3006", "port: 3007", 1) },
+			err:    ErrMACMismatch,
+		},
+		{
+			name: "mac",
+			file: "secrets.enc.yaml",
+			tamper: func(s string) string {
+				return regexp.MustCompile(`lastmodified: "[^"]+"`).ReplaceAllString(s, `lastmodified: "2001-01-01T00:00:00Z"`)
+			},
+			err: ErrDecryptFailed,
+		},
+		{
+			// List indices are not part of the additional data, so only the
+			// MAC catches reordered values.
+			name: "swapped list values",
+			file: "partial.enc.yaml",
+			tamper: func(s string) string {
+				values := regexp.MustCompile(`ENC\[[^\]]+\]`).FindAllString(s, 2)
+				s = strings.Replace(s, values[0], "SWAP", 1)
+				s = strings.Replace(s, values[1], values[0], 1)
+				return strings.Replace(s, "SWAP", values[1], 1)
+			},
+			err: ErrMACMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.tamper(string(readTestdata(t, tt.file)))
+			if data == string(readTestdata(t, tt.file)) {
+				t.Fatal("fixture not tampered")
+			}
+			if _, _, err := d.Document([]byte(data)); !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+	t.Run("mac only covers encrypted values", func(t *testing.T) {
+		data := strings.Replace(string(readTestdata(t, "maconly.enc.yaml")), "port: 3006", "port: 3007", 1)
+		doc, _ := decodeDocument(t, d, []byte(data))
+		if doc["port"] != json.Number("3007") {
+			t.Errorf("port = %v", doc["port"])
+		}
+	})
+}
+
+func TestDocumentPassthrough(t *testing.T) {
+	data := []byte("kind: Service\nname: plain\n")
+	if IsDocument(data) {
+		t.Fatal("plain YAML detected as a sops document")
+	}
+	got, values, err := NewDecrypter().Document(data)
+	if err != nil || !bytes.Equal(got, data) || values != nil {
+		t.Fatalf("Document = %q, %q, %v", got, values, err)
+	}
+}
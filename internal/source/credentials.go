@@ -0,0 +1,192 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+var (
+	ErrNoToken = errors.New("source: no token found")
+)
+
+const githubHost = "github.com"
+
+// ChainTokenProvider returns the token of the first provider that has one.
+// Providers report ErrNoToken to pass on to the next one; other errors are
+// collected and only returned when no provider succeeds.
+func ChainTokenProvider(providers ...TokenProvider) TokenProvider {
+	return func(ctx context.Context) (Token, error) {
+		var chainErrs []error
+		for _, p := range providers {
+			token, err := p(ctx)
+			if err == nil && token.Value != "" {
+				return token, nil
+			}
+			if err != nil && !errors.Is(err, ErrNoToken) {
+				chainErrs = append(chainErrs, err)
+			}
+		}
+		if len(chainErrs) > 0 {
+			return Token{}, errors.Join(chainErrs...)
+		}
+		return Token{}, ErrNoToken
+	}
+}
+
+// EnvTokenProvider reads the first non-empty of the given variables.
+func EnvTokenProvider(vars ...string) TokenProvider {
+	return func(ctx context.Context) (Token, error) {
+		for _, v := range vars {
+			if value := os.Getenv(v); value != "" {
+				slog.DebugContext(ctx, "using github token from environment", "var", v)
+				return Token{Value: value}, nil
+			}
+		}
+		return Token{}, ErrNoToken
+	}
+}
+
+// GHCLITokenProvider reads the token the gh CLI stored for github.com. When gh
+// keeps it in the system keyring instead, it asks gh for it.
+func GHCLITokenProvider() TokenProvider {
+	return func(ctx context.Context) (Token, error) {
+		dir := os.Getenv("GH_CONFIG_DIR")
+		if dir == "" {
+			base := os.Getenv("XDG_CONFIG_HOME")
+			if base == "" {
+				home, err := os.UserHomeDir()
+				if err != nil {
+					return Token{}, ErrNoToken
+				}
+				base = filepath.Join(home, ".config")
+			}
+			dir = filepath.Join(base, "gh")
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "hosts.yml"))
+		if err != nil {
+			return Token{}, ErrNoToken
+		}
+		var hosts map[string]struct {
+			OAuthToken string `yaml:"oauth_token"`
+		}
+		if err := yaml.Unmarshal(data, &hosts); err != nil {
+			return Token{}, fmt.Errorf("gh hosts.yml: %w", err)
+		}
+		host, ok := hosts[githubHost]
+		if !ok {
+			return Token{}, ErrNoToken
+		}
+		if host.OAuthToken != "" {
+			slog.DebugContext(ctx, "using github token from gh hosts file")
+			return Token{Value: host.OAuthToken}, nil
+		}
+		if _, err := exec.LookPath("gh"); err != nil {
+			return Token{}, ErrNoToken
+		}
+		out, err := exec.CommandContext(ctx, "gh", "auth", "token", "--hostname", githubHost).Output()
+		if err != nil {
+			return Token{}, ErrNoToken
+		}
+		slog.DebugContext(ctx, "using github token from gh keyring")
+		return Token{Value: strings.TrimSpace(string(out))}, nil
+	}
+}
+
+// GitCredentialTokenProvider asks the configured git credential helpers for
+// github.com, without ever prompting.
+func GitCredentialTokenProvider() TokenProvider {
+	return func(ctx context.Context) (Token, error) {
+		if _, err := exec.LookPath("git"); err != nil {
+			return Token{}, ErrNoToken
+		}
+		cmd := exec.CommandContext(ctx, "git", "credential", "fill")
+		cmd.Stdin = strings.NewReader("protocol=https\nhost=" + githubHost + "\n\n")
+		cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never", "GIT_ASKPASS=", "SSH_ASKPASS=")
+		out, err := cmd.Output()
+		if err != nil {
+			return Token{}, ErrNoToken
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(out))
+		for scanner.Scan() {
+			if password, ok := strings.CutPrefix(scanner.Text(), "password="); ok && password != "" {
+				slog.DebugContext(ctx, "using github token from git credential helper")
+				return Token{Value: password}, nil
+			}
+		}
+		return Token{}, ErrNoToken
+	}
+}
+
+// NetrcTokenProvider reads the password of the github.com (or default)
+// machine entry in $NETRC or ~/.netrc.
+func NetrcTokenProvider() TokenProvider {
+	return func(ctx context.Context) (Token, error) {
+		path := os.Getenv("NETRC")
+		if path == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return Token{}, ErrNoToken
+			}
+			path = filepath.Join(home, ".netrc")
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Token{}, ErrNoToken
+		}
+		if password := netrcPassword(string(data), githubHost); password != "" {
+			slog.DebugContext(ctx, "using github token from netrc", "path", path)
+			return Token{Value: password}, nil
+		}
+		return Token{}, ErrNoToken
+	}
+}
+
+func netrcPassword(data, host string) string {
+	fields := strings.Fields(data)
+	var machine, fallback string
+	inMachine, inDefault := false, false
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			if i+1 < len(fields) {
+				i++
+				inMachine, inDefault = fields[i] == host, false
+			}
+		case "default":
+			inMachine, inDefault = false, true
+		case "macdef":
+			// Macro bodies run to the next blank line, which Fields cannot see;
+			// stop instead of misreading them.
+			return firstNonEmpty(machine, fallback)
+		case "password":
+			if i+1 < len(fields) {
+				i++
+				if inMachine && machine == "" {
+					machine = fields[i]
+				} else if inDefault && fallback == "" {
+					fallback = fields[i]
+				}
+			}
+		}
+	}
+	return firstNonEmpty(machine, fallback)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
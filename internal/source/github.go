@@ -12,10 +12,12 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v69/github"
 	"golang.org/x/oauth2"
 	"vinr.eu/vanguard/internal/errs"
+	"vinr.eu/vanguard/internal/metrics"
 )
 
 var (
@@ -42,41 +44,57 @@ func NewGitHubSource(repoURL, branch string, tp TokenProvider) *GitHubSource {
 	}
 }
 
-func (s *GitHubSource) Fetch(ctx context.Context, dest string) error {
+func (s *GitHubSource) Fetch(ctx context.Context, dest string) (commit string, err error) {
+	started := time.Now()
+	body := &countingReader{}
+	defer func() {
+		metrics.ObserveSourceFetch("github", time.Since(started), body.n, err)
+	}()
+
 	token, err := s.tokenProvider(ctx)
-	if err != nil {
-		return errs.Wrap(ErrAuthFailed, err)
+	if err != nil && !errors.Is(err, ErrNoToken) {
+		return "", errs.Wrap(ErrAuthFailed, err)
 	}
 
 	owner, repo, err := s.parseRepoURL()
 	if err != nil {
-		return errs.Wrap(ErrRepoInvalid, err)
+		return "", errs.Wrap(ErrRepoInvalid, err)
 	}
 
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	tc := oauth2.NewClient(ctx, ts)
+	// Public repositories can still be fetched without a token.
+	tc := http.DefaultClient
+	if token.Value != "" {
+		tc = oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token.Value}))
+	}
 	client := github.NewClient(tc)
 
-	opt := &github.RepositoryContentGetOptions{Ref: s.branch}
+	// Pin the archive to the resolved commit so the reported revision is the one unpacked.
+	commit, _, err = client.Repositories.GetCommitSHA1(ctx, owner, repo, s.branch, "")
+	if err != nil {
+		return "", errs.Wrap(ErrFetchFailed, err)
+	}
+
+	opt := &github.RepositoryContentGetOptions{Ref: commit}
 	url, _, err := client.Repositories.GetArchiveLink(ctx, owner, repo, github.Tarball, opt, 3)
 	if err != nil {
-		return errs.Wrap(ErrFetchFailed, err)
+		return "", errs.Wrap(ErrFetchFailed, err)
 	}
 
-	slog.Info("downloading repository archive", "owner", owner, "repo", repo, "branch", s.branch)
+	slog.Info("downloading repository archive", "owner", owner, "repo", repo, "branch", s.branch, "commit", commit)
 	resp, err := tc.Get(url.String())
 	if err != nil {
-		return errs.Wrap(ErrFetchFailed, err)
+		return "", errs.Wrap(ErrFetchFailed, err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return errs.Wrap(ErrFetchFailed, fmt.Errorf("unexpected status: %s", resp.Status))
+		return "", errs.Wrap(ErrFetchFailed, fmt.Errorf("unexpected status: %s", resp.Status))
 	}
 	if err := os.MkdirAll(dest, 0755); err != nil {
-		return errs.Wrap(ErrUnpackFailed, err)
+		return "", errs.Wrap(ErrUnpackFailed, err)
 	}
-	if err := s.unpackTarball(resp.Body, dest); err != nil {
-		return errs.Wrap(ErrUnpackFailed, err)
+	body.r = resp.Body
+	if err := s.unpackTarball(body, dest); err != nil {
+		return "", errs.Wrap(ErrUnpackFailed, err)
 	}
 	if entries, err := os.ReadDir(dest); err == nil {
 		var names []string
@@ -86,7 +104,7 @@ func (s *GitHubSource) Fetch(ctx context.Context, dest string) error {
 		slog.Info("unpacked repository", "dest", dest, "entries", names)
 	}
 
-	return nil
+	return commit, nil
 }
 
 func (s *GitHubSource) parseRepoURL() (string, string, error) {
@@ -170,3 +188,14 @@ func (s *GitHubSource) unpackTarball(r io.Reader, dest string) error {
 	}
 	return nil
 }
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
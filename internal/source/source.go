@@ -12,10 +12,10 @@ var (
 	ErrUnsupportedProvider = errors.New("source: unsupported provider")
 )
 
-type TokenProvider func(ctx context.Context) (string, error)
+type TokenProvider func(ctx context.Context) (Token, error)
 
 type Source interface {
-	Fetch(ctx context.Context, dest string) error
+	Fetch(ctx context.Context, dest string) (string, error)
 }
 
 func New(repoURL, branch string, tp TokenProvider) (Source, error) {
@@ -0,0 +1,112 @@
+package source
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// tokenRefreshAhead is how long before expiry a token is replaced, so a
+	// fetch that starts with it does not run past its lifetime.
+	tokenRefreshAhead = 5 * time.Minute
+	// tokenDefaultTTL bounds how long a token without a known expiry is reused.
+	tokenDefaultTTL   = 10 * time.Minute
+	tokenFetchTimeout = 30 * time.Second
+)
+
+type Token struct {
+	Value     string
+	ExpiresAt time.Time
+}
+
+func (t Token) validAt(now time.Time) bool {
+	return t.Value != "" && (t.ExpiresAt.IsZero() || now.Before(t.ExpiresAt))
+}
+
+type cachingTokenProvider struct {
+	next      TokenProvider
+	mu        sync.Mutex
+	token     Token
+	refreshAt time.Time
+	inflight  *tokenRefresh
+}
+
+type tokenRefresh struct {
+	done  chan struct{}
+	token Token
+	err   error
+}
+
+// NewCachingTokenProvider reuses tokens from next until shortly before they
+// expire. Concurrent callers share a single refresh.
+func NewCachingTokenProvider(next TokenProvider) TokenProvider {
+	p := &cachingTokenProvider{next: next}
+	return p.Token
+}
+
+func (p *cachingTokenProvider) Token(ctx context.Context) (Token, error) {
+	p.mu.Lock()
+	if p.token.Value != "" && time.Now().Before(p.refreshAt) {
+		token := p.token
+		p.mu.Unlock()
+		return token, nil
+	}
+	r := p.inflight
+	if r == nil {
+		r = &tokenRefresh{done: make(chan struct{})}
+		p.inflight = r
+		// The refresh is shared, so it must not die with the first caller.
+		go p.refresh(context.WithoutCancel(ctx), r)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return Token{}, ctx.Err()
+	}
+	if r.err != nil {
+		p.mu.Lock()
+		current := p.token
+		p.mu.Unlock()
+		if current.validAt(time.Now()) {
+			slog.WarnContext(ctx, "token refresh failed, reusing current token", "expires_at", current.ExpiresAt, "error", r.err)
+			return current, nil
+		}
+		return Token{}, r.err
+	}
+	return r.token, nil
+}
+
+func (p *cachingTokenProvider) refresh(ctx context.Context, r *tokenRefresh) {
+	ctx, cancel := context.WithTimeout(ctx, tokenFetchTimeout)
+	defer cancel()
+	r.token, r.err = p.next(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if r.err == nil {
+		p.token = r.token
+		p.refreshAt = refreshTime(r.token, time.Now())
+	}
+	p.inflight = nil
+	close(r.done)
+}
+
+func refreshTime(t Token, now time.Time) time.Time {
+	if t.ExpiresAt.IsZero() {
+		return now.Add(tokenDefaultTTL)
+	}
+	lifetime := t.ExpiresAt.Sub(now)
+	if lifetime <= 0 {
+		return now
+	}
+	ahead := tokenRefreshAhead
+	// Short-lived tokens are refreshed once half their lifetime has passed.
+	if lifetime < 2*ahead {
+		ahead = lifetime / 2
+	}
+	return t.ExpiresAt.Add(-ahead)
+}
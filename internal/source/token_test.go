@@ -0,0 +1,144 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefreshTime(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		expires time.Time
+		want    time.Time
+	}{
+		{"no expiry", time.Time{}, now.Add(tokenDefaultTTL)},
+		{"long lived", now.Add(time.Hour), now.Add(time.Hour - tokenRefreshAhead)},
+		{"short lived", now.Add(4 * time.Minute), now.Add(2 * time.Minute)},
+		{"expires now", now, now},
+		{"already expired", now.Add(-time.Minute), now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := refreshTime(Token{Value: "t", ExpiresAt: tt.expires}, now)
+			if !got.Equal(tt.want) {
+				t.Errorf("refreshTime = %v, want %v", got, tt.want)
+			}
+			if !tt.expires.IsZero() && got.After(tt.expires) && got.After(now) {
+				t.Errorf("refreshTime %v is after expiry %v", got, tt.expires)
+			}
+		})
+	}
+}
+
+// countingProvider issues numbered tokens that expire after ttl.
+type countingProvider struct {
+	ttl   time.Duration
+	calls atomic.Int32
+	err   atomic.Pointer[error]
+}
+
+func (p *countingProvider) token(context.Context) (Token, error) {
+	n := p.calls.Add(1)
+	if err := p.err.Load(); err != nil {
+		return Token{}, *err
+	}
+	return Token{Value: string(rune('0' + n)), ExpiresAt: time.Now().Add(p.ttl)}, nil
+}
+
+func TestCachingTokenProviderReuses(t *testing.T) {
+	next := &countingProvider{ttl: time.Hour}
+	tp := NewCachingTokenProvider(next.token)
+	ctx := context.Background()
+	for range 3 {
+		token, err := tp(ctx)
+		if err != nil || token.Value != "1" {
+			t.Fatalf("token = %q, %v", token.Value, err)
+		}
+	}
+	if n := next.calls.Load(); n != 1 {
+		t.Errorf("calls = %d, want 1", n)
+	}
+}
+
+func TestCachingTokenProviderRefreshesBeforeExpiry(t *testing.T) {
+	next := &countingProvider{ttl: 200 * time.Millisecond}
+	tp := NewCachingTokenProvider(next.token)
+	ctx := context.Background()
+	first, err := tp(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Half of a short lifetime in, the token is replaced while still valid.
+	time.Sleep(120 * time.Millisecond)
+	second, err := tp(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Value == first.Value || time.Now().After(first.ExpiresAt) {
+		t.Fatalf("token %q not refreshed before it expired at %v", first.Value, first.ExpiresAt)
+	}
+}
+
+func TestCachingTokenProviderExpiredToken(t *testing.T) {
+	next := &countingProvider{ttl: -time.Minute}
+	tp := NewCachingTokenProvider(next.token)
+	ctx := context.Background()
+	for i := range 3 {
+		if _, err := tp(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if n := next.calls.Load(); int(n) != i+1 {
+			t.Fatalf("calls = %d after %d lookups, an expired token was served from the cache", n, i+1)
+		}
+	}
+}
+
+func TestCachingTokenProviderSharesRefresh(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	tp := NewCachingTokenProvider(func(context.Context) (Token, error) {
+		calls.Add(1)
+		<-release
+		return Token{Value: "shared", ExpiresAt: time.Now().Add(time.Hour)}, nil
+	})
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			if token, err := tp(context.Background()); err != nil || token.Value != "shared" {
+				t.Errorf("token = %q, %v", token.Value, err)
+			}
+		})
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("calls = %d, want 1", n)
+	}
+}
+
+func TestCachingTokenProviderKeepsValidTokenOnError(t *testing.T) {
+	next := &countingProvider{ttl: 200 * time.Millisecond}
+	tp := NewCachingTokenProvider(next.token)
+	ctx := context.Background()
+	first, err := tp(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failure := errors.New("github down")
+	next.err.Store(&failure)
+	time.Sleep(120 * time.Millisecond)
+	token, err := tp(ctx)
+	if err != nil || token.Value != first.Value {
+		t.Fatalf("token = %q, %v, want the still valid %q", token.Value, err, first.Value)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := tp(ctx); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want the refresh error once the token expired", err)
+	}
+}